@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// The kinds of failures returned by Consul that the resources can react to,
+// they are matched with errors.Is against the errors returned by the
+// keyClient.
+var (
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNotFound         = errors.New("not found")
+	ErrValueTooLarge    = errors.New("value too large")
+	ErrUnreachable      = errors.New("Consul could not be reached")
+	ErrTimeout          = errors.New("Consul did not answer in time")
+)
+
+// apiError is an error returned by the Consul API along with its kind. Its
+// message is the one of the original error.
+type apiError struct {
+	kind error
+	err  error
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+func (e *apiError) Is(target error) bool {
+	return target == e.kind
+}
+
+// responseCodeRe extracts the status code of the errors of the endpoints that
+// do not return a consulapi.StatusError.
+var responseCodeRe = regexp.MustCompile(`Unexpected response code: (\d{3})`)
+
+// newAPIError returns err with the kind of failure found by inspecting its
+// status code and message, err is returned as is when its kind is unknown.
+func newAPIError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if kind := apiErrorKind(err); kind != nil {
+		return &apiError{kind: kind, err: err}
+	}
+	return err
+}
+
+func apiErrorKind(err error) error {
+	var timeoutErr *timeoutError
+	if errors.As(err, &timeoutErr) {
+		return ErrTimeout
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrUnreachable
+	}
+
+	code := 0
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) {
+		code = statusErr.Code
+	} else if m := responseCodeRe.FindStringSubmatch(err.Error()); m != nil {
+		code, _ = strconv.Atoi(m[1])
+	}
+
+	msg := err.Error()
+	switch {
+	case code == http.StatusNotFound:
+		return ErrNotFound
+	case strings.Contains(msg, "ACL not found"):
+		// Consul answers with a 403 when the ACL object to read does not
+		// exist
+		return ErrNotFound
+	case code == http.StatusForbidden || code == http.StatusUnauthorized:
+		return ErrPermissionDenied
+	case code == http.StatusRequestEntityTooLarge:
+		return ErrValueTooLarge
+	case code == 0 && strings.Contains(msg, "connection refused"):
+		return ErrUnreachable
+	}
+	return nil
+}
+
+// isDatacenterUnreachable returns whether err is a connection-level failure
+// while reaching a datacenter, either because the agent itself cannot be
+// reached or because it could not forward the request to the servers of the
+// datacenter.
+func isDatacenterUnreachable(err error) bool {
+	if errors.Is(newAPIError(err), ErrUnreachable) {
+		return true
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "No path to datacenter") {
+		return true
+	}
+	return strings.Contains(msg, "rpc error") &&
+		(strings.Contains(msg, "connection refused") || strings.Contains(msg, "i/o timeout"))
+}
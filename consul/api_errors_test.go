@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestNewAPIError(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected error
+	}{
+		"permission denied":  {consulapi.StatusError{Code: 403, Body: "Permission denied: token with AccessorID '00000000-0000-0000-0000-000000000002' lacks permission 'key:write' on \"app\""}, ErrPermissionDenied},
+		"unauthorized":       {consulapi.StatusError{Code: 401, Body: "Unauthorized"}, ErrPermissionDenied},
+		"acl not found":      {consulapi.StatusError{Code: 403, Body: "ACL not found"}, ErrNotFound},
+		"not found":          {consulapi.StatusError{Code: 404, Body: "Namespace not found"}, ErrNotFound},
+		"too large":          {consulapi.StatusError{Code: 413, Body: "Request body(600000 bytes) too large, max size: 524288 bytes"}, ErrValueTooLarge},
+		"legacy format":      {errors.New("Unexpected response code: 403 (Permission denied)"), ErrPermissionDenied},
+		"url error":          {&url.Error{Op: "Get", URL: "http://127.0.0.1:8500/v1/kv/app", Err: syscall.ECONNREFUSED}, ErrUnreachable},
+		"connection refused": {errors.New("dial tcp 127.0.0.1:8500: connect: connection refused"), ErrUnreachable},
+		"timeout":            {&timeoutError{timeout: time.Minute}, ErrTimeout},
+		"server error":       {consulapi.StatusError{Code: 500, Body: "No cluster leader"}, nil},
+		"bad request":        {consulapi.StatusError{Code: 400, Body: "Bad request"}, nil},
+	}
+
+	kinds := []error{ErrPermissionDenied, ErrNotFound, ErrValueTooLarge, ErrUnreachable, ErrTimeout}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := newAPIError(c.err)
+			if err.Error() != c.err.Error() {
+				t.Fatalf("the message changed\ngot:      %s\nexpected: %s", err, c.err)
+			}
+			if !errors.Is(err, c.err) {
+				t.Fatalf("the original error is lost: %#v", err)
+			}
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == c.expected) {
+					t.Fatalf("errors.Is(%q) = %t, expected kind %v", kind, errors.Is(err, kind), c.expected)
+				}
+			}
+
+			// The kind is still found once the error is wrapped
+			wrapped := fmt.Errorf("failed to read Consul key 'app': %w", err)
+			if c.expected != nil && !errors.Is(wrapped, c.expected) {
+				t.Fatalf("the kind of the wrapped error is lost")
+			}
+		})
+	}
+
+	if newAPIError(nil) != nil {
+		t.Fatal("expected a nil error")
+	}
+}
+
+func TestKeyClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte("Request body(600000 bytes) too large, max size: 524288 bytes"))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Permission denied"))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+
+	_, _, err = c.Get(context.Background(), "app/config")
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected a permission denied error, got %v", err)
+	}
+	expected := "failed to read Consul key 'app/config': Unexpected response code: 403 (Permission denied)"
+	if err.Error() != expected {
+		t.Fatalf("unexpected message\ngot:      %s\nexpected: %s", err, expected)
+	}
+
+	err = c.Put(context.Background(), "app/config", "value", 0)
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected a value too large error, got %v", err)
+	}
+}
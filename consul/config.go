@@ -4,12 +4,21 @@
 package consul
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/go-version"
+	"golang.org/x/time/rate"
 )
 
 // Config is configuration defined in the provider block
@@ -19,6 +28,7 @@ type Config struct {
 	Scheme        string `mapstructure:"scheme"`
 	HttpAuth      string `mapstructure:"http_auth"`
 	Token         string `mapstructure:"token"`
+	TokenFile     string `mapstructure:"token_file"`
 	CAFile        string `mapstructure:"ca_file"`
 	CAPem         string `mapstructure:"ca_pem"`
 	CertFile      string `mapstructure:"cert_file"`
@@ -28,7 +38,67 @@ type Config struct {
 	CAPath        string `mapstructure:"ca_path"`
 	InsecureHttps bool   `mapstructure:"insecure_https"`
 	Namespace     string `mapstructure:"namespace"`
-	client        *consulapi.Client
+	Partition     string `mapstructure:"partition"`
+	MaxRetries    int    `mapstructure:"max_retries"`
+	RetryWaitMin  string `mapstructure:"retry_wait_min"`
+	RetryWaitMax  string `mapstructure:"retry_wait_max"`
+	MaxIdleConns  int    `mapstructure:"http_max_idle_conns"`
+	IdleTimeout   string `mapstructure:"http_idle_conn_timeout"`
+
+	ValidateDatacenters bool    `mapstructure:"validate_datacenters"`
+	SkipConnectionCheck bool    `mapstructure:"skip_connection_check"`
+	EncryptionKey       string  `mapstructure:"encryption_key"`
+	WritesPerSecond     float64 `mapstructure:"writes_per_second"`
+	ReadsPerSecond      float64 `mapstructure:"reads_per_second"`
+	ReadCache           bool    `mapstructure:"read_cache"`
+	ReadCacheTTL        string  `mapstructure:"read_cache_ttl"`
+	DefaultKVFlags      int     `mapstructure:"default_kv_flags"`
+	MetricsFile         string  `mapstructure:"metrics_file"`
+	OperationTimeout    string  `mapstructure:"operation_timeout"`
+	KVMaxValueBytes     int     `mapstructure:"kv_max_value_bytes"`
+
+	client    *consulapi.Client
+	retry     retryPolicy
+	transport *http.Transport
+
+	// operationTimeout is the parsed OperationTimeout
+	operationTimeout time.Duration
+
+	// stopCtx is cancelled when Terraform stops the provider
+	stopCtx context.Context
+
+	// encryptionKey is the decoded EncryptionKey
+	encryptionKey []byte
+
+	// The limiters of the requests made to the key/value store, they are
+	// nil when there is no limit
+	writeLimiter *rate.Limiter
+	readLimiter  *rate.Limiter
+
+	// readCache is nil unless read_cache is set
+	readCache *readCache
+
+	// metrics is nil unless metrics_file is set
+	metrics *kvMetrics
+
+	// The TTL sessions shared by the consul_keys resources
+	sessionPool sessionPool
+
+	// The locks of the ACL tokens updated by the attachment resources,
+	// indexed by accessor ID
+	aclTokenLocks sync.Map
+
+	// The datacenters are only fetched once per run
+	datacentersLock sync.Mutex
+	datacenters     []string
+
+	// The version of the servers is only fetched once per run
+	serverVersionLock sync.Mutex
+	serverVersion     *version.Version
+
+	// The connection is only checked once per run
+	connectionCheck    sync.Once
+	connectionCheckErr error
 }
 
 // Client returns a new client for accessing consul.
@@ -72,10 +142,48 @@ func (c *Config) Client() (*consulapi.Client, error) {
 		config.TLSConfig.InsecureSkipVerify = c.InsecureHttps
 	}
 
+	// All the requests share the same transport so that the connections to
+	// Consul are reused instead of being opened for each resource.
+	if c.MaxIdleConns > 0 {
+		config.Transport.MaxIdleConns = c.MaxIdleConns
+		config.Transport.MaxIdleConnsPerHost = c.MaxIdleConns
+	}
+	if c.IdleTimeout != "" {
+		idleTimeout, err := time.ParseDuration(c.IdleTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse http_idle_conn_timeout: %v", err)
+		}
+		config.Transport.IdleConnTimeout = idleTimeout
+	}
+	// Consul can also be reached through a Unix domain socket, the shared
+	// transport then dials the socket for all the connections. The address
+	// is rewritten here so that the client keeps using this transport.
+	if strings.HasPrefix(config.Address, "unix://") {
+		socket := strings.TrimPrefix(config.Address, "unix://")
+		if err := checkUnixSocket(socket); err != nil {
+			return nil, err
+		}
+		config.Address = socket
+		config.Transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+	c.transport = config.Transport
+
 	// This is a temporary workaround to add the Content-Type header when
 	// needed until the fix is released in the Consul api client.
+	var roundTripper http.RoundTripper = transport{config.Transport}
+	// The token of token_file is set by the transport so that it can be
+	// read again when it is rotated
+	if c.TokenFile != "" {
+		roundTripper = &tokenFileTransport{
+			RoundTripper: roundTripper,
+			file:         &tokenFile{path: c.TokenFile},
+		}
+	}
 	config.HttpClient = &http.Client{
-		Transport: transport{config.Transport},
+		Transport: roundTripper,
 	}
 
 	if config.Transport.TLSClientConfig == nil {
@@ -88,6 +196,19 @@ func (c *Config) Client() (*consulapi.Client, error) {
 		config.Transport.TLSClientConfig = tlsClientConfig
 	}
 
+	// The certificates read from files are loaded again for each new
+	// connection so that they can be rotated during a long apply.
+	if config.Scheme == "https" && (c.CertFile != "" || c.CAFile != "" || c.CAPath != "") {
+		files := &tlsFiles{
+			certFile: c.CertFile,
+			keyFile:  c.KeyFile,
+			caFile:   c.CAFile,
+			caPath:   c.CAPath,
+			caPEM:    []byte(c.CAPem),
+		}
+		files.install(config.Transport)
+	}
+
 	if c.HttpAuth != "" {
 		var username, password string
 		if strings.Contains(c.HttpAuth, ":") {
@@ -114,6 +235,22 @@ func (c *Config) Client() (*consulapi.Client, error) {
 	return client, nil
 }
 
+// checkUnixSocket returns an error when path is not an existing Unix domain
+// socket.
+func checkUnixSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("failed to use the Unix socket %q: it does not exist", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to use the Unix socket %q: %v", path, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("failed to use the Unix socket %q: it is not a socket", path)
+	}
+	return nil
+}
+
 // transport adds the Content-Type header to all requests that might need it
 // until we update the API client to a version with
 // https://github.com/hashicorp/consul/pull/10204 at which time we will be able
@@ -131,3 +268,150 @@ func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 	return t.RoundTripper.RoundTrip(req)
 }
+
+// Datacenters returns the datacenters known to Consul, the list is cached
+// for the lifetime of the provider.
+func (c *Config) Datacenters() ([]string, error) {
+	c.datacentersLock.Lock()
+	defer c.datacentersLock.Unlock()
+
+	if c.datacenters != nil {
+		return c.datacenters, nil
+	}
+
+	datacenters, err := c.client.Catalog().Datacenters()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the datacenters: %v", err)
+	}
+	c.datacenters = datacenters
+	return datacenters, nil
+}
+
+// newRateLimiter returns a limiter allowing perSecond requests per second, or
+// nil when perSecond is 0. The burst is one request so that they are evenly
+// spread.
+func newRateLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), 1)
+}
+
+// ServerVersion returns the version of Consul reported by the agent, it is
+// cached for the lifetime of the provider.
+func (c *Config) ServerVersion() (*version.Version, error) {
+	c.serverVersionLock.Lock()
+	defer c.serverVersionLock.Unlock()
+
+	if c.serverVersion != nil {
+		return c.serverVersion, nil
+	}
+
+	info, err := c.client.Agent().Self()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the version of Consul: %v", err)
+	}
+
+	raw, _ := info["Config"]["Version"].(string)
+	v, err := version.NewVersion(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the version of Consul %q: %v", raw, err)
+	}
+	c.serverVersion = v
+	return v, nil
+}
+
+// requireVersion returns an error when the version of Consul is older than
+// min, feature describes what needs this version in the error message.
+func (c *Config) requireVersion(feature, min string) error {
+	v, err := c.ServerVersion()
+	if err != nil {
+		return err
+	}
+
+	if v.Core().LessThan(version.Must(version.NewVersion(min))) {
+		return fmt.Errorf("%s requires Consul >= %s, the version of Consul is %s", feature, min, v)
+	}
+	return nil
+}
+
+// checkConnection checks that Consul can be reached and that it accepts the
+// ACL token of the client, the result is cached for the lifetime of the
+// provider.
+func (c *Config) checkConnection() error {
+	c.connectionCheck.Do(func() {
+		address := c.Address
+		if address == "" {
+			address = consulapi.DefaultConfig().Address
+		}
+
+		// The status endpoints do not require any ACL
+		leader, err := c.client.Status().Leader()
+		if err != nil {
+			c.connectionCheckErr = connectionError(address, err)
+			return
+		}
+		if leader == "" {
+			c.connectionCheckErr = fmt.Errorf("failed to connect to Consul at %s: the cluster has no leader", address)
+			return
+		}
+
+		// Without a token the requests use the anonymous token or the
+		// default token of the agent, which cannot read themselves
+		if c.Token == "" && c.TokenFile == "" {
+			return
+		}
+
+		// Any token can read itself
+		_, _, err = c.client.ACL().TokenReadSelf(nil)
+		if err != nil && !strings.Contains(err.Error(), "ACL support disabled") {
+			if kind := apiErrorKind(err); kind == ErrNotFound || kind == ErrPermissionDenied {
+				c.connectionCheckErr = fmt.Errorf("failed to authenticate to Consul at %s: the ACL token is not valid (%v)", address, err)
+				return
+			}
+			c.connectionCheckErr = fmt.Errorf("failed to authenticate to Consul at %s: %v", address, err)
+		}
+	})
+	return c.connectionCheckErr
+}
+
+// connectionError returns an error explaining why Consul could not be
+// reached at address.
+func connectionError(address string, err error) error {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	msg := err.Error()
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("failed to connect to Consul at %s: the host name could not be resolved, check the address (%v)", address, err)
+	case errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(msg, "connection refused"):
+		return fmt.Errorf("failed to connect to Consul at %s: the connection was refused, check that Consul listens on this address and port (%v)", address, err)
+	case strings.Contains(msg, "x509:") || strings.Contains(msg, "tls:") || strings.Contains(msg, "HTTP response to HTTPS client"):
+		return fmt.Errorf("failed to connect to Consul at %s: the TLS handshake failed, check the scheme and the certificates (%v)", address, err)
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("failed to connect to Consul at %s: the connection timed out (%v)", address, err)
+	case apiErrorKind(err) == ErrPermissionDenied:
+		return fmt.Errorf("failed to authenticate to Consul at %s: %v", address, err)
+	}
+	return fmt.Errorf("failed to connect to Consul at %s: %v", address, err)
+}
+
+// validateDatacenter returns an error listing the valid datacenters when dc
+// is not known to Consul. Nothing is checked unless validate_datacenters is
+// set.
+func (c *Config) validateDatacenter(dc string) error {
+	if !c.ValidateDatacenters || dc == "" {
+		return nil
+	}
+
+	datacenters, err := c.Datacenters()
+	if err != nil {
+		return err
+	}
+	for _, datacenter := range datacenters {
+		if datacenter == dc {
+			return nil
+		}
+	}
+	return fmt.Errorf("datacenter %q does not exist, valid datacenters are: %s", dc, strings.Join(datacenters, ", "))
+}
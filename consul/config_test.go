@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigClient_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "consul.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/test" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"Key": "test", "Value": "aGVsbG8="}]`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	config := &Config{Address: "unix://" + socket}
+	client, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pair, _, err := client.KV().Get("test", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if pair == nil || string(pair.Value) != "hello" {
+		t.Fatalf("unexpected pair %#v", pair)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+	_, err = (&Config{Address: "unix://" + missing}).Client()
+	if err == nil || err.Error() != `failed to use the Unix socket "`+missing+`": it does not exist` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = (&Config{Address: "unix://" + t.TempDir()}).Client()
+	if err == nil {
+		t.Fatal("expected an error for a directory")
+	}
+}
+
+func TestConfigRequireVersion(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/agent/self" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls++
+		w.Write([]byte(`{"Config": {"Version": "1.12.3+ent"}}`))
+	}))
+	defer server.Close()
+
+	config := &Config{Address: server.URL}
+	client, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config.client = client
+
+	if err := config.requireVersion("consul_admin_partition", "1.11.0"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := config.requireVersion("consul_admin_partition", "1.12.3"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = config.requireVersion("consul_peering", "1.13.0")
+	if err == nil || err.Error() != "consul_peering requires Consul >= 1.13.0, the version of Consul is 1.12.3+ent" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The version is only fetched once
+	if calls != 1 {
+		t.Fatalf("the version has been fetched %d times", calls)
+	}
+}
+
+func TestConfigCheckConnection(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/status/leader":
+			calls++
+			w.Write([]byte(`"127.0.0.1:8300"`))
+		case "/v1/acl/token/self":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("ACL not found"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	check := func(address string) error {
+		config := &Config{Address: address}
+		client, err := config.Client()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		config.client = client
+		return config.checkConnection()
+	}
+
+	// The token is not checked when none is set
+	if err := check(server.URL); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := &Config{Address: server.URL, Token: "secret"}
+	client, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	config.client = client
+	calls = 0
+
+	err = config.checkConnection()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to authenticate to Consul at "+server.URL+": the ACL token is not valid") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The connection is only checked once
+	if err2 := config.checkConnection(); err2 != err {
+		t.Fatalf("unexpected error: %v", err2)
+	}
+	if calls != 1 {
+		t.Fatalf("the connection has been checked %d times", calls)
+	}
+
+	// Nothing listens on the address anymore
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	err = check(address)
+	if err == nil || !strings.Contains(err.Error(), "the connection was refused") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = check("consul.invalid:8500")
+	if err == nil || !strings.Contains(err.Error(), "the host name could not be resolved") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
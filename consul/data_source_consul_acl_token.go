@@ -4,6 +4,8 @@
 package consul
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -28,6 +30,12 @@ func dataSourceConsulACLToken() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"include_secret": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the secret ID of the token is exported in the `secret_id` attribute.",
+			},
 
 			// Out parameters
 			"description": {
@@ -117,6 +125,17 @@ func dataSourceConsulACLToken() *schema.Resource {
 				Computed:    true,
 				Description: "If set this represents the point after which a token should be considered revoked and is eligible for destruction.",
 			},
+			"create_time": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The time the token was created at.",
+			},
+			"secret_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The secret ID of the token, it is only set when `include_secret` is true.",
+			},
 		},
 	}
 }
@@ -127,7 +146,13 @@ func dataSourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) erro
 
 	aclToken, _, err := client.ACL().TokenRead(accessorID, qOpts)
 	if err != nil {
-		return err
+		if errors.Is(newAPIError(err), ErrNotFound) {
+			return fmt.Errorf("ACL token %q not found", accessorID)
+		}
+		return fmt.Errorf("failed to read ACL token %q: %v", accessorID, err)
+	}
+	if aclToken == nil {
+		return fmt.Errorf("ACL token %q not found", accessorID)
 	}
 
 	policies := make([]map[string]interface{}, len(aclToken.Policies))
@@ -177,6 +202,14 @@ func dataSourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) erro
 	sw.set("service_identities", serviceIdentities)
 	sw.set("node_identities", nodeIdentities)
 	sw.set("expiration_time", expirationTime)
+	sw.set("create_time", aclToken.CreateTime.Format(time.RFC3339))
+
+	// The secret ID is never stored in the state unless it has been requested
+	var secretID string
+	if d.Get("include_secret").(bool) {
+		secretID = aclToken.SecretID
+	}
+	sw.set("secret_id", secretID)
 
 	return sw.error()
 }
@@ -4,6 +4,7 @@
 package consul
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
@@ -34,8 +35,27 @@ func TestAccDataACLToken_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("data.consul_acl_token.read", "service_identities.0.datacenters.#", "1"),
 					resource.TestCheckResourceAttr("data.consul_acl_token.read", "service_identities.0.datacenters.0", "world"),
 					resource.TestCheckResourceAttr("data.consul_acl_token.read", "service_identities.0.service_name", "hello"),
+					resource.TestCheckResourceAttrSet("data.consul_acl_token.read", "create_time"),
+					resource.TestCheckResourceAttr("data.consul_acl_token.read", "secret_id", ""),
 				),
 			},
+			{
+				Config: testAccDataACLTokenConfig + `
+data "consul_acl_token" "secret" {
+	accessor_id    = consul_acl_token.test.id
+	include_secret = true
+}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair("data.consul_acl_token.secret", "secret_id", "consul_acl_token.test", "secret_id"),
+				),
+			},
+			{
+				Config: `
+data "consul_acl_token" "missing" {
+	accessor_id = "9b8af3f5-6a48-4a47-a8a3-3a0f5b2f3a4e"
+}`,
+				ExpectError: regexp.MustCompile(`ACL token "9b8af3f5-6a48-4a47-a8a3-3a0f5b2f3a4e" not found`),
+			},
 		},
 	})
 }
@@ -49,6 +49,24 @@ func dataSourceConsulAgentConfig() *schema.Resource {
 				Description: "The version of the build of Consul that is running",
 				Computed:    true,
 			},
+
+			"primary_datacenter": {
+				Type:        schema.TypeString,
+				Description: "The primary datacenter of the cluster, it is empty when Consul does not report it",
+				Computed:    true,
+			},
+
+			"acl_enabled": {
+				Type:        schema.TypeBool,
+				Description: "If the ACL system is enabled on the agent",
+				Computed:    true,
+			},
+
+			"connect_enabled": {
+				Type:        schema.TypeBool,
+				Description: "If Connect is enabled on the agent",
+				Computed:    true,
+			},
 		},
 	}
 }
@@ -78,6 +96,28 @@ func dataSourceConsulAgentConfigRead(d *schema.ResourceData, meta interface{}) e
 	sw.set("server", config["Server"])
 	sw.set("revision", config["Revision"])
 	sw.set("version", config["Version"])
+	sw.set("primary_datacenter", agentConfigString(config, "PrimaryDatacenter"))
+
+	// The feature flags are only part of the debug configuration, which is
+	// missing or incomplete on older versions of Consul. They are then
+	// reported as disabled.
+	debugConfig := agentSelf["DebugConfig"]
+	sw.set("acl_enabled", agentConfigBool(debugConfig, "ACLsEnabled"))
+	sw.set("connect_enabled", agentConfigBool(debugConfig, "ConnectEnabled"))
 
 	return sw.error()
 }
+
+// agentConfigString returns the string stored at key in the configuration
+// returned by the agent or "" when it is missing.
+func agentConfigString(config map[string]interface{}, key string) string {
+	v, _ := config[key].(string)
+	return v
+}
+
+// agentConfigBool returns the boolean stored at key in the configuration
+// returned by the agent or false when it is missing.
+func agentConfigBool(config map[string]interface{}, key string) bool {
+	v, _ := config[key].(bool)
+	return v
+}
@@ -23,12 +23,37 @@ func TestAccDataConsulAgentConfig_basic(t *testing.T) {
 					resource.TestCheckResourceAttrSet("data.consul_agent_config.example", "node_name"),
 					resource.TestCheckResourceAttrSet("data.consul_agent_config.example", "node_id"),
 					resource.TestCheckResourceAttrSet("data.consul_agent_config.example", "version"),
+					resource.TestCheckResourceAttr("data.consul_agent_config.example", "primary_datacenter", "dc1"),
+					resource.TestCheckResourceAttr("data.consul_agent_config.example", "acl_enabled", "true"),
+					resource.TestCheckResourceAttr("data.consul_agent_config.example", "connect_enabled", "true"),
 				),
 			},
 		},
 	})
 }
 
+func TestAgentConfigValues(t *testing.T) {
+	config := map[string]interface{}{
+		"PrimaryDatacenter": "dc1",
+		"ACLsEnabled":       true,
+		"ConnectEnabled":    "true",
+	}
+
+	if v := agentConfigString(config, "PrimaryDatacenter"); v != "dc1" {
+		t.Fatalf("unexpected value %q", v)
+	}
+	if v := agentConfigString(nil, "PrimaryDatacenter"); v != "" {
+		t.Fatalf("unexpected value %q", v)
+	}
+	if !agentConfigBool(config, "ACLsEnabled") {
+		t.Fatal("ACLsEnabled should be true")
+	}
+	// Values with an unexpected type are ignored
+	if agentConfigBool(config, "ConnectEnabled") {
+		t.Fatal("ConnectEnabled should be false")
+	}
+}
+
 const testAccDataConsulAgentConfig = `
 data "consul_agent_config" "example" {}
 `
@@ -22,9 +22,7 @@ func dataSourceConsulDatacenters() *schema.Resource {
 }
 
 func dataSourceConsulDatacentersRead(d *schema.ResourceData, meta interface{}) error {
-	client, _, _ := getClient(d, meta)
-
-	datacenters, err := client.Catalog().Datacenters()
+	datacenters, err := meta.(*Config).Datacenters()
 	if err != nil {
 		return err
 	}
@@ -69,6 +69,20 @@ func dataSourceConsulKeyPrefix() *schema.Resource {
 				},
 			},
 
+			"allow_stale": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"require_consistent": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_stale"},
+				Description:   "Whether the keys must be read in the consistent mode, the leader then confirms its leadership with a quorum of servers before answering.",
+			},
+
 			"namespace": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -78,12 +92,32 @@ func dataSourceConsulKeyPrefix() *schema.Resource {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+
+			"read_datacenters": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The datacenters to read the keys from, in order, when the datacenter cannot be reached.",
+			},
+
+			"read_datacenter": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The datacenter the keys were read from.",
+			},
 		},
 	}
 }
 
 func dataSourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) error {
-	keyClient := newKeyClient(d, meta)
+	keyClient := newKeyClient(d, meta,
+		withAllowStale(d.Get("allow_stale").(bool)),
+		withRequireConsistent(d.Get("require_consistent").(bool)),
+		withReadCache(),
+		withReadDatacenters(readDatacenters(d)),
+	)
+	ctx := stopContext(meta)
+	datacenter := keyClient.qOpts.Datacenter
 
 	pathPrefix := d.Get("path_prefix").(string)
 
@@ -97,12 +131,12 @@ func dataSourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) err
 		}
 
 		fullPath := pathPrefix + path
-		value, _, err := keyClient.Get(fullPath)
+		entry, _, err := keyClient.Get(ctx, fullPath)
 		if err != nil {
 			return err
 		}
 
-		value = attributeValue(sub, value)
+		value := attributeValue(sub, entry.value)
 		vars[key] = value
 	}
 
@@ -111,7 +145,7 @@ func dataSourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) err
 	}
 
 	if len(keys) <= 0 {
-		pairs, err := keyClient.GetUnderPrefix(pathPrefix)
+		pairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
 		if err != nil {
 			return err
 		}
@@ -125,7 +159,8 @@ func dataSourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) err
 
 	// Store the datacenter on this resource, which can be helpful for reference
 	// in case it was read from the provider
-	d.Set("datacenter", keyClient.qOpts.Datacenter)
+	d.Set("datacenter", datacenter)
+	d.Set("read_datacenter", keyClient.qOpts.Datacenter)
 	d.Set("path_prefix", pathPrefix)
 
 	d.SetId("-")
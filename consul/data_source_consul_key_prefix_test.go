@@ -5,7 +5,6 @@ package consul
 
 import (
 	"fmt"
-	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
@@ -47,7 +46,7 @@ func TestAccDataConsulKeyPrefix_namespaceCE(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      testAccDataConsulKeyPrefixConfigNamespaceCE,
-				ExpectError: regexp.MustCompile("Unexpected response code: 400"),
+				ExpectError: namespaceEnterpriseFeature,
 			},
 		},
 	})
@@ -4,6 +4,17 @@
 package consul
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
@@ -36,14 +47,35 @@ func dataSourceConsulKeys() *schema.Resource {
 						},
 
 						"path": {
-							Type:     schema.TypeString,
-							Required: true,
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateKVPath,
+						},
+
+						"fallback_paths": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateKVPath},
+							Description: "The paths to read, in order, when the key does not exist. The read fails when none of them exist and the key has no default.",
 						},
 
 						"default": {
 							Type:     schema.TypeString,
 							Optional: true,
 						},
+
+						"decode_json": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+
+						"min_modify_index": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validateIntMinFactory("min_modify_index", 0),
+							Description:  "The read fails if the modify index of the key is lower, meaning that it has not been written yet by the process expected to update it.",
+						},
 					},
 				},
 			},
@@ -56,6 +88,90 @@ func dataSourceConsulKeys() *schema.Resource {
 				},
 			},
 
+			"matched_paths": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The path each key was read from, indexed by its name. It is empty for the keys that do not exist.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"decoded": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"known_leader": {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+
+			"last_contact_ms": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+
+			"read_datacenters": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The datacenters to read the keys from, in order, when the datacenter cannot be reached.",
+			},
+
+			"read_datacenter": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The datacenter the keys were read from.",
+			},
+
+			"allow_stale": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"require_consistent": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_stale"},
+				Description:   "Whether the keys must be read in the consistent mode, the leader then confirms its leadership with a quorum of servers before answering.",
+			},
+
+			"render_template": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"wait_index": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validateIntMinFactory("wait_index", 0),
+			},
+
+			"wait_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDurationMinFactory("wait_timeout", "0s"),
+			},
+
+			"min_modify_index_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDurationMinFactory("min_modify_index_timeout", "0s"),
+				Description:  "How long to wait for the keys to reach their min_modify_index before failing, the keys are read again using blocking queries.",
+			},
+
+			"modify_index": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+
 			"namespace": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -69,36 +185,391 @@ func dataSourceConsulKeys() *schema.Resource {
 	}
 }
 
+// waitMinModifyIndex reads the key at path again using blocking queries
+// until its modify index is at least minIndex, entry is the last read of the
+// key made at index. An error is returned if the key is not modified in time.
+func waitMinModifyIndex(ctx context.Context, d *schema.ResourceData, meta interface{}, keyClient *keyClient, path string, entry keyEntry, index, minIndex uint64, timeout time.Duration) (keyEntry, error) {
+	deadline := time.Now().Add(timeout)
+	for entry.modifyIndex < minIndex {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if timeout == 0 {
+				return entry, fmt.Errorf("Consul key '%s' has the modify index %d, at least %d is expected", path, entry.modifyIndex, minIndex)
+			}
+			return entry, fmt.Errorf("Consul key '%s' still has the modify index %d after waiting %s, at least %d is expected", path, entry.modifyIndex, timeout, minIndex)
+		}
+
+		keyClient.logf("DEBUG", "get", path, "Waiting for the modify index %d of the key to reach %d", entry.modifyIndex, minIndex)
+		waiting := newKeyClient(d, meta,
+			withAllowStale(keyClient.qOpts.AllowStale),
+			withRequireConsistent(keyClient.qOpts.RequireConsistent),
+			withDatacenter(keyClient.qOpts.Datacenter),
+			withWait(index, remaining),
+			withRequestID(keyClient.requestID),
+		)
+		var queryMeta *consulapi.QueryMeta
+		var err error
+		entry, queryMeta, err = waiting.Get(ctx, path)
+		if err != nil {
+			return entry, err
+		}
+		index = queryMeta.LastIndex
+	}
+	return entry, nil
+}
+
+// keyReadPaths returns the paths to read for a key of the data source, in
+// order: its path and then its fallback_paths.
+func keyReadPaths(path string, sub map[string]interface{}) []string {
+	paths := []string{path}
+	for _, raw := range sub["fallback_paths"].([]interface{}) {
+		paths = append(paths, raw.(string))
+	}
+	return paths
+}
+
+// readDatacenters returns the read_datacenters of a data source.
+func readDatacenters(d *schema.ResourceData) []string {
+	raw := d.Get("read_datacenters").([]interface{})
+	dcs := make([]string, 0, len(raw))
+	for _, dc := range raw {
+		dcs = append(dcs, dc.(string))
+	}
+	return dcs
+}
+
 func dataSourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
-	keyClient := newKeyClient(d, meta)
+	opts := []keyClientOption{
+		withAllowStale(d.Get("allow_stale").(bool)),
+		withRequireConsistent(d.Get("require_consistent").(bool)),
+		withReadDatacenters(readDatacenters(d)),
+	}
+
+	waitIndex := uint64(d.Get("wait_index").(int))
+	waitTimeout := d.Get("wait_timeout").(string)
+	blocking := waitIndex > 0 || waitTimeout != ""
+	if blocking {
+		// The duration has already been validated by the schema
+		wait, _ := time.ParseDuration(waitTimeout)
+		opts = append(opts, withWait(waitIndex, wait))
+	} else {
+		opts = append(opts, withReadCache())
+	}
+	keyClient := newKeyClient(d, meta, opts...)
+	ctx := stopContext(meta)
+	datacenter := keyClient.qOpts.Datacenter
 
 	vars := make(map[string]string)
+	matchedPaths := make(map[string]string)
+	decoded := make(map[string]string)
+	decodeJSON := make(map[string]string)
+	knownLeader := true
+	var lastContact time.Duration
+	var modifyIndex uint64
+
+	// We report the least fresh result of all the reads
+	recordMeta := func(queryMeta *consulapi.QueryMeta) {
+		if !queryMeta.KnownLeader {
+			knownLeader = false
+		}
+		if queryMeta.LastContact > lastContact {
+			lastContact = queryMeta.LastContact
+		}
+		if queryMeta.LastIndex > modifyIndex {
+			modifyIndex = queryMeta.LastIndex
+		}
+	}
 
 	keys := d.Get("key").(*schema.Set).List()
+
+	// A blocking query is made on the longest prefix shared by all the keys so
+	// that it returns as soon as one of them changes. When it times out
+	// Consul returns the current values, as for a regular read.
+	var values map[string]string
+	var indexes map[string]uint64
+	if blocking {
+		paths := make([]string, 0, len(keys))
+		for _, raw := range keys {
+			_, path, sub, err := parseKey(raw)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, keyReadPaths(path, sub)...)
+		}
+
+		pairs, queryMeta, err := keyClient.GetUnderPrefix(ctx, commonPrefix(paths))
+		if err != nil {
+			return err
+		}
+		recordMeta(queryMeta)
+
+		values = make(map[string]string, len(pairs))
+		indexes = make(map[string]uint64, len(pairs))
+		for _, pair := range pairs {
+			values[pair.Key] = string(pair.Value)
+			indexes[pair.Key] = pair.ModifyIndex
+		}
+	}
+
+	// The duration has already been validated by the schema
+	minIndexTimeout, _ := time.ParseDuration(d.Get("min_modify_index_timeout").(string))
+
 	for _, raw := range keys {
 		key, path, sub, err := parseKey(raw)
 		if err != nil {
 			return err
 		}
 
-		value, _, err := keyClient.Get(path)
+		// The first of the paths that exists is used
+		paths := keyReadPaths(path, sub)
+		minIndex := uint64(sub["min_modify_index"].(int))
+		var entry keyEntry
+		var matched string
+		if blocking {
+			for _, p := range paths {
+				if indexes[p] != 0 {
+					entry = keyEntry{value: values[p], modifyIndex: indexes[p]}
+					matched = p
+					break
+				}
+			}
+		}
+		if !blocking || entry.modifyIndex < minIndex {
+			entry, matched = keyEntry{}, ""
+			var queryMeta *consulapi.QueryMeta
+			for _, p := range paths {
+				entry, queryMeta, err = keyClient.Get(ctx, p)
+				if err != nil {
+					return err
+				}
+				recordMeta(queryMeta)
+				if entry.modifyIndex != 0 {
+					matched = p
+					break
+				}
+			}
+
+			if entry.modifyIndex < minIndex {
+				// We wait for the key that exists, or for the first path
+				// when none of them exist yet
+				if matched == "" {
+					matched = path
+				}
+				entry, err = waitMinModifyIndex(ctx, d, meta, keyClient, matched, entry, queryMeta.LastIndex, minIndex, minIndexTimeout)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if matched == "" && len(paths) > 1 && sub["default"].(string) == "" {
+			return fmt.Errorf("none of the paths of key %q exist and it has no default: %s", key, strings.Join(paths, ", "))
+		}
+		vars[key] = attributeValue(sub, entry.value)
+		matchedPaths[key] = matched
+
+		if sub["decode_json"].(bool) {
+			decodeJSON[key] = path
+		}
+	}
+
+	if d.Get("render_template").(bool) {
+		rendered, err := renderTemplates(vars)
 		if err != nil {
 			return err
 		}
+		vars = rendered
+	}
 
-		value = attributeValue(sub, value)
-		vars[key] = value
+	for key, path := range decodeJSON {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(vars[key]), &object); err != nil {
+			return fmt.Errorf("failed to decode the value of %q as a JSON object: %v", path, err)
+		}
+		flattenJSON(key, object, decoded)
 	}
 
 	if err := d.Set("var", vars); err != nil {
 		return err
 	}
+	if err := d.Set("matched_paths", matchedPaths); err != nil {
+		return err
+	}
+	if err := d.Set("decoded", decoded); err != nil {
+		return err
+	}
+	if err := d.Set("known_leader", knownLeader); err != nil {
+		return err
+	}
+	if err := d.Set("last_contact_ms", int(lastContact/time.Millisecond)); err != nil {
+		return err
+	}
+	if err := d.Set("modify_index", int(modifyIndex)); err != nil {
+		return err
+	}
 
 	// Store the datacenter on this resource, which can be helpful for reference
 	// in case it was read from the provider
-	d.Set("datacenter", keyClient.qOpts.Datacenter)
+	d.Set("datacenter", datacenter)
+	d.Set("read_datacenter", keyClient.qOpts.Datacenter)
 
 	d.SetId("-")
 
 	return nil
 }
+
+// commonPrefix returns the longest prefix shared by all the paths.
+func commonPrefix(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	prefix := paths[0]
+	for _, path := range paths[1:] {
+		i := 0
+		for i < len(prefix) && i < len(path) && prefix[i] == path[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+	return prefix
+}
+
+// flattenJSON recursively adds the leaves of a decoded JSON document to
+// result, using the path of each leaf joined with dots as its key.
+func flattenJSON(prefix string, value interface{}, result map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			flattenJSON(prefix+"."+k, e, result)
+		}
+	case []interface{}:
+		for i, e := range v {
+			flattenJSON(prefix+"."+strconv.Itoa(i), e, result)
+		}
+	case string:
+		result[prefix] = v
+	case nil:
+		result[prefix] = ""
+	default:
+		// Numbers and booleans are written back using their JSON
+		// representation
+		encoded, _ := json.Marshal(v)
+		result[prefix] = string(encoded)
+	}
+}
+
+// renderTemplates renders each value as a Go template, the other values are
+// available in the .Keys map using the name of their key. The values are
+// rendered in the order of their references so that a template can refer to
+// a value that is itself a template.
+func renderTemplates(values map[string]string) (map[string]string, error) {
+	templates := make(map[string]*template.Template, len(values))
+	for name, value := range values {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the template of key %q: %v", name, err)
+		}
+		templates[name] = tmpl
+	}
+
+	rendered := make(map[string]string, len(values))
+	visiting := make(map[string]bool)
+
+	var render func(name string, stack []string) error
+	render = func(name string, stack []string) error {
+		if _, ok := rendered[name]; ok {
+			return nil
+		}
+		stack = append(stack, name)
+		if visiting[name] {
+			return fmt.Errorf("failed to render the template of key %q: cyclic reference %s", stack[0], strings.Join(stack, " -> "))
+		}
+		visiting[name] = true
+
+		tmpl := templates[name]
+		for _, ref := range templateKeyReferences(tmpl.Tree.Root) {
+			if _, ok := templates[ref]; !ok {
+				return fmt.Errorf("failed to render the template of key %q: key %q does not exist", name, ref)
+			}
+			if err := render(ref, stack); err != nil {
+				return err
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, map[string]interface{}{"Keys": rendered}); err != nil {
+			return fmt.Errorf("failed to render the template of key %q: %v", name, err)
+		}
+		rendered[name] = buf.String()
+		return nil
+	}
+
+	for name := range values {
+		if err := render(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return rendered, nil
+}
+
+// templateKeyReferences returns the names of the keys referenced by a template
+// using either .Keys.<name> or index .Keys "<name>".
+func templateKeyReferences(node parse.Node) []string {
+	var refs []string
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			if len(n.Args) == 3 {
+				ident, isIdent := n.Args[0].(*parse.IdentifierNode)
+				field, isField := n.Args[1].(*parse.FieldNode)
+				str, isString := n.Args[2].(*parse.StringNode)
+				if isIdent && isField && isString && ident.Ident == "index" &&
+					len(field.Ident) == 1 && field.Ident[0] == "Keys" {
+					refs = append(refs, str.Text)
+				}
+			}
+			for _, c := range n.Args {
+				walk(c)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) >= 2 && n.Ident[0] == "Keys" {
+				refs = append(refs, n.Ident[1])
+			}
+		}
+	}
+	walk(node)
+
+	return refs
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+func dataSourceConsulKeysLookup() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulKeysLookupRead,
+
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"paths": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "The paths of the keys to read.",
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.NoZeroValues,
+				},
+			},
+
+			"values": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of the keys that exist, indexed by their path.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"missing": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The paths of the keys that do not exist, in the order of `paths`.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"allow_stale": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"require_consistent": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_stale"},
+				Description:   "Whether the keys must be read in the consistent mode, the leader then confirms its leadership with a quorum of servers before answering.",
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func dataSourceConsulKeysLookupRead(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta,
+		withAllowStale(d.Get("allow_stale").(bool)),
+		withRequireConsistent(d.Get("require_consistent").(bool)),
+		withReadCache(),
+	)
+	ctx := stopContext(meta)
+
+	paths := make([]string, 0)
+	for _, raw := range d.Get("paths").([]interface{}) {
+		paths = append(paths, raw.(string))
+	}
+
+	// A missing key is not an error, the caller decides how to handle it
+	// using the missing attribute
+	values := make(map[string]string, len(paths))
+	missing := make([]string, 0)
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		entry, _, err := keyClient.Get(ctx, path)
+		if err != nil {
+			return err
+		}
+		if entry.modifyIndex == 0 {
+			missing = append(missing, path)
+			continue
+		}
+		values[path] = entry.value
+	}
+
+	sw := newStateWriter(d)
+	sw.set("values", values)
+	sw.set("missing", missing)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId("-")
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccDataConsulKeysLookup_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulKeysLookupConfig,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "values.%", "2"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "values.lookup/a", "foo"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "values.other/b", "bar"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "missing.#", "2"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "missing.0", "lookup/missing"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "missing.1", "other/missing"),
+					resource.TestCheckResourceAttr("data.consul_keys_lookup.test", "datacenter", "dc1"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataConsulKeysLookupConfig = `
+resource "consul_keys" "write" {
+  key {
+    path  = "lookup/a"
+    value = "foo"
+  }
+
+  key {
+    path  = "other/b"
+    value = "bar"
+  }
+}
+
+data "consul_keys_lookup" "test" {
+  paths = [
+    "lookup/a",
+    "lookup/missing",
+    "other/b",
+    "other/missing",
+    "lookup/a",
+  ]
+
+  depends_on = [consul_keys.write]
+}
+`
@@ -4,6 +4,8 @@
 package consul
 
 import (
+	"fmt"
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -20,12 +22,220 @@ func TestAccDataConsulKeys_basic(t *testing.T) {
 				Config: testAccDataConsulKeysConfig,
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckConsulKeysValue("data.consul_keys.read", "read", "written"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "known_leader", "true"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "last_contact_ms", "0"),
 				),
 			},
 		},
 	})
 }
 
+func TestAccDataConsulKeys_allowStale(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulKeysConfigAllowStale,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysValue("data.consul_keys.read", "read", "written"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "allow_stale", "true"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "known_leader", "true"),
+					resource.TestCheckResourceAttrSet("data.consul_keys.read", "last_contact_ms"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataConsulKeys_wait(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				// The index is never reached so the query times out and
+				// returns the current values
+				Config: testAccDataConsulKeysConfigWait,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysValue("data.consul_keys.read", "first", "1"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "second", "2"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "missing", "default"),
+					resource.TestCheckResourceAttrSet("data.consul_keys.read", "modify_index"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataConsulKeys_minModifyIndex(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccDataConsulKeysConfigMinModifyIndex, 1, ""),
+				Check:  testAccCheckConsulKeysValue("data.consul_keys.read", "first", "1"),
+			},
+			{
+				Config:      fmt.Sprintf(testAccDataConsulKeysConfigMinModifyIndex, 1000000000, ""),
+				ExpectError: regexp.MustCompile(`Consul key 'test/min/first' has the modify index \d+, at least 1000000000 is expected`),
+			},
+			{
+				// The key is read again until the timeout elapses
+				Config:      fmt.Sprintf(testAccDataConsulKeysConfigMinModifyIndex, 1000000000, `min_modify_index_timeout = "1s"`),
+				ExpectError: regexp.MustCompile(`Consul key 'test/min/first' still has the modify index \d+ after waiting 1s`),
+			},
+		},
+	})
+}
+
+func TestAccDataConsulKeys_fallbackPaths(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccDataConsulKeysConfigFallbackPaths, `default = "default"`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysValue("data.consul_keys.read", "first", "first"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "second", "second"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "missing", "default"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "matched_paths.first", "test/fallback/first"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "matched_paths.second", "test/fallback/second"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "matched_paths.missing", ""),
+				),
+			},
+			{
+				Config:      fmt.Sprintf(testAccDataConsulKeysConfigFallbackPaths, ""),
+				ExpectError: regexp.MustCompile(`none of the paths of key "missing" exist and it has no default: test/fallback/missing, test/fallback/unknown`),
+			},
+		},
+	})
+}
+
+func TestCommonPrefix(t *testing.T) {
+	cases := map[string]struct {
+		paths    []string
+		expected string
+	}{
+		"empty":     {nil, ""},
+		"single":    {[]string{"app/config"}, "app/config"},
+		"shared":    {[]string{"app/db/host", "app/db/port", "app/name"}, "app/"},
+		"unrelated": {[]string{"app", "web"}, ""},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := commonPrefix(c.paths); got != c.expected {
+				t.Fatalf("expected %q, got %q", c.expected, got)
+			}
+		})
+	}
+}
+
+func TestAccDataConsulKeys_decodeJSON(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulKeysConfigDecodeJSON,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.consul_keys.read", "var.read", `{"db":{"host":"localhost"},"name":"app","port":8080,"tags":["a","b"]}`),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "decoded.%", "5"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "decoded.read.name", "app"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "decoded.read.port", "8080"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "decoded.read.tags.1", "b"),
+					resource.TestCheckResourceAttr("data.consul_keys.read", "decoded.read.db.host", "localhost"),
+				),
+			},
+			{
+				Config:      testAccDataConsulKeysConfigDecodeInvalidJSON,
+				ExpectError: regexp.MustCompile(`failed to decode the value of "test/not_json" as a JSON object`),
+			},
+		},
+	})
+}
+
+func TestFlattenJSON(t *testing.T) {
+	value := map[string]interface{}{
+		"name":    "app",
+		"enabled": true,
+		"port":    float64(8080),
+		"empty":   nil,
+		"tags":    []interface{}{"a", "b"},
+		"db": map[string]interface{}{
+			"host": "localhost",
+		},
+	}
+
+	result := map[string]string{}
+	flattenJSON("app", value, result)
+
+	expected := map[string]string{
+		"app.name":    "app",
+		"app.enabled": "true",
+		"app.port":    "8080",
+		"app.empty":   "",
+		"app.tags.0":  "a",
+		"app.tags.1":  "b",
+		"app.db.host": "localhost",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected result, got %#v, expected %#v", result, expected)
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	result, err := renderTemplates(map[string]string{
+		"host": "db.example.com",
+		"port": "5432",
+		"addr": "{{ .Keys.host }}:{{ .Keys.port }}",
+		"url":  `postgres://{{ index .Keys "addr" }}/app`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"host": "db.example.com",
+		"port": "5432",
+		"addr": "db.example.com:5432",
+		"url":  "postgres://db.example.com:5432/app",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected result, got %#v, expected %#v", result, expected)
+	}
+
+	errors := map[string]map[string]string{
+		"cyclic reference": {
+			"a": "{{ .Keys.b }}",
+			"b": "{{ if .Keys.a }}yes{{ end }}",
+		},
+		`key "c" does not exist`: {
+			"a": "{{ .Keys.c }}",
+		},
+		"failed to parse the template": {
+			"a": "{{ .Keys.b",
+		},
+	}
+	for expectedErr, values := range errors {
+		_, err := renderTemplates(values)
+		if err == nil {
+			t.Fatalf("expected an error for %#v", values)
+		}
+		if !regexp.MustCompile(regexp.QuoteMeta(expectedErr)).MatchString(err.Error()) {
+			t.Fatalf("expected error %q, got %q", expectedErr, err)
+		}
+	}
+}
+
 func TestAccDataConsulKeys_namespaceCE(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -35,7 +245,7 @@ func TestAccDataConsulKeys_namespaceCE(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      testAccDataConsulKeysConfigNamespaceCE,
-				ExpectError: regexp.MustCompile("Unexpected response code: 400"),
+				ExpectError: namespaceEnterpriseFeature,
 			},
 		},
 	})
@@ -94,6 +304,169 @@ data "consul_keys" "read" {
 }
 `
 
+const testAccDataConsulKeysConfigAllowStale = `
+resource "consul_keys" "write" {
+  datacenter = "dc1"
+
+  key {
+    path  = "test/data_source"
+    value = "written"
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter  = consul_keys.write.datacenter
+  allow_stale = true
+
+  key {
+    path = "test/data_source"
+    name = "read"
+  }
+}
+`
+
+const testAccDataConsulKeysConfigMinModifyIndex = `
+resource "consul_keys" "write" {
+  key {
+    path  = "test/min/first"
+    value = "1"
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.write.datacenter
+  %[2]s
+
+  key {
+    path             = "test/min/first"
+    name             = "first"
+    min_modify_index = %[1]d
+  }
+}
+`
+
+const testAccDataConsulKeysConfigFallbackPaths = `
+resource "consul_keys" "write" {
+  key {
+    path  = "test/fallback/first"
+    value = "first"
+  }
+
+  key {
+    path  = "test/fallback/second"
+    value = "second"
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.write.datacenter
+
+  key {
+    path           = "test/fallback/first"
+    name           = "first"
+    fallback_paths = ["test/fallback/second"]
+  }
+
+  key {
+    path           = "test/fallback/override"
+    name           = "second"
+    fallback_paths = ["test/fallback/second", "test/fallback/first"]
+  }
+
+  key {
+    path           = "test/fallback/missing"
+    name           = "missing"
+    fallback_paths = ["test/fallback/unknown"]
+    %s
+  }
+}
+`
+
+const testAccDataConsulKeysConfigWait = `
+resource "consul_keys" "write" {
+  datacenter = "dc1"
+
+  key {
+    path  = "test/wait/first"
+    value = "1"
+  }
+
+  key {
+    path  = "test/wait/second"
+    value = "2"
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter   = consul_keys.write.datacenter
+  wait_index   = 1000000000
+  wait_timeout = "1s"
+
+  key {
+    path = "test/wait/first"
+    name = "first"
+  }
+
+  key {
+    path = "test/wait/second"
+    name = "second"
+  }
+
+  key {
+    path    = "test/wait/missing"
+    name    = "missing"
+    default = "default"
+  }
+}
+`
+
+const testAccDataConsulKeysConfigDecodeJSON = `
+resource "consul_keys" "write" {
+  datacenter = "dc1"
+
+  key {
+    path  = "test/json"
+    value = jsonencode({
+      name = "app"
+      port = 8080
+      tags = ["a", "b"]
+      db   = { host = "localhost" }
+    })
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.write.datacenter
+
+  key {
+    path        = "test/json"
+    name        = "read"
+    decode_json = true
+  }
+}
+`
+
+const testAccDataConsulKeysConfigDecodeInvalidJSON = `
+resource "consul_keys" "write" {
+  datacenter = "dc1"
+
+  key {
+    path  = "test/not_json"
+    value = "hello"
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.write.datacenter
+
+  key {
+    path        = "test/not_json"
+    name        = "read"
+    decode_json = true
+  }
+}
+`
+
 const testAccDataConsulKeysConfigNamespaceCE = `
 data "consul_keys" "read" {
   namespace  = "test-data-consul-keys"
@@ -0,0 +1,141 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func dataSourceConsulKVMetadata() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulKVMetadataRead,
+
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path of the key to read.",
+			},
+
+			"include_value": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether to export the value of the key. It can be disabled for large values that are not needed.",
+			},
+
+			"exists": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the key exists, the other attributes are empty when it does not.",
+			},
+
+			"value": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The value of the key, empty when `include_value` is `false`.",
+			},
+
+			"create_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The index at which the key was created.",
+			},
+
+			"modify_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The index at which the key was last modified.",
+			},
+
+			"lock_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of times the lock of the key has been acquired.",
+			},
+
+			"flags": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The flags of the key.",
+			},
+
+			"session": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the session holding the lock of the key, if any.",
+			},
+
+			"allow_stale": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"require_consistent": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_stale"},
+				Description:   "Whether the key must be read in the consistent mode, the leader then confirms its leadership with a quorum of servers before answering.",
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func dataSourceConsulKVMetadataRead(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta,
+		withAllowStale(d.Get("allow_stale").(bool)),
+		withRequireConsistent(d.Get("require_consistent").(bool)),
+		withMetadataOnly(!d.Get("include_value").(bool)),
+		withReadCache(),
+	)
+	ctx := stopContext(meta)
+
+	path := d.Get("path").(string)
+
+	// A missing key is not an error, a zero entry is returned and exists is
+	// set to false
+	entry, _, err := keyClient.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	sw := newStateWriter(d)
+	sw.set("exists", entry.modifyIndex != 0)
+	sw.set("value", entry.value)
+	sw.set("create_index", int(entry.createIndex))
+	sw.set("modify_index", int(entry.modifyIndex))
+	sw.set("lock_index", int(entry.lockIndex))
+	sw.set("flags", entry.flags)
+	sw.set("session", entry.session)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId("-")
+
+	return nil
+}
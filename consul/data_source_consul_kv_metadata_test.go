@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccDataConsulKVMetadata_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulKVMetadataConfig,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "exists", "true"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "value", "bar"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "flags", "4"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "lock_index", "0"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "session", ""),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "datacenter", "dc1"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.no_value", "exists", "true"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.no_value", "value", ""),
+					resource.TestCheckResourceAttrPair("data.consul_kv_metadata.test", "modify_index", "data.consul_kv_metadata.no_value", "modify_index"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.missing", "exists", "false"),
+					resource.TestCheckResourceAttr("data.consul_kv_metadata.missing", "modify_index", "0"),
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/metadata", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil {
+							return fmt.Errorf("key 'test/metadata' does not exist")
+						}
+						return resource.ComposeTestCheckFunc(
+							resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "create_index", fmt.Sprint(pair.CreateIndex)),
+							resource.TestCheckResourceAttr("data.consul_kv_metadata.test", "modify_index", fmt.Sprint(pair.ModifyIndex)),
+						)(s)
+					},
+				),
+			},
+		},
+	})
+}
+
+const testAccDataConsulKVMetadataConfig = `
+resource "consul_keys" "write" {
+  key {
+    path  = "test/metadata"
+    value = "bar"
+    flags = 4
+  }
+}
+
+data "consul_kv_metadata" "test" {
+  path = "test/metadata"
+
+  depends_on = [consul_keys.write]
+}
+
+data "consul_kv_metadata" "no_value" {
+  path          = "test/metadata"
+  include_value = false
+
+  depends_on = [consul_keys.write]
+}
+
+data "consul_kv_metadata" "missing" {
+  path = "test/missing"
+}
+`
@@ -0,0 +1,191 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+func dataSourceConsulKVPrefix() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulKVPrefixRead,
+
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"path_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The prefix of the keys to read.",
+			},
+
+			"recurse_separator": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "When set, only the keys whose path after `path_prefix` does not contain the separator are returned.",
+			},
+
+			"filter_flags": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "When set, only the keys that have all the bits of `filter_flags` set in their flags are returned.",
+			},
+
+			"page_size": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, maxTxnOps),
+				Description:  "When set, the keys are read this many at a time instead of in a single request, for prefixes holding a large number of keys.",
+			},
+
+			"include_values": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the values of the keys are read. When false, only the paths of the keys are listed to compute `key_count`.",
+			},
+
+			"subkeys": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The values of the keys found under `path_prefix`, indexed by their path with the prefix removed.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"highest_modify_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The highest modify index of the keys under `path_prefix`, it changes each time one of them is written.",
+			},
+
+			"key_count": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of keys under `path_prefix`.",
+			},
+
+			"allow_stale": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"require_consistent": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"allow_stale"},
+				Description:   "Whether the keys must be read in the consistent mode, the leader then confirms its leadership with a quorum of servers before answering.",
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func dataSourceConsulKVPrefixRead(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta,
+		withAllowStale(d.Get("allow_stale").(bool)),
+		withRequireConsistent(d.Get("require_consistent").(bool)),
+		withFlagsFilter(uint64(d.Get("filter_flags").(int))),
+		withReadCache(),
+	)
+	ctx := stopContext(meta)
+
+	pathPrefix := d.Get("path_prefix").(string)
+	separator := d.Get("recurse_separator").(string)
+
+	// Counting the keys does not require to read their values
+	if !d.Get("include_values").(bool) {
+		if d.Get("filter_flags").(int) != 0 {
+			return fmt.Errorf("filter_flags cannot be used when include_values is false, the flags are stored with the values")
+		}
+		paths, _, err := keyClient.ListKeys(ctx, pathPrefix)
+		if err != nil {
+			return err
+		}
+
+		sw := newStateWriter(d)
+		sw.set("subkeys", map[string]string{})
+		sw.set("highest_modify_index", 0)
+		sw.set("key_count", len(paths))
+		sw.set("datacenter", keyClient.qOpts.Datacenter)
+		if err := sw.error(); err != nil {
+			return err
+		}
+
+		d.SetId("-")
+
+		return nil
+	}
+
+	var pairs consulapi.KVPairs
+	var err error
+	if pageSize := d.Get("page_size").(int); pageSize > 0 {
+		pairs, _, err = keyClient.GetUnderPrefixPaged(ctx, pathPrefix, pageSize)
+	} else {
+		pairs, _, err = keyClient.GetUnderPrefix(ctx, pathPrefix)
+	}
+	if err != nil {
+		return err
+	}
+
+	// An empty prefix is not an error, we just return no subkeys
+	subKeys := make(map[string]string, len(pairs))
+	var highestModifyIndex uint64
+	for _, pair := range pairs {
+		// The indexes account for all the keys, even those filtered out below
+		// by the separator. The keys filtered out by their flags are never
+		// returned.
+		if pair.ModifyIndex > highestModifyIndex {
+			highestModifyIndex = pair.ModifyIndex
+		}
+
+		subKey := pair.Key[len(pathPrefix):]
+		if subKey == "" {
+			continue
+		}
+		if separator != "" && strings.Contains(subKey, separator) {
+			continue
+		}
+		subKeys[subKey] = string(pair.Value)
+	}
+
+	sw := newStateWriter(d)
+	sw.set("subkeys", subKeys)
+	sw.set("highest_modify_index", int(highestModifyIndex))
+	sw.set("key_count", len(pairs))
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId("-")
+
+	return nil
+}
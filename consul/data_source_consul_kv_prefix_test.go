@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccDataConsulKVPrefix_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulKVPrefixConfig,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "subkeys.%", "3"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "subkeys.name", "app"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "subkeys.port", "8080"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "subkeys.db/host", "localhost"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "datacenter", "dc1"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.top", "subkeys.%", "2"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.top", "subkeys.name", "app"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.top", "subkeys.port", "8080"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "key_count", "3"),
+					resource.TestCheckResourceAttrPair("data.consul_kv_prefix.all", "highest_modify_index", "data.consul_kv_prefix.top", "highest_modify_index"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.top", "key_count", "3"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.empty", "subkeys.%", "0"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.empty", "key_count", "0"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.empty", "highest_modify_index", "0"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.managed", "subkeys.%", "1"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.managed", "subkeys.db/host", "localhost"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.managed", "key_count", "1"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.paged", "subkeys.%", "3"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.paged", "subkeys.name", "app"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.paged", "subkeys.port", "8080"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.paged", "subkeys.db/host", "localhost"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.paged", "key_count", "3"),
+					resource.TestCheckResourceAttrPair("data.consul_kv_prefix.all", "highest_modify_index", "data.consul_kv_prefix.paged", "highest_modify_index"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.count", "subkeys.%", "0"),
+					resource.TestCheckResourceAttr("data.consul_kv_prefix.count", "key_count", "3"),
+					func(s *terraform.State) error {
+						pairs, _, err := client.KV().List("kv-prefix/config/", nil)
+						if err != nil {
+							return err
+						}
+						var highest uint64
+						for _, pair := range pairs {
+							if pair.ModifyIndex > highest {
+								highest = pair.ModifyIndex
+							}
+						}
+						return resource.TestCheckResourceAttr("data.consul_kv_prefix.all", "highest_modify_index", fmt.Sprint(highest))(s)
+					},
+				),
+			},
+		},
+	})
+}
+
+const testAccDataConsulKVPrefixConfig = `
+resource "consul_key_prefix" "write" {
+  path_prefix = "kv-prefix/config/"
+
+  subkeys = {
+    "name" = "app"
+    "port" = "8080"
+  }
+
+  subkey {
+    path  = "db/host"
+    value = "localhost"
+    flags = 6
+  }
+}
+
+data "consul_kv_prefix" "all" {
+  path_prefix = consul_key_prefix.write.path_prefix
+}
+
+data "consul_kv_prefix" "paged" {
+  path_prefix = consul_key_prefix.write.path_prefix
+  page_size   = 2
+}
+
+data "consul_kv_prefix" "count" {
+  path_prefix    = consul_key_prefix.write.path_prefix
+  include_values = false
+}
+
+data "consul_kv_prefix" "top" {
+  path_prefix       = consul_key_prefix.write.path_prefix
+  recurse_separator = "/"
+}
+
+data "consul_kv_prefix" "managed" {
+  path_prefix  = consul_key_prefix.write.path_prefix
+  filter_flags = 4
+}
+
+data "consul_kv_prefix" "empty" {
+  path_prefix = "kv-prefix/missing/"
+}
+`
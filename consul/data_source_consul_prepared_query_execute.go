@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+func dataSourceConsulPreparedQueryExecute() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulPreparedQueryExecuteRead,
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"query": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.NoZeroValues,
+				Description:  "The ID or the name of the prepared query to execute.",
+			},
+
+			"near": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The node to sort the instances by, `_agent` can be used to sort them by their distance to the agent. This overrides the `near` attribute of the prepared query.",
+			},
+
+			"service": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The name of the service that was queried.",
+			},
+
+			"result_datacenter": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The datacenter the instances were found in, it differs from `datacenter` when the query failed over to another datacenter.",
+			},
+
+			"failovers": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of remote datacenters that were queried before finding the instances.",
+			},
+
+			"instances": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The instances of the service returned by the query, empty when none was found.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"node": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"node_address": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"service_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"address": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"port": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"tags": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"meta": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"health": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceConsulPreparedQueryExecuteRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+	qOpts.Near = d.Get("near").(string)
+
+	query := d.Get("query").(string)
+	result, _, err := client.PreparedQuery().Execute(query, qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to execute prepared query %q: %v", query, err)
+	}
+
+	// A query that does not find any instance is not an error, the caller
+	// decides how to handle it
+	instances := make([]interface{}, 0, len(result.Nodes))
+	for _, entry := range result.Nodes {
+		instances = append(instances, flattenPreparedQueryInstance(entry))
+	}
+
+	sw := newStateWriter(d)
+	sw.set("service", result.Service)
+	sw.set("result_datacenter", result.Datacenter)
+	sw.set("failovers", result.Failovers)
+	sw.set("instances", instances)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("prepared-query-execute-%s-%s", qOpts.Datacenter, query))
+
+	return nil
+}
+
+// flattenPreparedQueryInstance returns an instance of the service found by a
+// prepared query. Like the DNS interface, the address of the node is used
+// when the service does not register its own.
+func flattenPreparedQueryInstance(entry consulapi.ServiceEntry) map[string]interface{} {
+	address := entry.Service.Address
+	if address == "" {
+		address = entry.Node.Address
+	}
+	return map[string]interface{}{
+		"node":         entry.Node.Node,
+		"node_address": entry.Node.Address,
+		"service_id":   entry.Service.ID,
+		"address":      address,
+		"port":         entry.Service.Port,
+		"tags":         entry.Service.Tags,
+		"meta":         entry.Service.Meta,
+		"health":       entry.Checks.AggregatedStatus(),
+	}
+}
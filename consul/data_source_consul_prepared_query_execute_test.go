@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccDataConsulPreparedQueryExecute_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulPreparedQueryExecute,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "datacenter", "dc1"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "service", "consul"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "result_datacenter", "dc1"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "failovers", "0"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.#", "1"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.0.node", "<any>"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.0.address", "127.0.0.1"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.0.service_id", "consul"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.0.port", "8300"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_name", "instances.0.health", "passing"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.by_id", "instances.#", "1"),
+
+					// No instance is not an error
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.empty", "service", "missing"),
+					testAccCheckDataSourceValue("data.consul_prepared_query_execute.empty", "instances.#", "0"),
+				),
+			},
+			{
+				Config:      testAccDataConsulPreparedQueryExecuteUnknown,
+				ExpectError: regexp.MustCompile(`failed to execute prepared query "unknown"`),
+			},
+		},
+	})
+}
+
+const testAccDataConsulPreparedQueryExecute = `
+resource "consul_prepared_query" "consul" {
+  name    = "consul-servers"
+  service = "consul"
+}
+
+resource "consul_prepared_query" "missing" {
+  name    = "missing-service"
+  service = "missing"
+}
+
+data "consul_prepared_query_execute" "by_name" {
+  query = consul_prepared_query.consul.name
+}
+
+data "consul_prepared_query_execute" "by_id" {
+  query = consul_prepared_query.consul.id
+}
+
+data "consul_prepared_query_execute" "empty" {
+  query = consul_prepared_query.missing.name
+}
+`
+
+const testAccDataConsulPreparedQueryExecuteUnknown = `
+data "consul_prepared_query_execute" "unknown" {
+  query = "unknown"
+}
+`
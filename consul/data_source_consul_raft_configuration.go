@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func dataSourceConsulRaftConfiguration() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulRaftConfigurationRead,
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"allow_stale": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the configuration can be read from a server that is not the leader, this is useful to inspect a cluster that lost its leader.",
+			},
+
+			"index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The Raft index of the configuration.",
+			},
+
+			"leader": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The address of the current leader, empty when the cluster has no leader.",
+			},
+
+			"servers": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The servers in the Raft peer set.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"node": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"address": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"leader": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"voter": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"protocol_version": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"last_index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceConsulRaftConfigurationRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+	qOpts.AllowStale = d.Get("allow_stale").(bool)
+
+	config, err := client.Operator().RaftGetConfiguration(qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to read the Raft configuration: %v", err)
+	}
+
+	var leader string
+	servers := make([]interface{}, 0, len(config.Servers))
+	for _, server := range config.Servers {
+		if server.Leader {
+			leader = server.Address
+		}
+		servers = append(servers, flattenRaftServer(server))
+	}
+
+	sw := newStateWriter(d)
+	sw.set("index", int(config.Index))
+	sw.set("leader", leader)
+	sw.set("servers", servers)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("raft-configuration-%s", qOpts.Datacenter))
+
+	return nil
+}
+
+func flattenRaftServer(server *consulapi.RaftServer) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               server.ID,
+		"node":             server.Node,
+		"address":          server.Address,
+		"leader":           server.Leader,
+		"voter":            server.Voter,
+		"protocol_version": server.ProtocolVersion,
+		"last_index":       int(server.LastIndex),
+	}
+}
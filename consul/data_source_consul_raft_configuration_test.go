@@ -0,0 +1,39 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccDataConsulRaftConfiguration_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataRaftConfiguration,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "datacenter", "dc1"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "index", "<any>"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "leader", "<any>"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.#", "1"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.id", "<any>"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.node", "<any>"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.address", "<any>"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.leader", "true"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.voter", "true"),
+					testAccCheckDataSourceValue("data.consul_raft_configuration.read", "servers.0.protocol_version", "<any>"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataRaftConfiguration = `
+data "consul_raft_configuration" "read" {}
+`
@@ -4,6 +4,7 @@
 package consul
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -12,6 +13,7 @@ import (
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func dataSourceConsulServiceHealth() *schema.Resource {
@@ -55,8 +57,34 @@ func dataSourceConsulServiceHealth() *schema.Resource {
 				Optional: true,
 				Type:     schema.TypeString,
 			},
+			"wait_for_healthy": {
+				Optional:      true,
+				Type:          schema.TypeBool,
+				Default:       false,
+				ConflictsWith: []string{"wait_for"},
+				Description:   "Whether to block until at least `min_instances` instances of the service are passing.",
+			},
+			"min_instances": {
+				Optional:     true,
+				Type:         schema.TypeInt,
+				Default:      1,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "The number of passing instances to wait for when `wait_for_healthy` is set.",
+			},
+			"timeout": {
+				Optional:     true,
+				Type:         schema.TypeString,
+				Default:      "5m",
+				ValidateFunc: validateDurationMinFactory("timeout", "0s"),
+				Description:  "The maximum time to wait for the instances to be healthy when `wait_for_healthy` is set.",
+			},
 
 			// Out parameters
+			"healthy_instances": {
+				Computed:    true,
+				Type:        schema.TypeInt,
+				Description: "The number of instances of the service whose checks are all passing.",
+			},
 			"results": {
 				Computed: true,
 				Type:     schema.TypeList,
@@ -213,7 +241,16 @@ func dataSourceConsulServiceHealthRead(d *schema.ResourceData, meta interface{})
 
 	var err error
 	var serviceEntries []*consulapi.ServiceEntry
-	if d.Get("wait_for").(string) == "" || !passingOnly {
+	if d.Get("wait_for_healthy").(bool) {
+		// The duration has already been validated by the schema
+		timeout, _ := time.ParseDuration(d.Get("timeout").(string))
+		minInstances := d.Get("min_instances").(int)
+		log.Printf("[INFO] Waiting up to %s for %d instances of service '%s' to be healthy", timeout, minInstances, serviceName)
+		serviceEntries, err = waitForHealthyInstances(stopContext(meta), health, serviceName, serviceTag, passingOnly, qOps, minInstances, timeout)
+		if err != nil {
+			return err
+		}
+	} else if d.Get("wait_for").(string) == "" || !passingOnly {
 		log.Printf("[INFO] Fetching health information for service '%s'", serviceName)
 		serviceEntries, _, err = health.Service(serviceName, serviceTag, passingOnly, qOps)
 		if err != nil {
@@ -309,6 +346,49 @@ func dataSourceConsulServiceHealthRead(d *schema.ResourceData, meta interface{})
 	if err = d.Set("results", results); err != nil {
 		return fmt.Errorf("Failed to set 'results': %s", err)
 	}
+	if err = d.Set("healthy_instances", countHealthyInstances(serviceEntries)); err != nil {
+		return fmt.Errorf("Failed to set 'healthy_instances': %s", err)
+	}
 
 	return nil
 }
+
+// waitForHealthyInstances uses blocking queries to wait until at least
+// minInstances instances of the service are passing, or until the timeout
+// expires.
+func waitForHealthyInstances(ctx context.Context, health *consulapi.Health, service, tag string, passingOnly bool, qOpts *consulapi.QueryOptions, minInstances int, timeout time.Duration) ([]*consulapi.ServiceEntry, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		entries, meta, err := health.Service(service, tag, passingOnly, qOpts.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to retrieve service health: %v", err)
+		}
+
+		healthy := countHealthyInstances(entries)
+		if healthy >= minInstances {
+			return entries, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("timed out after %s waiting for %d healthy instances of service %q, %d are passing", timeout, minInstances, service, healthy)
+		}
+		log.Printf("[DEBUG] %d instances of service '%s' are passing, waiting for %d", healthy, service, minInstances)
+
+		// Consul returns as soon as the health of the service changes
+		qOpts.WaitIndex = meta.LastIndex
+		qOpts.WaitTime = remaining
+	}
+}
+
+// countHealthyInstances returns the number of entries whose checks are all
+// passing.
+func countHealthyInstances(entries []*consulapi.ServiceEntry) int {
+	count := 0
+	for _, entry := range entries {
+		if entry.Checks.AggregatedStatus() == consulapi.HealthPassing {
+			count++
+		}
+	}
+	return count
+}
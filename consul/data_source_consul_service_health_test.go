@@ -4,8 +4,16 @@
 package consul
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 )
 
@@ -82,6 +90,7 @@ func TestAccDataConsulServiceHealthPassing(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckDataSourceValue("data.consul_service_health.google", "name", "google"),
 					testAccCheckDataSourceValue("data.consul_service_health.google", "passing", "false"),
+					testAccCheckDataSourceValue("data.consul_service_health.google", "healthy_instances", "1"),
 					testAccCheckDataSourceValue("data.consul_service_health.google", "results.#", "2"),
 				),
 			},
@@ -90,9 +99,18 @@ func TestAccDataConsulServiceHealthPassing(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckDataSourceValue("data.consul_service_health.google", "name", "google"),
 					testAccCheckDataSourceValue("data.consul_service_health.google", "passing", "true"),
+					testAccCheckDataSourceValue("data.consul_service_health.google", "healthy_instances", "1"),
 					testAccCheckDataSourceValue("data.consul_service_health.google", "results.#", "1"),
 				),
 			},
+			{
+				Config: testAccDataConsulServiceHealthWaitForHealthy,
+				Check:  testAccCheckDataSourceValue("data.consul_service_health.google", "healthy_instances", "1"),
+			},
+			{
+				Config:      testAccDataConsulServiceHealthWaitForHealthyTimeout,
+				ExpectError: regexp.MustCompile(`timed out after 2s waiting for 2 healthy instances of service "google", 1 are passing`),
+			},
 		},
 	})
 }
@@ -111,6 +129,50 @@ func TestAccDataConsulServiceHealthDatacenter(t *testing.T) {
 	})
 }
 
+func TestWaitForHealthyInstances(t *testing.T) {
+	passing := &consulapi.ServiceEntry{
+		Node:    &consulapi.Node{Node: "web-1"},
+		Service: &consulapi.AgentService{Service: "web"},
+		Checks:  consulapi.HealthChecks{{Status: consulapi.HealthPassing}},
+	}
+	critical := &consulapi.ServiceEntry{
+		Node:    &consulapi.Node{Node: "web-2"},
+		Service: &consulapi.AgentService{Service: "web"},
+		Checks:  consulapi.HealthChecks{{Status: consulapi.HealthCritical}},
+	}
+
+	// The second instance becomes healthy at index 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entries := []*consulapi.ServiceEntry{passing, critical}
+		index := "1"
+		if r.URL.Query().Get("index") != "" {
+			entries = []*consulapi.ServiceEntry{passing, passing}
+			index = "2"
+		}
+		w.Header().Set("X-Consul-Index", index)
+		json.NewEncoder(w).Encode(entries)
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := waitForHealthyInstances(context.Background(), client.Health(), "web", "", false, &consulapi.QueryOptions{}, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if countHealthyInstances(entries) != 2 {
+		t.Fatalf("expected 2 healthy instances, got %d", countHealthyInstances(entries))
+	}
+
+	_, err = waitForHealthyInstances(context.Background(), client.Health(), "web", "", false, &consulapi.QueryOptions{}, 3, 0)
+	if err == nil || !strings.Contains(err.Error(), `waiting for 3 healthy instances of service "web"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 const testAccDataConsulServiceHealth = `
 data "consul_service_health" "consul" {
 	name   = "consul"
@@ -218,6 +280,23 @@ data "consul_service_health" "google" {
 }
 `
 
+const testAccDataConsulServiceHealthWaitForHealthy = testAccDataConsulServiceHealthPassingSetup + `
+data "consul_service_health" "google" {
+  name             = "google"
+  wait_for_healthy = true
+  timeout          = "10s"
+}
+`
+
+const testAccDataConsulServiceHealthWaitForHealthyTimeout = testAccDataConsulServiceHealthPassingSetup + `
+data "consul_service_health" "google" {
+  name             = "google"
+  wait_for_healthy = true
+  min_instances    = 2
+  timeout          = "2s"
+}
+`
+
 const testAccDataConsulServiceHealthDatacenter = `
 data "consul_service_health" "consul" {
 	name       = "consul"
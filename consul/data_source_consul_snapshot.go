@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func dataSourceConsulSnapshot() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceConsulSnapshotRead,
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"allow_stale": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the snapshot can be taken by a server that is not the leader.",
+			},
+
+			"output_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The local path the snapshot is written to. When not set, only its checksum is computed.",
+			},
+
+			"sha256": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The SHA-256 checksum of the snapshot.",
+			},
+
+			"size": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The size of the snapshot in bytes.",
+			},
+
+			"index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The Raft index of the snapshot.",
+			},
+		},
+	}
+}
+
+func dataSourceConsulSnapshotRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+	qOpts.AllowStale = d.Get("allow_stale").(bool)
+
+	snapshot, qMeta, err := client.Snapshot().Save(qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to save the snapshot: %v", err)
+	}
+	defer snapshot.Close()
+
+	// Snapshots can be large, they are streamed to disk instead of being kept
+	// in memory
+	outputPath := d.Get("output_path").(string)
+	var dest io.Writer = io.Discard
+	var file *os.File
+	if outputPath != "" {
+		// The snapshot is written to a temporary file first so that a failed
+		// save never replaces a previous snapshot with a truncated one
+		file, err = os.CreateTemp(filepath.Dir(outputPath), filepath.Base(outputPath)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to create the snapshot file: %v", err)
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		dest = file
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dest, hash), snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to save the snapshot: %v", err)
+	}
+
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write the snapshot to %q: %v", outputPath, err)
+		}
+		if err := os.Rename(file.Name(), outputPath); err != nil {
+			return fmt.Errorf("failed to write the snapshot to %q: %v", outputPath, err)
+		}
+		log.Printf("[DEBUG] Saved a snapshot of %d bytes to %q", size, outputPath)
+	}
+
+	sw := newStateWriter(d)
+	sw.set("sha256", hex.EncodeToString(hash.Sum(nil)))
+	sw.set("size", int(size))
+	sw.set("index", int(qMeta.LastIndex))
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	d.SetId(fmt.Sprintf("snapshot-%s-%d", qOpts.Datacenter, qMeta.LastIndex))
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccDataConsulSnapshot_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	path := filepath.Join(t.TempDir(), "consul.snap")
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataConsulSnapshotConfig(path),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.consul_snapshot.backup", "datacenter", "dc1"),
+					resource.TestCheckResourceAttrSet("data.consul_snapshot.backup", "index"),
+					resource.TestCheckResourceAttrSet("data.consul_snapshot.backup", "size"),
+					func(s *terraform.State) error {
+						sum, err := fileSHA256(path)
+						if err != nil {
+							return err
+						}
+						return resource.TestCheckResourceAttr("data.consul_snapshot.backup", "sha256", sum)(s)
+					},
+				),
+			},
+		},
+	})
+}
+
+func testAccDataConsulSnapshotConfig(path string) string {
+	return fmt.Sprintf(`
+data "consul_snapshot" "backup" {
+  output_path = %q
+}`, path)
+}
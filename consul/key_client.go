@@ -4,95 +4,1331 @@
 package consul
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
-	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/go-uuid"
+	"golang.org/x/time/rate"
 )
 
+// maxTxnOps is the maximum number of operations Consul accepts in a single
+// transaction with its default configuration.
+const maxTxnOps = 64
+
+// kvFlagCompressed is the flag bit reserved by the provider to mark the
+// values that are stored gzip-compressed in Consul. Like kvFlagEncrypted, a
+// high bit is used so that it does not collide with the flags already set by
+// users.
+const kvFlagCompressed = 0x20000000
+
+// kvFlagEncrypted is the flag bit reserved by the provider to mark the values
+// that are stored encrypted with the encryption_key of the provider.
+const kvFlagEncrypted = 0x40000000
+
+// kvFlagsReserved are the flag bits that cannot be set by users.
+const kvFlagsReserved = kvFlagCompressed | kvFlagEncrypted
+
 // keyClient is a wrapper around the upstream Consul client that is
 // specialized for Terraform's manipulations of the key/value store.
 type keyClient struct {
-	client *consulapi.KV
-	qOpts  *consulapi.QueryOptions
-	wOpts  *consulapi.WriteOptions
+	client   *consulapi.KV
+	sessions *sessionClient
+	qOpts    *consulapi.QueryOptions
+	wOpts    *consulapi.WriteOptions
+	retry    retryPolicy
+
+	// requestID identifies the operations made by this client in the logs
+	requestID string
+
+	// encryptionKey is the AES-256 key used for the values that have the
+	// kvFlagEncrypted bit set
+	encryptionKey []byte
+
+	// defaultFlags are the flags written when none are given, see
+	// writeFlags
+	defaultFlags int
+
+	// The limiters shared by all the clients, see newRateLimiter
+	writeLimiter *rate.Limiter
+	readLimiter  *rate.Limiter
+
+	// flagsFilter are the flags the keys returned by GetUnderPrefix must
+	// have, all the keys are returned when it is 0
+	flagsFilter uint64
+
+	// readDatacenters are the datacenters Get and GetUnderPrefix fall back
+	// to, in order, when the datacenter of the client cannot be reached
+	readDatacenters []string
+
+	// metadataOnly makes Get skip the decoding of the values
+	metadataOnly bool
+
+	// rawValues disables the encoding of the values, see withRawValues
+	rawValues bool
+
+	// maxValueBytes is the maximum size of the values accepted by Consul,
+	// they are not checked when it is 0
+	maxValueBytes int
+
+	// timeout is the maximum duration of each attempt of an operation, there
+	// is none when it is 0
+	timeout time.Duration
+
+	// readCache is shared by all the clients, it is nil when the cache is
+	// disabled in the provider. It is only used by the clients created
+	// withReadCache.
+	readCache    *readCache
+	useReadCache bool
+
+	// metrics is nil when the requests are not measured
+	metrics *kvMetrics
+}
+
+// keyClientOption customizes a keyClient returned by newKeyClient.
+type keyClientOption func(*keyClient)
+
+// withAllowStale overrides the consistency mode of the reads made by the
+// client.
+func withAllowStale(allowStale bool) keyClientOption {
+	return func(c *keyClient) {
+		// Copy the options so the override only affects this client
+		qOpts := *c.qOpts
+		qOpts.AllowStale = allowStale
+		c.qOpts = &qOpts
+	}
 }
 
-func newKeyClient(d *schema.ResourceData, meta interface{}) *keyClient {
+// withRequireConsistent makes the reads of the client use the consistent
+// mode: the leader confirms its leadership with a quorum of servers before
+// answering, so a read never returns data older than the last write.
+func withRequireConsistent(requireConsistent bool) keyClientOption {
+	return func(c *keyClient) {
+		qOpts := *c.qOpts
+		qOpts.RequireConsistent = requireConsistent
+		c.qOpts = &qOpts
+	}
+}
+
+// withWait turns the reads made by the client into blocking queries that
+// return once the index of the data is greater than index, or when wait
+// elapses.
+func withWait(index uint64, wait time.Duration) keyClientOption {
+	return func(c *keyClient) {
+		qOpts := *c.qOpts
+		qOpts.WaitIndex = index
+		qOpts.WaitTime = wait
+		c.qOpts = &qOpts
+	}
+}
+
+// withDatacenter makes the client read and write the keys of another
+// datacenter.
+func withDatacenter(dc string) keyClientOption {
+	return func(c *keyClient) {
+		qOpts := *c.qOpts
+		qOpts.Datacenter = dc
+		c.qOpts = &qOpts
+		wOpts := *c.wOpts
+		wOpts.Datacenter = dc
+		c.wOpts = &wOpts
+		c.sessions.qOpts = c.qOpts
+		c.sessions.wOpts = c.wOpts
+	}
+}
+
+// withFlagsFilter makes GetUnderPrefix only return the keys that have all the
+// bits of flags set.
+func withFlagsFilter(flags uint64) keyClientOption {
+	return func(c *keyClient) {
+		c.flagsFilter = flags
+	}
+}
+
+// withReadDatacenters makes Get and GetUnderPrefix read from the given
+// datacenters, in order, when the datacenter of the client cannot be
+// reached. The client then keeps reading from the datacenter that answered.
+func withReadDatacenters(dcs []string) keyClientOption {
+	return func(c *keyClient) {
+		c.readDatacenters = dcs
+	}
+}
+
+// withMetadataOnly makes Get only return the metadata of the keys. Consul
+// still sends the value but it is neither decoded nor kept, so encrypted
+// values can be inspected without the encryption key.
+func withMetadataOnly(metadataOnly bool) keyClientOption {
+	return func(c *keyClient) {
+		c.metadataOnly = metadataOnly
+	}
+}
+
+// withRawValues makes the client read and write the values and the flags as
+// they are stored in Consul: the compressed and encrypted values are neither
+// encoded nor decoded and default_kv_flags is not applied.
+func withRawValues() keyClientOption {
+	return func(c *keyClient) {
+		c.rawValues = true
+	}
+}
+
+// withReadCache makes the reads of the client go through the read cache of
+// the provider, if it is enabled. Only the data sources use it, the
+// resources always read the latest values.
+func withReadCache() keyClientOption {
+	return func(c *keyClient) {
+		c.useReadCache = true
+	}
+}
+
+// withRequestID makes the client log its operations with the request ID of
+// another client, so that the requests made for the same resource in several
+// datacenters can be correlated.
+func withRequestID(id string) keyClientOption {
+	return func(c *keyClient) {
+		c.requestID = id
+		c.sessions.requestID = id
+	}
+}
+
+func newKeyClient(d resourceGetter, meta interface{}, opts ...keyClientOption) *keyClient {
 	client, qOpts, wOpts := getClient(d, meta)
 
-	return &keyClient{
+	config := meta.(*Config)
+	retry := config.retry
+	requestID := newRequestID()
+	c := &keyClient{
 		client: client.KV(),
-		qOpts:  qOpts,
-		wOpts:  wOpts,
+		sessions: &sessionClient{
+			client:    client.Session(),
+			qOpts:     qOpts,
+			wOpts:     wOpts,
+			retry:     retry,
+			requestID: requestID,
+		},
+		qOpts:         qOpts,
+		wOpts:         wOpts,
+		retry:         retry,
+		requestID:     requestID,
+		encryptionKey: config.encryptionKey,
+		defaultFlags:  config.DefaultKVFlags,
+		writeLimiter:  config.writeLimiter,
+		readLimiter:   config.readLimiter,
+		readCache:     config.readCache,
+		metrics:       config.metrics,
+		timeout:       config.operationTimeout,
+		maxValueBytes: config.KVMaxValueBytes,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c *keyClient) Get(path string) (string, int, error) {
-	log.Printf(
-		"[DEBUG] Reading key '%s' in %s",
-		path, c.qOpts.Datacenter,
-	)
-	pair, _, err := c.client.Get(path, c.qOpts)
+// newRequestID returns a random ID used to tell apart the operations of the
+// different clients in the logs.
+func newRequestID() string {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "unknown"
+	}
+	return id
+}
+
+// keyEntry holds the value and the metadata of a key read from Consul.
+type keyEntry struct {
+	value       string
+	flags       int
+	createIndex uint64
+	modifyIndex uint64
+	lockIndex   uint64
+	session     string
+
+	// storedHash is the SHA-256 checksum of the value as stored in Consul,
+	// before it is decompressed or decrypted
+	storedHash string
+}
+
+// Get reads the given key, a zero keyEntry is returned if it does not exist.
+// The query metadata is returned so that the caller can inspect the
+// freshness of the result.
+func (c *keyClient) Get(ctx context.Context, path string) (keyEntry, *consulapi.QueryMeta, error) {
+	stored, meta, err := c.readPair(ctx, path)
+	if err != nil {
+		return keyEntry{}, nil, err
+	}
+	if stored == nil {
+		return keyEntry{}, meta, nil
+	}
+	pair, err := c.decodePair(path, stored)
 	if err != nil {
-		return "", 0, fmt.Errorf("failed to read Consul key '%s': %s", path, err)
+		return keyEntry{}, nil, err
 	}
-	value := ""
-	if pair != nil {
-		value = string(pair.Value)
+	return keyEntry{
+		value:       string(pair.Value),
+		flags:       int(pair.Flags),
+		createIndex: pair.CreateIndex,
+		modifyIndex: pair.ModifyIndex,
+		lockIndex:   pair.LockIndex,
+		session:     pair.Session,
+		storedHash:  contentHash(stored.Value),
+	}, meta, nil
+}
+
+// GetPair reads the given key and returns the pair sent by Consul, with all
+// its metadata, nil is returned if it does not exist. The value of the pair
+// is decoded, it is left empty when the client only reads the metadata.
+func (c *keyClient) GetPair(ctx context.Context, path string) (*consulapi.KVPair, *consulapi.QueryMeta, error) {
+	stored, meta, err := c.readPair(ctx, path)
+	if err != nil || stored == nil {
+		return nil, meta, err
 	}
-	flags := 0
-	if pair != nil {
-		flags = int(pair.Flags)
+	pair, err := c.decodePair(path, stored)
+	if err != nil {
+		return nil, nil, err
 	}
-	return value, flags, nil
+	return pair, meta, nil
 }
 
-func (c *keyClient) GetUnderPrefix(pathPrefix string) (consulapi.KVPairs, error) {
-	log.Printf(
-		"[DEBUG] Listing keys under '%s' in %s",
-		pathPrefix, c.qOpts.Datacenter,
-	)
-	pairs, _, err := c.client.List(pathPrefix, c.qOpts)
+// readPair reads the given key and returns the pair as stored in Consul, nil
+// is returned if it does not exist. The pair may be shared with the other
+// readers of the cache and must not be modified.
+func (c *keyClient) readPair(ctx context.Context, path string) (*consulapi.KVPair, *consulapi.QueryMeta, error) {
+	c.logf("DEBUG", "get", path, "Reading key")
+	var pairs consulapi.KVPairs
+	var meta *consulapi.QueryMeta
+	err := c.readWithFallback("get", path, func() (err error) {
+		pairs, meta, err = c.cachedRead("get", path, func() (consulapi.KVPairs, *consulapi.QueryMeta, error) {
+			var pair *consulapi.KVPair
+			var meta *consulapi.QueryMeta
+			err := c.do(ctx, "get", path, func(ctx context.Context) (err error) {
+				pair, meta, err = c.client.Get(path, c.qOpts.WithContext(ctx))
+				return err
+			})
+			if pair == nil {
+				return nil, meta, err
+			}
+			return consulapi.KVPairs{pair}, meta, err
+		})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to list Consul keys under prefix '%s': %s", pathPrefix, err,
+		return nil, nil, fmt.Errorf("failed to read Consul key '%s': %w", path, c.apiError(err))
+	}
+	if len(pairs) == 0 {
+		return nil, meta, nil
+	}
+	return pairs[0], meta, nil
+}
+
+// decodePair returns a copy of the pair read from Consul with its value
+// decoded, the value is left empty when the client only reads the metadata.
+func (c *keyClient) decodePair(path string, stored *consulapi.KVPair) (*consulapi.KVPair, error) {
+	pair := *stored
+	pair.Value = nil
+	if !c.metadataOnly {
+		value, err := c.decode(stored.Value, stored.Flags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Consul key '%s': %s", path, err)
+		}
+		pair.Value = []byte(value)
+	}
+	return &pair, nil
+}
+
+func (c *keyClient) GetUnderPrefix(ctx context.Context, pathPrefix string) (consulapi.KVPairs, *consulapi.QueryMeta, error) {
+	c.logf("DEBUG", "list", pathPrefix, "Listing keys under prefix")
+	var pairs consulapi.KVPairs
+	var meta *consulapi.QueryMeta
+	err := c.readWithFallback("list", pathPrefix, func() (err error) {
+		pairs, meta, err = c.cachedRead("list", pathPrefix, func() (consulapi.KVPairs, *consulapi.QueryMeta, error) {
+			var pairs consulapi.KVPairs
+			var meta *consulapi.QueryMeta
+			err := c.do(ctx, "list", pathPrefix, func(ctx context.Context) (err error) {
+				pairs, meta, err = c.client.List(pathPrefix, c.qOpts.WithContext(ctx))
+				return err
+			})
+			return pairs, meta, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"failed to list Consul keys under prefix '%s': %w", pathPrefix, c.apiError(err),
 		)
 	}
-	return pairs, nil
+	filtered, err := c.decodePairs(pathPrefix, pairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return filtered, meta, nil
 }
 
-func (c *keyClient) Put(path, value string, flags int) error {
-	log.Printf(
-		"[DEBUG] Setting key '%s' to '%v' in %s",
-		path, value, c.wOpts.Datacenter,
-	)
-	pair := consulapi.KVPair{Key: path, Value: []byte(value), Flags: uint64(flags)}
-	if _, err := c.client.Put(&pair, c.wOpts); err != nil {
-		return fmt.Errorf("failed to write Consul key '%s': %s", path, err)
+// readWithFallback runs read and, while it fails because the datacenter of
+// the client cannot be reached, switches the client to the next of its read
+// datacenters and runs read again. The errors returned by a datacenter that
+// answered, like a permission denied, never cause a fallback.
+func (c *keyClient) readWithFallback(operation, path string, read func() error) error {
+	err := read()
+	for err != nil && len(c.readDatacenters) > 0 && isDatacenterUnreachable(err) {
+		dc := c.readDatacenters[0]
+		c.readDatacenters = c.readDatacenters[1:]
+		c.logf("WARN", operation, path, "Datacenter '%s' cannot be reached, reading from '%s' instead: %v", c.qOpts.Datacenter, dc, err)
+		withDatacenter(dc)(c)
+		err = read()
+	}
+	return err
+}
+
+// decodePairs decodes the values of the pairs listed under pathPrefix and
+// removes those that do not match the flags filter of the client.
+func (c *keyClient) decodePairs(pathPrefix string, pairs consulapi.KVPairs) (consulapi.KVPairs, error) {
+	filtered := pairs[:0]
+	for _, pair := range pairs {
+		if pair.Flags&c.flagsFilter != c.flagsFilter {
+			continue
+		}
+		value, err := c.decode(pair.Value, pair.Flags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Consul key '%s': %s", pair.Key, err)
+		}
+		pair.Value = []byte(value)
+		filtered = append(filtered, pair)
+	}
+	if c.flagsFilter != 0 {
+		c.logf("DEBUG", "list", pathPrefix, "Kept %d of %d keys with flags %d", len(filtered), len(pairs), c.flagsFilter)
+	}
+	return filtered, nil
+}
+
+// ListKeys returns the paths of the keys under pathPrefix, without reading
+// their values.
+func (c *keyClient) ListKeys(ctx context.Context, pathPrefix string) ([]string, *consulapi.QueryMeta, error) {
+	c.logf("DEBUG", "list", pathPrefix, "Listing the paths of the keys under prefix")
+	var paths []string
+	var meta *consulapi.QueryMeta
+	err := c.do(ctx, "list", pathPrefix, func(ctx context.Context) (err error) {
+		paths, meta, err = c.client.Keys(pathPrefix, "", c.qOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list Consul keys under prefix '%s': %w", pathPrefix, c.apiError(err))
+	}
+	return paths, meta, nil
+}
+
+// GetUnderPrefixPaged returns the same keys as GetUnderPrefix for the
+// prefixes that hold too many keys to be read in a single response, see
+// forEachPageUnderPrefix.
+func (c *keyClient) GetUnderPrefixPaged(ctx context.Context, pathPrefix string, pageSize int) (consulapi.KVPairs, *consulapi.QueryMeta, error) {
+	var pairs consulapi.KVPairs
+	meta, err := c.forEachPageUnderPrefix(ctx, pathPrefix, pageSize, func(page consulapi.KVPairs) error {
+		pairs = append(pairs, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return pairs, meta, nil
+}
+
+// ForEachUnderPrefix calls fn for each of the keys GetUnderPrefix would
+// return, without holding all of them in memory. The keys are read
+// maxTxnOps at a time and the iteration stops at the first error returned by
+// fn, which is then returned as is.
+//
+// Unlike GetUnderPrefix the keys are not read from a single snapshot, a key
+// written during the iteration may or may not be seen.
+func (c *keyClient) ForEachUnderPrefix(ctx context.Context, pathPrefix string, fn func(consulapi.KVPair) error) error {
+	_, err := c.forEachPageUnderPrefix(ctx, pathPrefix, maxTxnOps, func(page consulapi.KVPairs) error {
+		for _, pair := range page {
+			if err := fn(*pair); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// forEachPageUnderPrefix calls fn with the keys under pathPrefix, pageSize at
+// a time. Only the paths of the keys are listed at first, the keys are then
+// read in order using read-only transactions, each page continuing after the
+// last key of the previous one. The iteration stops at the first error
+// returned by fn.
+func (c *keyClient) forEachPageUnderPrefix(ctx context.Context, pathPrefix string, pageSize int, fn func(consulapi.KVPairs) error) (*consulapi.QueryMeta, error) {
+	if pageSize <= 0 || pageSize > maxTxnOps {
+		pageSize = maxTxnOps
+	}
+
+	paths, meta, err := c.ListKeys(ctx, pathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	for start := 0; start < len(paths); start += pageSize {
+		end := start + pageSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+
+		ops := make(consulapi.KVTxnOps, 0, end-start)
+		for _, path := range paths[start:end] {
+			ops = append(ops, &consulapi.KVTxnOp{
+				Verb: consulapi.KVGetOrEmpty,
+				Key:  path,
+			})
+		}
+
+		c.logf("DEBUG", "list", pathPrefix, "Reading keys %d to %d of %d", start+1, end, len(paths))
+		var ok bool
+		var resp *consulapi.KVTxnResponse
+		err := c.do(ctx, "list", pathPrefix, func(ctx context.Context) (err error) {
+			ok, resp, _, err = c.client.Txn(ops, c.qOpts.WithContext(ctx))
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Consul keys under prefix '%s': %w", pathPrefix, c.apiError(err))
+		}
+		if !ok {
+			return nil, fmt.Errorf("failed to list Consul keys under prefix '%s': %s", pathPrefix, txnErrors(ops, resp.Errors))
+		}
+
+		page := make(consulapi.KVPairs, 0, len(resp.Results))
+		for _, pair := range resp.Results {
+			// The keys deleted since they were listed are returned empty
+			if pair.ModifyIndex == 0 {
+				continue
+			}
+			page = append(page, pair)
+		}
+		page, err = c.decodePairs(pathPrefix, page)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(page); err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
+
+// writeFlags returns the flags to write for a key. The default_kv_flags of
+// the provider are used when flags has no other bits set than those reserved
+// by the provider.
+func (c *keyClient) writeFlags(flags int) int {
+	if c.rawValues {
+		return flags
+	}
+	if flags&^(kvFlagCompressed|kvFlagEncrypted) == 0 {
+		flags |= c.defaultFlags
+	}
+	return flags
+}
+
+// readFlags returns the flags to report for a key stored with the given
+// flags when declared are the flags set in the configuration. The default
+// flags written by writeFlags are reported as 0 so that they are not seen as
+// drift.
+func (c *keyClient) readFlags(stored uint64, declared int) int {
+	if declared == 0 && c.defaultFlags != 0 && int(stored) == c.defaultFlags {
+		return 0
+	}
+	return int(stored)
+}
+
+func (c *keyClient) Put(ctx context.Context, path, value string, flags int) error {
+	c.logf("DEBUG", "set", path, "Setting key to a value of %d bytes", len(value))
+	flags = c.writeFlags(flags)
+	encoded, err := c.encode(value, flags)
+	if err != nil {
+		return fmt.Errorf("failed to write Consul key '%s': %w", path, err)
+	}
+	pair := consulapi.KVPair{Key: path, Value: encoded, Flags: uint64(flags)}
+	err = c.do(ctx, "set", path, func(ctx context.Context) error {
+		_, err := c.client.Put(&pair, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write Consul key '%s': %w", path, c.apiError(err))
 	}
 	return nil
 }
 
-func (c *keyClient) Delete(path string) error {
-	log.Printf(
-		"[DEBUG] Deleting key '%s' in %s",
-		path, c.wOpts.Datacenter,
-	)
-	if _, err := c.client.Delete(path, c.wOpts); err != nil {
-		return fmt.Errorf("failed to delete Consul key '%s': %s", path, err)
+// Cas writes the key only if its modify index is still cas. It returns false
+// when the key has been modified in the meantime.
+func (c *keyClient) Cas(ctx context.Context, path, value string, flags int, cas uint64) (bool, error) {
+	c.logf("DEBUG", "cas", path, "Setting key to a value of %d bytes with cas %d", len(value), cas)
+	flags = c.writeFlags(flags)
+	encoded, err := c.encode(value, flags)
+	if err != nil {
+		return false, fmt.Errorf("failed to write Consul key '%s': %w", path, err)
+	}
+	pair := consulapi.KVPair{Key: path, Value: encoded, Flags: uint64(flags), ModifyIndex: cas}
+	var written bool
+	err = c.do(ctx, "cas", path, func(ctx context.Context) (err error) {
+		written, _, err = c.client.CAS(&pair, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write Consul key '%s': %w", path, c.apiError(err))
+	}
+	return written, nil
+}
+
+// PutBatch writes all the given pairs using KV transactions. The pairs are
+// sent in chunks of maxTxnOps operations so each chunk is applied atomically.
+func (c *keyClient) PutBatch(ctx context.Context, pairs []consulapi.KVPair) error {
+	ops := make(consulapi.KVTxnOps, 0, len(pairs))
+	for _, pair := range pairs {
+		c.logf("DEBUG", "set", pair.Key, "Setting key to a value of %d bytes in a transaction", len(pair.Value))
+		flags := c.writeFlags(int(pair.Flags))
+		encoded, err := c.encode(string(pair.Value), flags)
+		if err != nil {
+			return fmt.Errorf("failed to write Consul key '%s': %w", pair.Key, err)
+		}
+		ops = append(ops, &consulapi.KVTxnOp{
+			Verb:  consulapi.KVSet,
+			Key:   pair.Key,
+			Value: encoded,
+			Flags: uint64(flags),
+		})
+	}
+
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > maxTxnOps {
+			n = maxTxnOps
+		}
+
+		ok, resp, err := c.txn(ctx, ops[:n])
+		if err != nil {
+			return fmt.Errorf("failed to write Consul keys: %w", c.apiError(err))
+		}
+		if !ok {
+			return fmt.Errorf("failed to write Consul keys: %s", txnErrors(ops[:n], resp.Errors))
+		}
+		ops = ops[n:]
 	}
 	return nil
 }
 
-func (c *keyClient) DeleteUnderPrefix(pathPrefix string) error {
+// DeleteBatch deletes all the given keys using KV transactions. The keys are
+// deleted in chunks of maxTxnOps operations so each chunk is applied
+// atomically.
+func (c *keyClient) DeleteBatch(ctx context.Context, paths []string) error {
+	ops := make(consulapi.KVTxnOps, 0, len(paths))
+	for _, path := range paths {
+		c.logf("DEBUG", "delete", path, "Deleting key in a transaction")
+		ops = append(ops, &consulapi.KVTxnOp{
+			Verb: consulapi.KVDelete,
+			Key:  path,
+		})
+	}
+
+	for len(ops) > 0 {
+		n := len(ops)
+		if n > maxTxnOps {
+			n = maxTxnOps
+		}
+
+		ok, resp, err := c.txn(ctx, ops[:n])
+		if err != nil {
+			return fmt.Errorf("failed to delete Consul keys: %w", c.apiError(err))
+		}
+		if !ok {
+			return fmt.Errorf("failed to delete Consul keys: %s", txnErrors(ops[:n], resp.Errors))
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// casOp describes a check-and-set write of a key for CasBatch.
+type casOp struct {
+	Path  string
+	Value string
+	Flags int
+	Cas   int
+
+	// Checks are other keys that must still have the given modify index for
+	// the write to be applied.
+	Checks []indexCheck
+
+	// RenamedFrom is the previous path of the key, it is deleted in the same
+	// transaction.
+	RenamedFrom string
+}
+
+// indexCheck describes a key whose modify index must not change.
+type indexCheck struct {
+	Path  string
+	Index uint64
+}
+
+// CasBatch writes all the given keys in a single KV transaction using
+// check-and-set operations, so either all the keys are updated or none are.
+// It returns false if the transaction was rolled back, along with an error
+// naming the keys whose check failed.
+//
+// Consul limits the number of operations of a transaction, the batches that
+// need more than maxTxnOps operations are split into several transactions
+// and are no longer atomic: a transaction that fails leaves the keys of the
+// previous ones written. The checks of a key are always sent in the same
+// transaction as its write.
+func (c *keyClient) CasBatch(ctx context.Context, batch []casOp) (bool, error) {
+	var chunks []consulapi.KVTxnOps
+	var chunk consulapi.KVTxnOps
+	var total int
+	for _, op := range batch {
+		ops := make(consulapi.KVTxnOps, 0, len(op.Checks)+2)
+		for _, check := range op.Checks {
+			c.logf("DEBUG", "check_index", check.Path, "Checking key is still at index %d in a transaction", check.Index)
+			ops = append(ops, &consulapi.KVTxnOp{
+				Verb:  consulapi.KVCheckIndex,
+				Key:   check.Path,
+				Index: check.Index,
+			})
+		}
+
+		c.logf("DEBUG", "cas", op.Path, "Setting key to a value of %d bytes with cas %d in a transaction", len(op.Value), op.Cas)
+		flags := c.writeFlags(op.Flags)
+		encoded, err := c.encode(op.Value, flags)
+		if err != nil {
+			return false, fmt.Errorf("failed to write Consul key '%s': %w", op.Path, err)
+		}
+		ops = append(ops, &consulapi.KVTxnOp{
+			Verb:  consulapi.KVCAS,
+			Key:   op.Path,
+			Value: encoded,
+			Flags: uint64(flags),
+			Index: uint64(op.Cas),
+		})
+		if op.RenamedFrom != "" {
+			c.logf("DEBUG", "delete", op.RenamedFrom, "Deleting key renamed to '%s' in a transaction", op.Path)
+			ops = append(ops, &consulapi.KVTxnOp{
+				Verb: consulapi.KVDelete,
+				Key:  op.RenamedFrom,
+			})
+		}
+
+		if len(chunk) > 0 && len(chunk)+len(ops) > maxTxnOps {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+		chunk = append(chunk, ops...)
+		total += len(ops)
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) > 1 {
+		c.logf("WARN", "txn", "", "Writing %d keys needs %d operations but Consul accepts at most %d in a transaction, they are written in %d transactions that are not atomic", len(batch), total, maxTxnOps, len(chunks))
+	}
+
+	for i, ops := range chunks {
+		ok, resp, err := c.txn(ctx, ops)
+		if err != nil {
+			err = c.apiError(err)
+		} else if !ok {
+			err = txnErrors(ops, resp.Errors)
+		}
+		if err == nil {
+			continue
+		}
+		if i > 0 {
+			return false, fmt.Errorf("failed to write Consul keys, the keys of the %d previous transactions have already been written: %w", i, err)
+		}
+		return false, fmt.Errorf("failed to write Consul keys: %w", err)
+	}
+	return true, nil
+}
+
+// cachedRead returns the result of fetch from the read cache when the client
+// uses it. The blocking queries always go to Consul.
+func (c *keyClient) cachedRead(operation, path string, fetch func() (consulapi.KVPairs, *consulapi.QueryMeta, error)) (consulapi.KVPairs, *consulapi.QueryMeta, error) {
+	if c.readCache == nil || !c.useReadCache || c.qOpts.WaitIndex != 0 {
+		return fetch()
+	}
+	key := readCacheKey{
+		operation:  operation,
+		datacenter: c.qOpts.Datacenter,
+		namespace:  c.qOpts.Namespace,
+		partition:  c.qOpts.Partition,
+		token:      c.qOpts.Token,
+		allowStale: c.qOpts.AllowStale,
+		consistent: c.qOpts.RequireConsistent,
+		path:       path,
+	}
+	pairs, meta, hit, err := c.readCache.get(key, fetch)
+	if hit {
+		c.logf("DEBUG", operation, path, "Using the result of an identical read from the cache")
+	}
+	return pairs, meta, err
+}
+
+// logf writes a log line about an operation of the client. The operation, the
+// key, the datacenter and the request ID are added as key=value fields so
+// that all the requests made for a resource can be found in the TF_LOG
+// output by grepping for its request_id.
+func (c *keyClient) logf(level, operation, path, format string, args ...interface{}) {
 	log.Printf(
-		"[DEBUG] Deleting all keys under prefix '%s' in %s",
-		pathPrefix, c.wOpts.Datacenter,
+		"[%s] %s: operation=%s key=%q datacenter=%s request_id=%s",
+		level, fmt.Sprintf(format, args...), operation, path, c.qOpts.Datacenter, c.requestID,
 	)
-	if _, err := c.client.DeleteTree(pathPrefix, c.wOpts); err != nil {
-		return fmt.Errorf("failed to delete Consul keys under '%s': %s", pathPrefix, err)
+}
+
+// do sends a request to Consul using the retry policy of the client, the
+// requests that fail are logged with the fields of the operation.
+func (c *keyClient) do(ctx context.Context, operation, path string, f func(ctx context.Context) error) error {
+	limiter := c.writeLimiter
+	if operation == "get" || operation == "list" {
+		limiter = c.readLimiter
+	}
+
+	start := time.Now()
+	err := c.retry.doContext(ctx, func() error {
+		// Each attempt counts against the rate so that the retries do not
+		// overload a struggling cluster
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		return c.attempt(ctx, operation, f)
+	})
+	c.metrics.observe(operation, time.Since(start), err)
+	if err != nil {
+		c.logf("WARN", operation, path, "Request to Consul failed after %s: %v", time.Since(start), err)
+	}
+	return err
+}
+
+// attempt calls f with a context that is cancelled once the operation
+// timeout of the client is reached so that a Consul server that stopped
+// answering does not block Terraform forever.
+//
+// The errors of the operations that are not idempotent are only retried when
+// Consul refused the connection, any other error may have been returned after
+// the operation was applied.
+func (c *keyClient) attempt(ctx context.Context, operation string, f func(ctx context.Context) error) error {
+	err := c.attemptWithTimeout(ctx, operation, f)
+	if err != nil && !idempotentOperations[operation] && !isConnectionRefused(err) {
+		return &unsafeRetryError{err: err}
+	}
+	return err
+}
+
+func (c *keyClient) attemptWithTimeout(ctx context.Context, operation string, f func(ctx context.Context) error) error {
+	if c.timeout == 0 {
+		return f(ctx)
+	}
+
+	// Consul holds the blocking queries up to their wait time, plus a jitter
+	// of up to a sixteenth of it
+	timeout := c.timeout
+	if operation == "get" || operation == "list" {
+		timeout += c.qOpts.WaitTime + c.qOpts.WaitTime/16
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := f(attemptCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return &timeoutError{
+			timeout:   timeout,
+			retryable: idempotentOperations[operation],
+		}
+	}
+	return err
+}
+
+// idempotentOperations are the operations that can be sent again after a
+// timeout or a 5xx error. The others, like a check-and-set, may have been
+// applied by Consul before the failure and retrying them would report a
+// conflict with the write made by the first attempt.
+var idempotentOperations = map[string]bool{
+	"get":         true,
+	"list":        true,
+	"set":         true,
+	"delete":      true,
+	"delete_tree": true,
+}
+
+// timeoutError is returned when Consul did not answer a request before the
+// operation timeout of the client.
+type timeoutError struct {
+	timeout   time.Duration
+	retryable bool
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("Consul did not answer within %s, the request has been aborted (see operation_timeout)", e.timeout)
+}
+
+// apiError returns a clearer version of the errors returned by Consul, see
+// enterpriseFeatureError, that can be matched against ErrPermissionDenied,
+// ErrNotFound, ErrValueTooLarge, ErrUnreachable and ErrTimeout.
+func (c *keyClient) apiError(err error) error {
+	return newAPIError(enterpriseFeatureError(c.qOpts, err))
+}
+
+// enterpriseFeatureError returns a clearer error when the request was rejected
+// by a Consul server that does not support namespaces or admin partitions.
+func enterpriseFeatureError(qOpts *consulapi.QueryOptions, err error) error {
+	if !strings.Contains(err.Error(), "Unexpected response code: 400") {
+		return err
+	}
+	if qOpts.Partition != "" {
+		return fmt.Errorf("partition %q cannot be used, admin partitions are a Consul Enterprise feature that requires Consul 1.11 or later (%s)", qOpts.Partition, err)
+	}
+	if qOpts.Namespace != "" {
+		return fmt.Errorf("namespace %q cannot be used, namespaces are a Consul Enterprise feature (%s)", qOpts.Namespace, err)
+	}
+	return err
+}
+
+// txn submits the given operations in a single KV transaction. It returns
+// whether the transaction was committed along with its results, the errors
+// of a rolled back transaction can then be formatted with txnErrors.
+func (c *keyClient) txn(ctx context.Context, ops consulapi.KVTxnOps) (bool, *consulapi.KVTxnResponse, error) {
+	// The transaction endpoint takes query options but it is a write so we
+	// use the write options of the client.
+	qOpts := &consulapi.QueryOptions{
+		Datacenter: c.wOpts.Datacenter,
+		Namespace:  c.wOpts.Namespace,
+		Partition:  c.wOpts.Partition,
+		Token:      c.wOpts.Token,
+	}
+
+	var ok bool
+	var resp *consulapi.KVTxnResponse
+	err := c.do(ctx, "txn", "", func(ctx context.Context) (err error) {
+		ok, resp, _, err = c.client.Txn(ops, qOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	return ok, resp, nil
+}
+
+// getPair reads the raw pair stored at the given path, without decoding its
+// value.
+func (c *keyClient) getPair(ctx context.Context, path string) (*consulapi.KVPair, error) {
+	var pair *consulapi.KVPair
+	err := c.do(ctx, "get", path, func(ctx context.Context) (err error) {
+		pair, _, err = c.client.Get(path, c.qOpts.WithContext(ctx))
+		return err
+	})
+	return pair, err
+}
+
+// txnErrors formats the errors returned by Consul for a rolled back
+// transaction, using the key of the operation that failed.
+func txnErrors(ops consulapi.KVTxnOps, errs consulapi.TxnErrors) error {
+	errors := make([]string, 0, len(errs))
+	for _, e := range errs {
+		if e.OpIndex < 0 || e.OpIndex >= len(ops) {
+			errors = append(errors, fmt.Sprintf(" - %s", e.What))
+			continue
+		}
+		op := ops[e.OpIndex]
+		if op.Verb == consulapi.KVCheckIndex {
+			errors = append(errors, fmt.Sprintf(" - key '%s' has been modified since it was checked: %s", op.Key, e.What))
+			continue
+		}
+		errors = append(errors, fmt.Sprintf(" - %s on key '%s': %s", op.Verb, op.Key, e.What))
+	}
+	return fmt.Errorf("transaction was rolled back:\n%s", strings.Join(errors, "\n"))
+}
+
+// AcquireLock tries to lock the given key using the session. It returns
+// false if the lock is already held by another session.
+func (c *keyClient) AcquireLock(ctx context.Context, path, sessionID string) (bool, error) {
+	c.logf("DEBUG", "acquire", path, "Acquiring lock on key with session '%s'", sessionID)
+	return c.lockTxn(ctx, consulapi.KVLock, path, sessionID, true)
+}
+
+// ReleaseLock releases the lock held by the session on the given key. It
+// returns false if the key was not locked by this session.
+func (c *keyClient) ReleaseLock(ctx context.Context, path, sessionID string) (bool, error) {
+	c.logf("DEBUG", "release", path, "Releasing lock on key with session '%s'", sessionID)
+	return c.lockTxn(ctx, consulapi.KVUnlock, path, sessionID, false)
+}
+
+// SessionKeys returns the paths of the keys locked by the session. Consul
+// cannot list the keys of a session so the whole key/value store is read.
+func (c *keyClient) SessionKeys(ctx context.Context, sessionID string) ([]string, error) {
+	c.logf("DEBUG", "list", "", "Listing keys locked by session '%s'", sessionID)
+	var pairs consulapi.KVPairs
+	err := c.do(ctx, "list", "", func(ctx context.Context) (err error) {
+		pairs, _, err = c.client.List("", c.qOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the Consul keys locked by session '%s': %w", sessionID, c.apiError(err))
+	}
+	var paths []string
+	for _, pair := range pairs {
+		if pair.Session == sessionID {
+			paths = append(paths, pair.Key)
+		}
+	}
+	return paths, nil
+}
+
+// lockTxn locks or unlocks the key with the session. Both operations also set
+// the value of the key so the current one is kept, and the transaction checks
+// that the key has not been modified since it was read so that a concurrent
+// write is not overwritten. When missing is true, a key that does not exist is
+// created empty, otherwise false is returned.
+func (c *keyClient) lockTxn(ctx context.Context, verb consulapi.KVOp, path, sessionID string, missing bool) (bool, error) {
+	pair, err := c.getPair(ctx, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read Consul key '%s': %w", path, c.apiError(err))
+	}
+	check := &consulapi.KVTxnOp{Verb: consulapi.KVCheckIndex, Key: path}
+	if pair == nil {
+		if !missing {
+			return false, nil
+		}
+		pair = &consulapi.KVPair{Key: path}
+		check.Verb = consulapi.KVCheckNotExists
+	}
+	check.Index = pair.ModifyIndex
+	ops := consulapi.KVTxnOps{
+		check,
+		&consulapi.KVTxnOp{
+			Verb:    verb,
+			Key:     path,
+			Value:   pair.Value,
+			Flags:   pair.Flags,
+			Session: sessionID,
+		},
+	}
+
+	action := "acquire"
+	if verb == consulapi.KVUnlock {
+		action = "release"
+	}
+	ok, resp, err := c.txn(ctx, ops)
+	if err != nil {
+		return false, fmt.Errorf("failed to %s lock on Consul key '%s': %w", action, path, c.apiError(err))
+	}
+	if ok {
+		return true, nil
+	}
+	// The lock operation fails when the key is locked by another session,
+	// or not locked by this one
+	for _, e := range resp.Errors {
+		if e.OpIndex == 0 {
+			return false, fmt.Errorf("failed to %s lock on Consul key '%s': it has been modified concurrently: %s", action, path, e.What)
+		}
+	}
+	return false, nil
+}
+
+// PutAcquire writes the key while acquiring its lock with the given session.
+// It returns false if the lock is already held by another session.
+func (c *keyClient) PutAcquire(ctx context.Context, path, value string, flags int, sessionID string) (bool, error) {
+	c.logf("DEBUG", "acquire", path, "Setting key to a value of %d bytes with session '%s'", len(value), sessionID)
+	flags = c.writeFlags(flags)
+	encoded, err := c.encode(value, flags)
+	if err != nil {
+		return false, fmt.Errorf("failed to write Consul key '%s': %w", path, err)
+	}
+	pair := consulapi.KVPair{Key: path, Value: encoded, Flags: uint64(flags), Session: sessionID}
+	var acquired bool
+	err = c.do(ctx, "acquire", path, func(ctx context.Context) (err error) {
+		acquired, _, err = c.client.Acquire(&pair, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write Consul key '%s': %w", path, c.apiError(err))
+	}
+	return acquired, nil
+}
+
+func (c *keyClient) Delete(ctx context.Context, path string) error {
+	c.logf("DEBUG", "delete", path, "Deleting key")
+	err := c.do(ctx, "delete", path, func(ctx context.Context) error {
+		_, err := c.client.Delete(path, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete Consul key '%s': %w", path, c.apiError(err))
 	}
 	return nil
 }
+
+// DeleteCas deletes the key only if its modify index is still cas. It
+// returns false when the key has been modified in the meantime.
+func (c *keyClient) DeleteCas(ctx context.Context, path string, cas uint64) (bool, error) {
+	c.logf("DEBUG", "delete", path, "Deleting key with cas %d", cas)
+	pair := consulapi.KVPair{Key: path, ModifyIndex: cas}
+	var deleted bool
+	err := c.do(ctx, "delete_cas", path, func(ctx context.Context) (err error) {
+		deleted, _, err = c.client.DeleteCAS(&pair, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete Consul key '%s': %w", path, c.apiError(err))
+	}
+	return deleted, nil
+}
+
+// DeleteReport deletes the given key like Delete but also returns whether
+// the key existed and was actually removed.
+//
+// The key is read first and then deleted with a check-and-set operation so
+// that a key created between the two requests is not reported as missing.
+func (c *keyClient) DeleteReport(ctx context.Context, path string) (bool, error) {
+	c.logf("DEBUG", "delete", path, "Deleting key")
+	pair, err := c.getPair(ctx, path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read Consul key '%s': %w", path, c.apiError(err))
+	}
+	if pair == nil {
+		return false, nil
+	}
+
+	var deleted bool
+	err = c.do(ctx, "delete_cas", path, func(ctx context.Context) (err error) {
+		deleted, _, err = c.client.DeleteCAS(pair, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete Consul key '%s': %w", path, c.apiError(err))
+	}
+	if !deleted {
+		// The key has been written since we read it, it still exists and must
+		// be removed.
+		if err := c.Delete(ctx, path); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (c *keyClient) DeleteUnderPrefix(ctx context.Context, pathPrefix string) error {
+	c.logf("DEBUG", "delete_tree", pathPrefix, "Deleting all keys under prefix")
+	err := c.do(ctx, "delete_tree", pathPrefix, func(ctx context.Context) error {
+		_, err := c.client.DeleteTree(pathPrefix, c.wOpts.WithContext(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete Consul keys under '%s': %w", pathPrefix, c.apiError(err))
+	}
+	return nil
+}
+
+// DeleteTreeCas deletes all the keys under the given prefix only if none of
+// them has been modified after the cas index. It returns false without an
+// error when the check fails so that the caller can retry, no key has been
+// deleted then.
+//
+// The keys are deleted in a single transaction with check-and-set operations
+// so a key modified between the listing and the deletion is also detected.
+// Consul cannot check that no key was created under the prefix in a
+// transaction, the keys created after the listing are left in place. An error
+// is returned when the prefix has more than maxTxnOps keys.
+func (c *keyClient) DeleteTreeCas(ctx context.Context, pathPrefix string, cas int) (bool, error) {
+	c.logf("DEBUG", "delete_tree", pathPrefix, "Deleting all keys under prefix with cas %d", cas)
+
+	pairs, _, err := c.GetUnderPrefix(ctx, pathPrefix)
+	if err != nil {
+		return false, err
+	}
+	if len(pairs) > maxTxnOps {
+		return false, fmt.Errorf("failed to delete Consul keys under '%s': there are %d keys but Consul accepts at most %d operations in a single transaction", pathPrefix, len(pairs), maxTxnOps)
+	}
+
+	ops := make(consulapi.KVTxnOps, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.ModifyIndex > uint64(cas) {
+			c.logf("DEBUG", "delete_tree", pair.Key, "Key has been modified at index %d after %d", pair.ModifyIndex, cas)
+			return false, nil
+		}
+		ops = append(ops, &consulapi.KVTxnOp{
+			Verb:  consulapi.KVDeleteCAS,
+			Key:   pair.Key,
+			Index: pair.ModifyIndex,
+		})
+	}
+	if len(ops) == 0 {
+		return true, nil
+	}
+
+	ok, _, err := c.txn(ctx, ops)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete Consul keys under '%s': %w", pathPrefix, c.apiError(err))
+	}
+	return ok, nil
+}
+
+// sameDuration returns whether both strings represent the same duration.
+func sameDuration(a, b string) bool {
+	da, err := time.ParseDuration(a)
+	if err != nil {
+		return a == b
+	}
+	db, err := time.ParseDuration(b)
+	if err != nil {
+		return a == b
+	}
+	return da == db
+}
+
+// encodeValue returns the bytes to store in Consul for the given value,
+// compressing it when the kvFlagCompressed bit is set in flags.
+func encodeValue(value string, flags int) ([]byte, error) {
+	if flags&kvFlagCompressed == 0 {
+		return []byte(value), nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(value)); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue is the reverse of encodeValue. Values that have the
+// kvFlagCompressed bit set but that were not written compressed are returned
+// as is.
+func decodeValue(value []byte, flags uint64) (string, error) {
+	if flags&kvFlagCompressed == 0 || !bytes.HasPrefix(value, []byte{0x1f, 0x8b}) {
+		return string(value), nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(value))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress value: %v", err)
+	}
+	defer r.Close()
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress value: %v", err)
+	}
+	return string(decoded), nil
+}
+
+// encode returns the bytes to store in Consul for the given value. It is
+// compressed first when the kvFlagCompressed bit is set in flags and then
+// encrypted when the kvFlagEncrypted bit is set.
+//
+// An error matching ErrValueTooLarge is returned when the result is larger
+// than the maximum size of the values accepted by Consul, so that the write
+// is not even attempted.
+func (c *keyClient) encode(value string, flags int) ([]byte, error) {
+	encoded := []byte(value)
+	if !c.rawValues {
+		var err error
+		encoded, err = encodeValue(value, flags)
+		if err != nil {
+			return nil, err
+		}
+		if flags&kvFlagEncrypted != 0 {
+			encoded, err = encryptValue(encoded, c.encryptionKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.maxValueBytes > 0 && len(encoded) > c.maxValueBytes {
+		return nil, valueTooLargeError(len(encoded), c.maxValueBytes)
+	}
+	return encoded, nil
+}
+
+// valueTooLargeError returns the error reported when a value of size bytes is
+// larger than the maximum size of the values accepted by Consul.
+func valueTooLargeError(size, max int) error {
+	return &apiError{
+		kind: ErrValueTooLarge,
+		err:  fmt.Errorf("the value is %d bytes, Consul accepts at most %d bytes (see kv_max_value_bytes)", size, max),
+	}
+}
+
+// decode is the reverse of encode.
+func (c *keyClient) decode(value []byte, flags uint64) (string, error) {
+	if c.rawValues {
+		return string(value), nil
+	}
+	if flags&kvFlagEncrypted != 0 {
+		var err error
+		value, err = decryptValue(value, c.encryptionKey)
+		if err != nil {
+			return "", err
+		}
+	}
+	return decodeValue(value, flags)
+}
+
+// encryptValue encrypts value using AES-GCM, the random nonce is prepended to
+// the ciphertext.
+func encryptValue(value, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("failed to encrypt value: encryption_key is not set in the provider configuration")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %v", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to encrypt value: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, value, nil), nil
+}
+
+// decryptValue is the reverse of encryptValue.
+func decryptValue(value, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("failed to decrypt value: it is encrypted but encryption_key is not set in the provider configuration")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %v", err)
+	}
+
+	if len(value) < gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt value: it is too short to have been encrypted by the provider")
+	}
+	nonce, ciphertext := value[:gcm.NonceSize()], value[gcm.NonceSize():]
+	decrypted, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		// The authentication only fails when the key differs from the one
+		// used to write the value, or when the value has been tampered with.
+		return nil, fmt.Errorf("failed to decrypt value: it was not encrypted with the current encryption_key, the key may have been rotated")
+	}
+	return decrypted, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,1059 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestEncodeDecodeValue(t *testing.T) {
+	cases := map[string]struct {
+		value      string
+		flags      int
+		compressed bool
+	}{
+		"plain": {
+			value: "hello",
+			flags: 0,
+		},
+		"plain with other flags": {
+			value: "hello",
+			flags: 4,
+		},
+		"compressed": {
+			value:      "hello",
+			flags:      kvFlagCompressed,
+			compressed: true,
+		},
+		"compressed empty": {
+			value:      "",
+			flags:      kvFlagCompressed | 4,
+			compressed: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			encoded, err := encodeValue(tc.value, tc.flags)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+
+			isCompressed := bytes.HasPrefix(encoded, []byte{0x1f, 0x8b})
+			if isCompressed != tc.compressed {
+				t.Fatalf("expected compressed to be %v, got %q", tc.compressed, encoded)
+			}
+
+			decoded, err := decodeValue(encoded, uint64(tc.flags))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if decoded != tc.value {
+				t.Fatalf("expected %q, got %q", tc.value, decoded)
+			}
+		})
+	}
+
+	// Values written with the flag by another tool must be left untouched
+	decoded, err := decodeValue([]byte("not compressed"), kvFlagCompressed)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if decoded != "not compressed" {
+		t.Fatalf("unexpected value %q", decoded)
+	}
+}
+
+func TestEncodeDecodeValue_Encrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	c := &keyClient{encryptionKey: key}
+
+	for _, flags := range []int{kvFlagEncrypted, kvFlagEncrypted | kvFlagCompressed | 4} {
+		encoded, err := c.encode("hello", flags)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if bytes.Contains(encoded, []byte("hello")) {
+			t.Fatalf("value is not encrypted: %q", encoded)
+		}
+
+		// A new nonce is used for each write
+		other, err := c.encode("hello", flags)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if bytes.Equal(encoded, other) {
+			t.Fatalf("the nonce has been reused")
+		}
+
+		decoded, err := c.decode(encoded, uint64(flags))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if decoded != "hello" {
+			t.Fatalf("expected %q, got %q", "hello", decoded)
+		}
+	}
+
+	encoded, err := c.encode("hello", kvFlagEncrypted)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	rotated := &keyClient{encryptionKey: bytes.Repeat([]byte{0x43}, 32)}
+	_, err = rotated.decode(encoded, kvFlagEncrypted)
+	if err == nil || err.Error() != "failed to decrypt value: it was not encrypted with the current encryption_key, the key may have been rotated" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = (&keyClient{}).decode(encoded, kvFlagEncrypted)
+	if err == nil || err.Error() != "failed to decrypt value: it is encrypted but encryption_key is not set in the provider configuration" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = (&keyClient{}).encode("hello", kvFlagEncrypted)
+	if err == nil || err.Error() != "failed to encrypt value: encryption_key is not set in the provider configuration" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestKeyClientLogf(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	c := &keyClient{
+		sessions:  &sessionClient{},
+		qOpts:     &consulapi.QueryOptions{Datacenter: "dc1"},
+		wOpts:     &consulapi.WriteOptions{Datacenter: "dc1"},
+		requestID: "first",
+	}
+	withRequestID("second")(c)
+	c.logf("DEBUG", "get", "foo/bar", "Reading key with cas %d", 3)
+
+	expected := `[DEBUG] Reading key with cas 3: operation=get key="foo/bar" datacenter=dc1 request_id=second`
+	if !strings.Contains(buf.String(), expected) {
+		t.Fatalf("expected %q in the logs, got %q", expected, buf.String())
+	}
+	if c.sessions.requestID != "second" {
+		t.Fatalf("the request ID of the sessions has not been updated")
+	}
+}
+
+func TestKeyClientLogsNoValue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("true"))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:        client.KV(),
+		sessions:      &sessionClient{},
+		qOpts:         &consulapi.QueryOptions{},
+		wOpts:         &consulapi.WriteOptions{},
+		encryptionKey: bytes.Repeat([]byte{1}, 32),
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// The plain value must not appear in the logs, even for the encrypted
+	// values
+	ctx := context.Background()
+	if err := c.Put(ctx, "app/secret", "s3cr3t", kvFlagEncrypted); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := c.Cas(ctx, "app/secret", "s3cr3t", kvFlagEncrypted, 3); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Fatalf("the value has been logged: %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Setting key to a value of 6 bytes") {
+		t.Fatalf("the size of the value has not been logged: %q", buf.String())
+	}
+}
+
+func TestKeyClientRateLimit(t *testing.T) {
+	var lock sync.Mutex
+	var writes, reads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if r.Method == http.MethodPut {
+			writes++
+			w.Write([]byte("true"))
+			return
+		}
+		reads++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:       client.KV(),
+		sessions:     &sessionClient{},
+		qOpts:        &consulapi.QueryOptions{},
+		wOpts:        &consulapi.WriteOptions{},
+		writeLimiter: newRateLimiter(50),
+	}
+
+	// More concurrent writers than the rate must not deadlock, the writes
+	// are only spread over time
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- c.Put(context.Background(), fmt.Sprintf("key/%d", i), "value", 0)
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the writes did not complete")
+	}
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	if writes != 20 {
+		t.Fatalf("expected 20 writes, got %d", writes)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("the writes were not limited, they took %s", elapsed)
+	}
+
+	// The reads have their own limiter
+	start = time.Now()
+	for i := 0; i < 20; i++ {
+		if _, _, err := c.Get(context.Background(), "key"); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("the reads should not be limited, they took %s", elapsed)
+	}
+
+	// A cancelled context aborts the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Put(ctx, "key", "value", 0); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestKeyClientGetUnderPrefixPaged(t *testing.T) {
+	var lock sync.Mutex
+	var pages []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/kv/app/" {
+			// app/c is deleted after being listed
+			json.NewEncoder(w).Encode([]string{"app/e", "app/a", "app/d", "app/c", "app/b"})
+			return
+		}
+
+		var ops []struct{ KV consulapi.KVTxnOp }
+		if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		pages = append(pages, len(ops))
+		lock.Unlock()
+
+		var results []map[string]*consulapi.KVPair
+		for _, op := range ops {
+			pair := &consulapi.KVPair{Key: op.KV.Key}
+			if op.KV.Key != "app/c" {
+				pair.Value = []byte(strings.ToUpper(op.KV.Key))
+				pair.ModifyIndex = 10
+			}
+			results = append(results, map[string]*consulapi.KVPair{"KV": pair})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Results": results})
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+
+	pairs, _, err := c.GetUnderPrefixPaged(context.Background(), "app/", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, pair := range pairs {
+		got = append(got, fmt.Sprintf("%s=%s", pair.Key, pair.Value))
+	}
+	expected := "app/a=APP/A app/b=APP/B app/d=APP/D app/e=APP/E"
+	if strings.Join(got, " ") != expected {
+		t.Fatalf("unexpected keys\ngot:      %s\nexpected: %s", strings.Join(got, " "), expected)
+	}
+	if fmt.Sprint(pages) != "[2 2 1]" {
+		t.Fatalf("unexpected pages %v", pages)
+	}
+
+	// The iteration stops at the first error returned by the callback
+	errStop := errors.New("stop")
+	got = nil
+	err = c.ForEachUnderPrefix(context.Background(), "app/", func(pair consulapi.KVPair) error {
+		got = append(got, fmt.Sprintf("%s=%s", pair.Key, pair.Value))
+		if len(got) == 2 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Fatalf("expected the error of the callback, got %v", err)
+	}
+	if strings.Join(got, " ") != "app/a=APP/A app/b=APP/B" {
+		t.Fatalf("unexpected keys %v", got)
+	}
+}
+
+func TestKeyClientReadDatacenters(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dc := r.URL.Query().Get("dc")
+		lock.Lock()
+		requests = append(requests, dc+":"+r.URL.Path)
+		lock.Unlock()
+
+		switch {
+		case dc == "dc1":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("No path to datacenter"))
+		case r.URL.Path == "/v1/kv/denied":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Permission denied"))
+		case r.URL.Path == "/v1/kv/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			json.NewEncoder(w).Encode(consulapi.KVPairs{{Key: "app", Value: []byte(dc)}})
+		}
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newClient := func() *keyClient {
+		c := &keyClient{
+			client:   client.KV(),
+			sessions: &sessionClient{},
+			qOpts:    &consulapi.QueryOptions{Datacenter: "dc1"},
+			wOpts:    &consulapi.WriteOptions{Datacenter: "dc1"},
+		}
+		withReadDatacenters([]string{"dc2", "dc3"})(c)
+		return c
+	}
+
+	c := newClient()
+	entry, _, err := c.Get(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.value != "dc2" || c.qOpts.Datacenter != "dc2" {
+		t.Fatalf("expected the key to be read from dc2, got %q from %q", entry.value, c.qOpts.Datacenter)
+	}
+
+	// The client keeps reading from the datacenter that answered
+	lock.Lock()
+	requests = nil
+	lock.Unlock()
+	if _, _, err := c.GetUnderPrefix(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(requests) != "[dc2:/v1/kv/app]" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	// A missing key or a permission denied does not cause a fallback
+	entry, _, err = c.Get(context.Background(), "missing")
+	if err != nil || entry.modifyIndex != 0 || c.qOpts.Datacenter != "dc2" {
+		t.Fatalf("unexpected result %#v, %v from %q", entry, err, c.qOpts.Datacenter)
+	}
+	lock.Lock()
+	requests = nil
+	lock.Unlock()
+	if _, _, err := c.Get(context.Background(), "denied"); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected a permission denied error, got %v", err)
+	}
+	if fmt.Sprint(requests) != "[dc2:/v1/kv/denied]" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	// Without read datacenters the error is returned as is
+	c = newClient()
+	c.readDatacenters = nil
+	if _, _, err := c.Get(context.Background(), "app"); err == nil || !strings.Contains(err.Error(), "No path to datacenter") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
+func TestKeyClientGetPair(t *testing.T) {
+	encoded, err := encodeValue("hello", kvFlagCompressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := &consulapi.KVPair{
+		Key:         "app/config",
+		Value:       encoded,
+		Flags:       kvFlagCompressed,
+		CreateIndex: 3,
+		ModifyIndex: 7,
+		LockIndex:   2,
+		Session:     "5b0d7b06-1ea5-4ba6-a1a3-f2b1f5e5e4a1",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/app/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Consul-Index", "12")
+		json.NewEncoder(w).Encode(consulapi.KVPairs{stored})
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+
+	pair, meta, err := c.GetPair(context.Background(), "app/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := *stored
+	expected.Value = []byte("hello")
+	if !reflect.DeepEqual(*pair, expected) {
+		t.Fatalf("unexpected pair\ngot:      %#v\nexpected: %#v", *pair, expected)
+	}
+	if meta.LastIndex != 12 {
+		t.Fatalf("expected the index 12, got %d", meta.LastIndex)
+	}
+
+	// Get reports the same metadata
+	entry, _, err := c.Get(context.Background(), "app/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != (keyEntry{value: "hello", flags: kvFlagCompressed, createIndex: 3, modifyIndex: 7, lockIndex: 2, session: stored.Session, storedHash: contentHash(encoded)}) {
+		t.Fatalf("unexpected entry %#v", entry)
+	}
+
+	pair, _, err = c.GetPair(context.Background(), "app/missing")
+	if err != nil || pair != nil {
+		t.Fatalf("expected no pair, got %#v, %v", pair, err)
+	}
+}
+
+func TestKeyClientConsistencyMode(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newClient := func(opts ...keyClientOption) *keyClient {
+		c := &keyClient{
+			client:   client.KV(),
+			sessions: &sessionClient{},
+			qOpts:    &consulapi.QueryOptions{},
+			wOpts:    &consulapi.WriteOptions{},
+		}
+		for _, opt := range opts {
+			opt(c)
+		}
+		return c
+	}
+
+	clients := []*keyClient{
+		newClient(),
+		newClient(withAllowStale(true)),
+		newClient(withRequireConsistent(true)),
+	}
+	for _, c := range clients {
+		if _, _, err := c.Get(context.Background(), "app/config"); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	expected := []string{"", "stale=", "consistent="}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Fatalf("unexpected queries %q, expected %q", queries, expected)
+	}
+}
+
+func TestKeyClientDefaultFlags(t *testing.T) {
+	c := &keyClient{defaultFlags: 0x100}
+
+	writes := []struct {
+		flags    int
+		expected int
+	}{
+		{0, 0x100},
+		{kvFlagCompressed, 0x100 | kvFlagCompressed},
+		{kvFlagCompressed | kvFlagEncrypted, 0x100 | kvFlagCompressed | kvFlagEncrypted},
+		{2, 2},
+		{2 | kvFlagEncrypted, 2 | kvFlagEncrypted},
+	}
+	for _, w := range writes {
+		if got := c.writeFlags(w.flags); got != w.expected {
+			t.Fatalf("writeFlags(%d) = %d, expected %d", w.flags, got, w.expected)
+		}
+	}
+
+	reads := []struct {
+		stored   uint64
+		declared int
+		expected int
+	}{
+		{0x100, 0, 0},
+		{0, 0, 0},
+		{0x100, 0x100, 0x100},
+		{2, 0, 2},
+	}
+	for _, r := range reads {
+		if got := c.readFlags(r.stored, r.declared); got != r.expected {
+			t.Fatalf("readFlags(%d, %d) = %d, expected %d", r.stored, r.declared, got, r.expected)
+		}
+	}
+
+	// Nothing changes without default flags
+	c = &keyClient{}
+	if got := c.writeFlags(0); got != 0 {
+		t.Fatalf("writeFlags(0) = %d, expected 0", got)
+	}
+	if got := c.readFlags(0, 0); got != 0 {
+		t.Fatalf("readFlags(0, 0) = %d, expected 0", got)
+	}
+}
+
+func TestKeyClientOperationTimeout(t *testing.T) {
+	var lock sync.Mutex
+	requests := map[string]int{}
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.Method]++
+		lock.Unlock()
+
+		// The server never answers
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+		retry:    retryPolicy{maxRetries: 2, waitMin: time.Millisecond, waitMax: time.Millisecond},
+		timeout:  50 * time.Millisecond,
+	}
+
+	_, _, err = c.Get(context.Background(), "app/config")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	expected := "failed to read Consul key 'app/config': Consul did not answer within 50ms, the request has been aborted (see operation_timeout)"
+	if err.Error() != expected {
+		t.Fatalf("unexpected message\ngot:      %s\nexpected: %s", err, expected)
+	}
+
+	// A check-and-set may have been applied before the timeout so it is
+	// never sent again
+	_, err = c.Cas(context.Background(), "app/config", "value", 0, 12)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if requests[http.MethodGet] != 3 {
+		t.Fatalf("expected 3 attempts for the read, got %d", requests[http.MethodGet])
+	}
+	if requests[http.MethodPut] != 1 {
+		t.Fatalf("expected 1 attempt for the check-and-set, got %d", requests[http.MethodPut])
+	}
+}
+
+func TestKeyClientServerErrorRetries(t *testing.T) {
+	var lock sync.Mutex
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		lock.Unlock()
+
+		// The write may have been committed before the error is returned
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("timed out enqueuing operation"))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+		retry:    retryPolicy{maxRetries: 2, waitMin: time.Millisecond, waitMax: time.Millisecond},
+	}
+	ctx := context.Background()
+
+	if _, _, err := c.Get(ctx, "app/read"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := c.Put(ctx, "app/set", "value", 0); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := c.Cas(ctx, "app/cas", "value", 0, 12); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := c.CasBatch(ctx, []casOp{{Path: "app/batch", Value: "value", Cas: 12}}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	expected := map[string]int{
+		"/v1/kv/app/read": 3,
+		"/v1/kv/app/set":  3,
+		"/v1/kv/app/cas":  1,
+		"/v1/txn":         1,
+	}
+	for path, count := range expected {
+		if requests[path] != count {
+			t.Fatalf("expected %d attempts for %s, got %v", count, path, requests)
+		}
+	}
+
+	// A refused connection never reached Consul so the check-and-set can be
+	// sent again
+	refused := func(ctx context.Context) error {
+		return fmt.Errorf("dial tcp 127.0.0.1:8500: connect: connection refused")
+	}
+	if err := c.attempt(ctx, "cas", refused); !isTransientError(err) {
+		t.Fatalf("the refused connection should be retried: %v", err)
+	}
+}
+
+func TestKeyClientPathEscaping(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+
+	// The special characters must not be interpreted as the query string,
+	// the fragment or an escape sequence of the URL
+	if _, _, err := c.Get(context.Background(), "app/my key?#%2F"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := "/v1/kv/app/my%20key%3F%23%252F"
+	if len(paths) != 1 || paths[0] != expected {
+		t.Fatalf("expected a request to %q, got %v", expected, paths)
+	}
+}
+
+func TestKeyClientMaxValueBytes(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("true"))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:        client.KV(),
+		sessions:      &sessionClient{},
+		qOpts:         &consulapi.QueryOptions{},
+		wOpts:         &consulapi.WriteOptions{},
+		maxValueBytes: 64,
+	}
+
+	value := strings.Repeat("a", 100)
+	err = c.Put(context.Background(), "app/config", value, 0)
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected a value too large error, got %v", err)
+	}
+	expected := "failed to write Consul key 'app/config': the value is 100 bytes, Consul accepts at most 64 bytes (see kv_max_value_bytes)"
+	if err.Error() != expected {
+		t.Fatalf("unexpected message\ngot:      %s\nexpected: %s", err, expected)
+	}
+	if _, err := c.Cas(context.Background(), "app/config", value, 0, 12); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected a value too large error, got %v", err)
+	}
+	if requests != 0 {
+		t.Fatalf("the values should not have been sent to Consul")
+	}
+
+	// The size of the stored value is checked, after its compression
+	if err := c.Put(context.Background(), "app/config", value, kvFlagCompressed); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestKeyClientCasBatchChunked(t *testing.T) {
+	var requests int
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var ops []interface{}
+		json.NewDecoder(r.Body).Decode(&ops)
+		sizes = append(sizes, len(ops))
+		if requests == 3 {
+			// The second transaction of the second batch is rolled back
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"Results": null, "Errors": [{"OpIndex": 0, "What": "current modify index 5 != 3"}]}`))
+			return
+		}
+		w.Write([]byte(`{"Results": [], "Errors": null}`))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+
+	batch := make([]casOp, maxTxnOps)
+	for i := range batch {
+		batch[i] = casOp{Path: fmt.Sprintf("app/%d", i), Value: "v"}
+	}
+	if ok, err := c.CasBatch(context.Background(), batch); !ok || err != nil {
+		t.Fatalf("unexpected result %v: %v", ok, err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request, got %d", requests)
+	}
+
+	// A single check more needs a second transaction, the check is sent
+	// along with the write of its key
+	batch[maxTxnOps-1].Checks = []indexCheck{{Path: "app/gate", Index: 3}}
+	_, err = c.CasBatch(context.Background(), batch)
+	expected := "failed to write Consul keys, the keys of the 1 previous transactions have already been written: transaction was rolled back:\n"
+	if err == nil || !strings.HasPrefix(err.Error(), expected) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 3 || sizes[1] != maxTxnOps-1 || sizes[2] != 2 {
+		t.Fatalf("unexpected transactions %v", sizes)
+	}
+}
+
+func TestKeyClientDeleteTreeCas(t *testing.T) {
+	var pairs []*consulapi.KVPair
+	var txns [][]map[string]interface{}
+	conflict := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/app/":
+			json.NewEncoder(w).Encode(pairs)
+		case "/v1/txn":
+			var ops []map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+				t.Errorf("invalid transaction: %v", err)
+			}
+			txns = append(txns, ops)
+			if conflict {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"Results": null, "Errors": [{"OpIndex": 0, "What": "current modify index 9 does not match 5"}]}`))
+				return
+			}
+			w.Write([]byte(`{"Results": [], "Errors": null}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+	ctx := context.Background()
+
+	pairs = []*consulapi.KVPair{
+		{Key: "app/a", Value: []byte("1"), ModifyIndex: 5},
+		{Key: "app/b", Value: []byte("2"), ModifyIndex: 7},
+	}
+	ok, err := c.DeleteTreeCas(ctx, "app/", 7)
+	if !ok || err != nil {
+		t.Fatalf("unexpected result %v: %v", ok, err)
+	}
+	if len(txns) != 1 || len(txns[0]) != 2 {
+		t.Fatalf("expected a single transaction deleting 2 keys, got %v", txns)
+	}
+	for i, op := range txns[0] {
+		kv := op["KV"].(map[string]interface{})
+		if kv["Verb"] != string(consulapi.KVDeleteCAS) || kv["Key"] != pairs[i].Key || kv["Index"] != float64(pairs[i].ModifyIndex) {
+			t.Fatalf("unexpected operation %v", kv)
+		}
+	}
+
+	// A key modified after the index is detected before the transaction
+	txns = nil
+	ok, err = c.DeleteTreeCas(ctx, "app/", 6)
+	if ok || err != nil {
+		t.Fatalf("unexpected result %v: %v", ok, err)
+	}
+	if len(txns) != 0 {
+		t.Fatalf("no key should have been deleted, got %v", txns)
+	}
+
+	// A key modified between the listing and the transaction rolls it back
+	conflict = true
+	ok, err = c.DeleteTreeCas(ctx, "app/", 7)
+	if ok || err != nil {
+		t.Fatalf("unexpected result %v: %v", ok, err)
+	}
+	conflict = false
+
+	// The prefixes with too many keys are rejected
+	txns = nil
+	pairs = nil
+	for i := 0; i <= maxTxnOps; i++ {
+		pairs = append(pairs, &consulapi.KVPair{Key: fmt.Sprintf("app/%d", i), ModifyIndex: 1})
+	}
+	_, err = c.DeleteTreeCas(ctx, "app/", 7)
+	expected := "failed to delete Consul keys under 'app/': there are 65 keys but Consul accepts at most 64 operations in a single transaction"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(txns) != 0 {
+		t.Fatalf("no key should have been deleted, got %v", txns)
+	}
+}
+
+func TestKeyClientLocks(t *testing.T) {
+	stored := &consulapi.KVPair{Key: "app/leader", Value: []byte("node-1"), Flags: 4, ModifyIndex: 7}
+	var txns []consulapi.TxnOps
+	var failedOp int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/kv/app/leader":
+			json.NewEncoder(w).Encode(consulapi.KVPairs{stored})
+		case "/v1/kv/app/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/txn":
+			var ops consulapi.TxnOps
+			if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+				t.Errorf("invalid transaction: %v", err)
+			}
+			txns = append(txns, ops)
+			if failedOp >= 0 {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprintf(w, `{"Errors": [{"OpIndex": %d, "What": "failed"}]}`, failedOp)
+				return
+			}
+			w.Write([]byte(`{"Results": [], "Errors": null}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+	ctx := context.Background()
+	session := "5b0d7b06-1ea5-4ba6-a1a3-f2b1f5e5e4a1"
+
+	// The lock is acquired if the key is still at the index it was read at,
+	// its value and its flags are kept
+	failedOp = -1
+	acquired, err := c.AcquireLock(ctx, "app/leader", session)
+	if !acquired || err != nil {
+		t.Fatalf("unexpected result %v: %v", acquired, err)
+	}
+	check, lock := txns[0][0].KV, txns[0][1].KV
+	if check.Verb != consulapi.KVCheckIndex || check.Key != "app/leader" || check.Index != 7 {
+		t.Fatalf("unexpected check %#v", check)
+	}
+	if lock.Verb != consulapi.KVLock || string(lock.Value) != "node-1" || lock.Flags != 4 || lock.Session != session {
+		t.Fatalf("unexpected lock %#v", lock)
+	}
+
+	// A missing key is created
+	if acquired, err := c.AcquireLock(ctx, "app/missing", session); !acquired || err != nil {
+		t.Fatalf("unexpected result %v: %v", acquired, err)
+	}
+	if check := txns[1][0].KV; check.Verb != consulapi.KVCheckNotExists {
+		t.Fatalf("unexpected check %#v", check)
+	}
+
+	// The lock is held by another session
+	failedOp = 1
+	if acquired, err := c.AcquireLock(ctx, "app/leader", session); acquired || err != nil {
+		t.Fatalf("unexpected result %v: %v", acquired, err)
+	}
+
+	// The key was written since it was read
+	failedOp = 0
+	_, err = c.AcquireLock(ctx, "app/leader", session)
+	if err == nil || !strings.Contains(err.Error(), "failed to acquire lock on Consul key 'app/leader': it has been modified concurrently") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Release
+	failedOp = -1
+	txns = nil
+	released, err := c.ReleaseLock(ctx, "app/leader", session)
+	if !released || err != nil {
+		t.Fatalf("unexpected result %v: %v", released, err)
+	}
+	if unlock := txns[0][1].KV; unlock.Verb != consulapi.KVUnlock || string(unlock.Value) != "node-1" || unlock.Session != session {
+		t.Fatalf("unexpected unlock %#v", unlock)
+	}
+	failedOp = 1
+	if released, err := c.ReleaseLock(ctx, "app/leader", session); released || err != nil {
+		t.Fatalf("unexpected result %v: %v", released, err)
+	}
+	if released, err := c.ReleaseLock(ctx, "app/missing", session); released || err != nil {
+		t.Fatalf("unexpected result %v: %v", released, err)
+	}
+	if len(txns) != 2 {
+		t.Fatalf("the missing key should not have been released")
+	}
+}
+
+func TestAccKeyClient_DeleteReport(t *testing.T) {
+	_, client := startTestServer(t)
+
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+	ctx := context.Background()
+
+	if err := c.Put(ctx, "test/delete-report", "value", 0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	deleted, err := c.DeleteReport(ctx, "test/delete-report")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !deleted {
+		t.Fatal("the key should have been reported as deleted")
+	}
+
+	deleted, err = c.DeleteReport(ctx, "test/delete-report")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if deleted {
+		t.Fatal("the key should have been reported as missing")
+	}
+}
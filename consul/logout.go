@@ -0,0 +1,41 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"log"
+	"sync"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+var (
+	logoutLock sync.Mutex
+	logouts    []func()
+)
+
+// registerLogout records that the token used by client has been created
+// using an auth method and must be destroyed by Logout.
+func registerLogout(client *consulapi.Client, wOpts *consulapi.WriteOptions) {
+	logoutLock.Lock()
+	defer logoutLock.Unlock()
+
+	logouts = append(logouts, func() {
+		if _, err := client.ACL().Logout(wOpts); err != nil {
+			log.Printf("[WARN] Failed to logout from Consul: %v", err)
+		}
+	})
+}
+
+// Logout destroys the ACL tokens obtained by logging in with an auth method.
+// It must be called once the provider is not used anymore.
+func Logout() {
+	logoutLock.Lock()
+	defer logoutLock.Unlock()
+
+	for _, logout := range logouts {
+		logout()
+	}
+	logouts = nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// kvMetricKey identifies the requests counted together by kvMetrics.
+type kvMetricKey struct {
+	operation string
+	status    string
+}
+
+type kvMetric struct {
+	count   uint64
+	seconds float64
+}
+
+// kvMetrics counts the requests made to the key/value store by the key
+// clients and their durations. It is nil when metrics_file is not set, the
+// requests are then not measured at all.
+type kvMetrics struct {
+	lock       sync.Mutex
+	operations map[kvMetricKey]*kvMetric
+}
+
+var (
+	metricsLock  sync.Mutex
+	metricsFiles = make(map[string]*kvMetrics)
+)
+
+// metricsForFile returns the metrics written to path by WriteMetrics, the
+// provider configurations using the same file share them.
+func metricsForFile(path string) *kvMetrics {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	m, ok := metricsFiles[path]
+	if !ok {
+		m = &kvMetrics{operations: make(map[kvMetricKey]*kvMetric)}
+		metricsFiles[path] = m
+	}
+	return m
+}
+
+// observe records a request that took duration and returned err.
+func (m *kvMetrics) observe(operation string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	key := kvMetricKey{operation: operation, status: "ok"}
+	if err != nil {
+		key.status = "error"
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	metric, ok := m.operations[key]
+	if !ok {
+		metric = &kvMetric{}
+		m.operations[key] = metric
+	}
+	metric.count++
+	metric.seconds += duration.Seconds()
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format, so
+// that the file can be read by the textfile collector of the node exporter.
+func (m *kvMetrics) writeTo(w io.Writer) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	keys := make([]kvMetricKey, 0, len(m.operations))
+	for key := range m.operations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("# HELP consul_provider_kv_requests_total The number of requests made to the Consul key/value store.\n")
+	buf.WriteString("# TYPE consul_provider_kv_requests_total counter\n")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "consul_provider_kv_requests_total{operation=%q,status=%q} %d\n", key.operation, key.status, m.operations[key].count)
+	}
+	buf.WriteString("# HELP consul_provider_kv_request_duration_seconds The duration of the requests made to the Consul key/value store, retries included.\n")
+	buf.WriteString("# TYPE consul_provider_kv_request_duration_seconds summary\n")
+	for _, key := range keys {
+		metric := m.operations[key]
+		fmt.Fprintf(&buf, "consul_provider_kv_request_duration_seconds_sum{operation=%q,status=%q} %g\n", key.operation, key.status, metric.seconds)
+		fmt.Fprintf(&buf, "consul_provider_kv_request_duration_seconds_count{operation=%q,status=%q} %d\n", key.operation, key.status, metric.count)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteMetrics writes the metrics of the requests made to the key/value
+// store to the metrics_file of the provider configurations. It must be called
+// once the provider is not used anymore.
+func WriteMetrics() {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	for path, m := range metricsFiles {
+		var buf bytes.Buffer
+		err := m.writeTo(&buf)
+		if err == nil {
+			err = os.WriteFile(path, buf.Bytes(), 0644)
+		}
+		if err != nil {
+			log.Printf("[WARN] Failed to write the metrics to %q: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestKeyClientMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Permission denied"))
+			return
+		}
+		if r.Method == http.MethodPut {
+			w.Write([]byte("true"))
+			return
+		}
+		w.Write([]byte(`[{"Key": "app/name", "Value": "d2Vi", "ModifyIndex": 10}]`))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "consul.prom")
+	c := &keyClient{
+		client:   client.KV(),
+		sessions: &sessionClient{},
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+		metrics:  metricsForFile(path),
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, _, err := c.Get(ctx, "app/name"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Put(ctx, "app/name", "web", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "app/name"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	WriteMetrics()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"# TYPE consul_provider_kv_requests_total counter\n",
+		`consul_provider_kv_requests_total{operation="delete",status="error"} 1` + "\n",
+		`consul_provider_kv_requests_total{operation="get",status="ok"} 2` + "\n",
+		`consul_provider_kv_requests_total{operation="set",status="ok"} 1` + "\n",
+		"# TYPE consul_provider_kv_request_duration_seconds summary\n",
+		`consul_provider_kv_request_duration_seconds_count{operation="get",status="ok"} 2` + "\n",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Fatalf("%q not found in:\n%s", expected, content)
+		}
+	}
+
+	// The requests are not measured when the metrics are disabled
+	c.metrics = nil
+	if _, _, err := c.Get(ctx, "app/name"); err != nil {
+		t.Fatal(err)
+	}
+}
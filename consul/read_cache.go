@@ -0,0 +1,113 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// readCacheKey identifies identical reads of the key/value store. The token
+// is part of the key so that a value is never returned to a caller that
+// would not have been allowed to read it.
+type readCacheKey struct {
+	operation  string
+	datacenter string
+	namespace  string
+	partition  string
+	token      string
+	allowStale bool
+	consistent bool
+	path       string
+}
+
+// readCacheEntry holds the result of a read, done is closed once it is
+// available.
+type readCacheEntry struct {
+	done    chan struct{}
+	expires time.Time
+	pairs   consulapi.KVPairs
+	meta    *consulapi.QueryMeta
+	err     error
+}
+
+// readCache collapses the identical reads made by the data sources during a
+// run into a single request to Consul. The entries expire after ttl, with a
+// jitter of up to 10% so that they do not all expire at the same time.
+type readCache struct {
+	ttl time.Duration
+
+	lock    sync.Mutex
+	entries map[readCacheKey]*readCacheEntry
+}
+
+func newReadCache(ttl time.Duration) *readCache {
+	return &readCache{
+		ttl:     ttl,
+		entries: make(map[readCacheKey]*readCacheEntry),
+	}
+}
+
+// get returns the cached result of the read identified by key, or calls
+// fetch to populate the cache. Concurrent calls for the same key wait for
+// the first one instead of sending their own request. Failed reads are not
+// cached.
+func (c *readCache) get(key readCacheKey, fetch func() (consulapi.KVPairs, *consulapi.QueryMeta, error)) (consulapi.KVPairs, *consulapi.QueryMeta, bool, error) {
+	c.lock.Lock()
+	if e, ok := c.entries[key]; ok {
+		select {
+		case <-e.done:
+			if time.Now().Before(e.expires) {
+				c.lock.Unlock()
+				return copyPairs(e.pairs), e.meta, true, nil
+			}
+		default:
+			c.lock.Unlock()
+			<-e.done
+			if e.err != nil {
+				return nil, nil, false, e.err
+			}
+			return copyPairs(e.pairs), e.meta, true, nil
+		}
+	}
+	e := &readCacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.lock.Unlock()
+
+	e.pairs, e.meta, e.err = fetch()
+	var jitter time.Duration
+	if c.ttl/10 > 0 {
+		jitter = time.Duration(rand.Int63n(int64(c.ttl / 10)))
+	}
+	e.expires = time.Now().Add(c.ttl + jitter)
+	close(e.done)
+
+	if e.err != nil {
+		c.lock.Lock()
+		if c.entries[key] == e {
+			delete(c.entries, key)
+		}
+		c.lock.Unlock()
+		return nil, nil, false, e.err
+	}
+	return copyPairs(e.pairs), e.meta, false, nil
+}
+
+// copyPairs returns a deep copy of pairs so that the callers can modify
+// their result without changing the cached one.
+func copyPairs(pairs consulapi.KVPairs) consulapi.KVPairs {
+	if pairs == nil {
+		return nil
+	}
+	result := make(consulapi.KVPairs, 0, len(pairs))
+	for _, pair := range pairs {
+		p := *pair
+		p.Value = append([]byte(nil), pair.Value...)
+		result = append(result, &p)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestReadCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Slow down the reads so that the concurrent ones overlap
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`[{"Key": "app/name", "Value": "d2Vi", "ModifyIndex": 10}]`))
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := newReadCache(200 * time.Millisecond)
+	newClient := func(useReadCache bool) *keyClient {
+		return &keyClient{
+			client:       client.KV(),
+			sessions:     &sessionClient{},
+			qOpts:        &consulapi.QueryOptions{},
+			wOpts:        &consulapi.WriteOptions{},
+			readCache:    cache,
+			useReadCache: useReadCache,
+		}
+	}
+
+	// The concurrent identical reads are collapsed into one request
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entry, _, err := newClient(true).Get(context.Background(), "app/name")
+			if err != nil {
+				t.Errorf("err: %v", err)
+				return
+			}
+			if entry.value != "web" {
+				t.Errorf("unexpected value %q", entry.value)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// Modifying the result must not change the cached one
+	pairs, _, err := newClient(true).GetUnderPrefix(context.Background(), "app/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pairs[0].Value[0] = 'x'
+	pairs, _, err = newClient(true).GetUnderPrefix(context.Background(), "app/")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(pairs[0].Value) != "web" {
+		t.Fatalf("the cached value has been modified: %q", pairs[0].Value)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	// The clients that do not use the cache always read from Consul
+	if _, _, err := newClient(false).Get(context.Background(), "app/name"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+
+	// The entries expire after the TTL
+	time.Sleep(250 * time.Millisecond)
+	if _, _, err := newClient(true).Get(context.Background(), "app/name"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Fatalf("expected 4 requests, got %d", n)
+	}
+}
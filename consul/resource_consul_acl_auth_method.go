@@ -6,6 +6,8 @@ package consul
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
@@ -19,6 +21,8 @@ func resourceConsulACLAuthMethod() *schema.Resource {
 		Update: resourceConsulACLAuthMethodUpdate,
 		Delete: resourceConsulACLAuthMethodDelete,
 
+		CustomizeDiff: resourceConsulACLAuthMethodCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:        schema.TypeString,
@@ -127,6 +131,87 @@ func resourceConsulACLAuthMethod() *schema.Resource {
 	}
 }
 
+// authMethodRequiredConfig lists the keys that must be set in the config of
+// the known types of auth methods. When several keys are given for a type,
+// at least one of them must be set.
+var authMethodRequiredConfig = map[string]struct {
+	all   []string
+	oneOf []string
+}{
+	"kubernetes": {all: []string{"Host", "CACert", "ServiceAccountJWT"}},
+	"jwt":        {oneOf: []string{"JWKSURL", "JWTValidationPubKeys", "OIDCDiscoveryURL"}},
+	"oidc":       {all: []string{"OIDCDiscoveryURL", "OIDCClientID", "OIDCClientSecret", "AllowedRedirectURIs"}},
+	"aws-iam":    {all: []string{"BoundIAMPrincipalARNs"}},
+}
+
+// resourceConsulACLAuthMethodCustomizeDiff checks during the plan that the
+// config of the auth method sets the keys required by its type.
+func resourceConsulACLAuthMethodCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("config_json") || !d.NewValueKnown("config") {
+		return nil
+	}
+
+	var config map[string]interface{}
+	if c := d.Get("config_json").(string); c != "" {
+		if err := json.Unmarshal([]byte(c), &config); err != nil {
+			return fmt.Errorf("failed to read 'config_json': %v", err)
+		}
+	} else {
+		config = d.Get("config").(map[string]interface{})
+	}
+
+	// A missing config is reported when creating the auth method
+	if len(config) == 0 {
+		return nil
+	}
+	return validateAuthMethodConfig(d.Get("type").(string), config)
+}
+
+// validateAuthMethodConfig returns an error listing the required keys that
+// are missing in config. The types of auth methods that are not known are
+// not checked.
+func validateAuthMethodConfig(typ string, config map[string]interface{}) error {
+	required, ok := authMethodRequiredConfig[typ]
+	if !ok {
+		return nil
+	}
+
+	isSet := func(key string) bool {
+		v, ok := config[key]
+		if !ok || v == nil {
+			return false
+		}
+		switch v := v.(type) {
+		case string:
+			return v != ""
+		case []interface{}:
+			return len(v) > 0
+		}
+		return true
+	}
+
+	var missing []string
+	for _, key := range required.all {
+		if !isSet(key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("the config of the %q auth method must set %s", typ, strings.Join(missing, ", "))
+	}
+
+	if len(required.oneOf) > 0 {
+		for _, key := range required.oneOf {
+			if isSet(key) {
+				return nil
+			}
+		}
+		return fmt.Errorf("the config of the %q auth method must set one of %s", typ, strings.Join(required.oneOf, ", "))
+	}
+	return nil
+}
+
 func resourceConsulACLAuthMethodCreate(d *schema.ResourceData, meta interface{}) error {
 	client, _, wOpts := getClient(d, meta)
 	ACL := client.ACL()
@@ -190,7 +275,7 @@ func resourceConsulACLAuthMethodRead(d *schema.ResourceData, meta interface{}) e
 	}
 	sw.set("namespace_rule", rules)
 	sw.set("namespace", authMethod.Namespace)
-	sw.set("partition", authMethod.Partition)
+	sw.set("partition", statePartition(d, meta, authMethod.Partition))
 
 	return sw.error()
 }
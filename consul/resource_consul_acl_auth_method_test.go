@@ -26,6 +26,10 @@ func TestAccConsulACLAuthMethod_basic(t *testing.T) {
 				Config:      testResourceACLAuthMethodConfigBasic_NoConfig,
 				ExpectError: regexp.MustCompile("one of 'config' or 'config_json' must be set"),
 			},
+			{
+				Config:      testResourceACLAuthMethodConfigBasic_MissingKeys,
+				ExpectError: regexp.MustCompile(`the config of the "kubernetes" auth method must set CACert, ServiceAccountJWT`),
+			},
 			{
 				Config: testResourceACLAuthMethodConfigBasic,
 				Check: resource.ComposeTestCheckFunc(
@@ -83,6 +87,56 @@ func TestAccConsulACLAuthMethod_basic(t *testing.T) {
 	})
 }
 
+func TestValidateAuthMethodConfig(t *testing.T) {
+	cases := map[string]struct {
+		typ      string
+		config   map[string]interface{}
+		expected string
+	}{
+		"kubernetes": {
+			typ: "kubernetes",
+			config: map[string]interface{}{
+				"Host":              "https://192.0.2.42:8443",
+				"CACert":            "cert",
+				"ServiceAccountJWT": "jwt",
+			},
+		},
+		"kubernetes missing keys": {
+			typ:      "kubernetes",
+			config:   map[string]interface{}{"Host": "https://192.0.2.42:8443", "CACert": ""},
+			expected: `the config of the "kubernetes" auth method must set CACert, ServiceAccountJWT`,
+		},
+		"jwt": {
+			typ:    "jwt",
+			config: map[string]interface{}{"JWTValidationPubKeys": []interface{}{"key"}},
+		},
+		"jwt missing keys": {
+			typ:      "jwt",
+			config:   map[string]interface{}{"BoundIssuer": "corp-issuer", "JWTValidationPubKeys": []interface{}{}},
+			expected: `the config of the "jwt" auth method must set one of JWKSURL, JWTValidationPubKeys, OIDCDiscoveryURL`,
+		},
+		"unknown type": {
+			typ:    "custom",
+			config: map[string]interface{}{"Foo": "bar"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateAuthMethodConfig(tc.typ, tc.config)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expected {
+				t.Fatalf("expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
 func TestAccConsulACLAuthMethod_namespaceCE(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -174,6 +228,15 @@ resource "consul_acl_auth_method" "test" {
     type        = "kubernetes"
 }`
 
+const testResourceACLAuthMethodConfigBasic_MissingKeys = `
+resource "consul_acl_auth_method" "test" {
+	name        = "auth_method"
+	type        = "kubernetes"
+	config_json = jsonencode({
+		Host = "https://192.0.2.42:8443"
+	})
+}`
+
 const testResourceACLAuthMethodConfigBasic = `
 resource "consul_acl_auth_method" "test" {
 	name           = "minikube"
@@ -5,6 +5,8 @@ package consul
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -44,9 +46,10 @@ func resourceConsulACLBindingRule() *schema.Resource {
 			},
 
 			"bind_name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The name to bind to a token at login-time.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateACLBindName,
+				Description:  "The name to bind to a token at login-time.",
 			},
 
 			"namespace": {
@@ -100,7 +103,7 @@ func resourceConsulACLBindingRuleRead(d *schema.ResourceData, meta interface{})
 	sw.set("bind_type", rule.BindType)
 	sw.set("bind_name", rule.BindName)
 	sw.set("namespace", rule.Namespace)
-	sw.set("partition", rule.Partition)
+	sw.set("partition", statePartition(d, meta, rule.Partition))
 
 	return sw.error()
 }
@@ -144,3 +147,26 @@ func getBindingRule(d *schema.ResourceData, meta interface{}) *consulapi.ACLBind
 		Namespace:   wOpts.Namespace,
 	}
 }
+
+var bindNameVariableRe = regexp.MustCompile(`^[A-Za-z0-9_\-]+(\.[A-Za-z0-9_\-]+)*$`)
+
+// validateACLBindName checks that the interpolations of the form
+// ${variable} used in the bind name of a binding rule are well-formed.
+func validateACLBindName(v interface{}, key string) ([]string, []error) {
+	name := v.(string)
+	for i := 0; i < len(name); i++ {
+		if !strings.HasPrefix(name[i:], "${") {
+			continue
+		}
+		end := strings.Index(name[i:], "}")
+		if end == -1 {
+			return nil, []error{fmt.Errorf("%s is not a valid template: the interpolation at offset %d is not closed", key, i)}
+		}
+		variable := strings.TrimSpace(name[i+2 : i+end])
+		if !bindNameVariableRe.MatchString(variable) {
+			return nil, []error{fmt.Errorf("%s is not a valid template: %q is not a valid variable name", key, variable)}
+		}
+		i += end
+	}
+	return nil, nil
+}
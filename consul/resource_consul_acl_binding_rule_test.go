@@ -58,6 +58,33 @@ func TestAccConsulACLBindingRule_basic(t *testing.T) {
 	})
 }
 
+func TestValidateACLBindName(t *testing.T) {
+	cases := map[string]string{
+		"minikube":                            "",
+		"${serviceaccount.name}":              "",
+		"prefix-${ serviceaccount.name }-end": "",
+		"${value.first_name}-${value.last}":   "",
+		"${serviceaccount.name":               "bind_name is not a valid template: the interpolation at offset 0 is not closed",
+		"app-${}":                             `bind_name is not a valid template: "" is not a valid variable name`,
+		"${service account}":                  `bind_name is not a valid template: "service account" is not a valid variable name`,
+	}
+
+	for name, expected := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, errs := validateACLBindName(name, "bind_name")
+			if expected == "" {
+				if len(errs) != 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Error() != expected {
+				t.Fatalf("expected %q, got %v", expected, errs)
+			}
+		})
+	}
+}
+
 func TestAccConsulACLBindingRule_namespaceCE(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -4,11 +4,15 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"reflect"
 	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
@@ -34,9 +38,13 @@ func resourceConsulACLPolicy() *schema.Resource {
 				Description: "The ACL policy description.",
 			},
 			"rules": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The ACL policy rules.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateACLRules,
+				Description:  "The ACL policy rules, in HCL or JSON.",
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return aclRulesEqual(old, new)
+				},
 			},
 			"datacenters": {
 				Type:        schema.TypeSet,
@@ -84,7 +92,7 @@ func resourceConsulACLPolicyCreate(d *schema.ResourceData, meta interface{}) err
 
 	policy, _, err := client.ACL().PolicyCreate(&aclPolicy, wOpts)
 	if err != nil {
-		return fmt.Errorf("error creating ACL policy: %s", err)
+		return fmt.Errorf("error creating ACL policy: %s", aclPolicyError(err))
 	}
 
 	log.Printf("[DEBUG] Created ACL policy %q", policy.ID)
@@ -101,20 +109,27 @@ func resourceConsulACLPolicyRead(d *schema.ResourceData, meta interface{}) error
 
 	aclPolicy, _, err := client.ACL().PolicyRead(id, qOpts)
 	if err != nil {
-		if strings.Contains(err.Error(), "ACL not found") {
+		if errors.Is(newAPIError(err), ErrNotFound) {
 			d.SetId("")
 			return nil
 		}
 		return fmt.Errorf("failed to read policy '%s': %v", id, err)
 	}
 
+	// The rules are kept as they are written in the configuration as long as
+	// they are equivalent to the ones returned by Consul
+	rules := aclPolicy.Rules
+	if aclRulesEqual(d.Get("rules").(string), rules) {
+		rules = d.Get("rules").(string)
+	}
+
 	sw := newStateWriter(d)
 	sw.set("name", aclPolicy.Name)
 	sw.set("description", aclPolicy.Description)
-	sw.set("rules", aclPolicy.Rules)
+	sw.set("rules", rules)
 	sw.set("datacenters", aclPolicy.Datacenters)
 	sw.set("namespace", aclPolicy.Namespace)
-	sw.set("partition", aclPolicy.Partition)
+	sw.set("partition", statePartition(d, meta, aclPolicy.Partition))
 
 	return sw.error()
 }
@@ -144,7 +159,7 @@ func resourceConsulACLPolicyUpdate(d *schema.ResourceData, meta interface{}) err
 
 	_, _, err := client.ACL().PolicyUpdate(&aclPolicy, wOpts)
 	if err != nil {
-		return fmt.Errorf("error updating ACL policy %q: %s", id, err)
+		return fmt.Errorf("error updating ACL policy %q: %s", id, aclPolicyError(err))
 	}
 	log.Printf("[DEBUG] Updated ACL policy %q", id)
 
@@ -165,3 +180,38 @@ func resourceConsulACLPolicyDelete(d *schema.ResourceData, meta interface{}) err
 
 	return nil
 }
+
+// validateACLRules checks that the rules of a policy are syntactically valid
+// HCL or JSON so that the errors are reported during the plan.
+func validateACLRules(v interface{}, key string) ([]string, []error) {
+	if _, err := hcl.Parse(v.(string)); err != nil {
+		return nil, []error{fmt.Errorf("%s are not valid HCL or JSON: %v", key, err)}
+	}
+	return nil, nil
+}
+
+// aclRulesEqual returns whether both rules are equivalent once decoded, so
+// that changes to their formatting are not reported as a diff.
+func aclRulesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var decodedA, decodedB interface{}
+	if err := hcl.Decode(&decodedA, a); err != nil {
+		return false
+	}
+	if err := hcl.Decode(&decodedB, b); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(decodedA, decodedB)
+}
+
+// aclPolicyError returns a clearer error when Consul rejected the policy,
+// for example because its rules use an unknown resource or policy.
+func aclPolicyError(err error) error {
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusBadRequest {
+		return fmt.Errorf("the policy was rejected by Consul: %s", strings.TrimSpace(statusErr.Body))
+	}
+	return err
+}
@@ -5,6 +5,7 @@ package consul
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	consulapi "github.com/hashicorp/consul/api"
@@ -54,6 +55,19 @@ func TestAccConsulACLPolicy_basic(t *testing.T) {
 					resource.TestCheckResourceAttr("consul_acl_policy.test", "datacenters.#", "1"),
 				),
 			},
+			{
+				// Reformatting the rules must not produce a diff
+				Config:   testResourceACLPolicyConfigBasicReformatted,
+				PlanOnly: true,
+			},
+			{
+				Config:      testResourceACLPolicyConfigInvalidRules,
+				ExpectError: regexp.MustCompile("rules are not valid HCL or JSON: At 1:"),
+			},
+			{
+				Config:      testResourceACLPolicyConfigRejectedRules,
+				ExpectError: regexp.MustCompile("the policy was rejected by Consul: "),
+			},
 			{
 				Config:  testResourceACLPolicyConfigBasicUpdate,
 				Destroy: true,
@@ -62,6 +76,45 @@ func TestAccConsulACLPolicy_basic(t *testing.T) {
 	})
 }
 
+func TestACLRulesEqual(t *testing.T) {
+	cases := map[string]struct {
+		a, b  string
+		equal bool
+	}{
+		"same": {
+			a:     `node_prefix "" { policy = "read" }`,
+			b:     `node_prefix "" { policy = "read" }`,
+			equal: true,
+		},
+		"reformatted": {
+			a: `node_prefix "" { policy = "read" }`,
+			b: `
+node_prefix "" {
+  policy = "read"
+}`,
+			equal: true,
+		},
+		"different": {
+			a:     `node_prefix "" { policy = "read" }`,
+			b:     `node_prefix "" { policy = "write" }`,
+			equal: false,
+		},
+		"invalid": {
+			a:     `node_prefix "" {`,
+			b:     `node_prefix "" { policy = "read" }`,
+			equal: false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if equal := aclRulesEqual(tc.a, tc.b); equal != tc.equal {
+				t.Fatalf("expected %v, got %v", tc.equal, equal)
+			}
+		})
+	}
+}
+
 func TestAccConsulACLPolicy_import(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -143,6 +196,31 @@ resource "consul_acl_policy" "test" {
 	datacenters = [ "dc1" ]
 }`
 
+const testResourceACLPolicyConfigBasicReformatted = `
+resource "consul_acl_policy" "test" {
+	name = "test-policy"
+	rules = <<-EOT
+	node_prefix "" {
+	  policy = "write"
+	}
+	EOT
+	datacenters = [ "dc1" ]
+}`
+
+const testResourceACLPolicyConfigInvalidRules = `
+resource "consul_acl_policy" "test" {
+	name = "test-policy"
+	rules = "node_prefix \"\" { policy = "
+	datacenters = [ "dc1" ]
+}`
+
+const testResourceACLPolicyConfigRejectedRules = `
+resource "consul_acl_policy" "test" {
+	name = "test-policy"
+	rules = "node_prefix \"\" { policy = \"admin\" }"
+	datacenters = [ "dc1" ]
+}`
+
 const testResourceACLPolicyNamespaceCE = `
 resource "consul_acl_policy" "test" {
   name      = "test"
@@ -5,8 +5,11 @@ package consul
 
 import (
 	"fmt"
+	"log"
+	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
@@ -18,7 +21,7 @@ func resourceConsulACLRole() *schema.Resource {
 		Update: resourceConsulACLRoleUpdate,
 		Delete: resourceConsulACLRoleDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceConsulACLRoleImport,
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -120,6 +123,9 @@ func resourceConsulACLRoleRead(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("failed to read role '%s': %s", d.Id(), err)
 	}
 	if role == nil {
+		// The role has been deleted outside of Terraform, it will be
+		// created again
+		log.Printf("[WARN] ACL role '%s' not found, removing it from the state", d.Id())
 		d.SetId("")
 		return nil
 	}
@@ -153,11 +159,39 @@ func resourceConsulACLRoleRead(d *schema.ResourceData, meta interface{}) error {
 	sw.set("service_identities", serviceIdentities)
 	sw.set("node_identities", nodeIdentities)
 	sw.set("namespace", role.Namespace)
-	sw.set("partition", role.Partition)
+	sw.set("partition", statePartition(d, meta, role.Partition))
 
 	return sw.error()
 }
 
+// resourceConsulACLRoleImport imports a role using either its ID or its name.
+// The name of a role in a namespace can be given as <namespace>/<name>.
+func resourceConsulACLRoleImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if _, err := uuid.ParseUUID(d.Id()); err == nil {
+		return []*schema.ResourceData{d}, nil
+	}
+
+	name := d.Id()
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		if err := d.Set("namespace", parts[0]); err != nil {
+			return nil, err
+		}
+		name = parts[1]
+	}
+
+	client, qOpts, _ := getClient(d, meta)
+	role, _, err := client.ACL().RoleReadByName(name, qOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role '%s': %s", name, err)
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role '%s' does not exist", name)
+	}
+
+	d.SetId(role.ID)
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceConsulACLRoleUpdate(d *schema.ResourceData, meta interface{}) error {
 	client, _, wOpts := getClient(d, meta)
 	ACL := client.ACL()
@@ -60,6 +60,39 @@ func TestAccConsulACLRole_basic(t *testing.T) {
 				ImportState:       true,
 				ImportStateVerify: true,
 			},
+			{
+				Config:            testResourceACLRoleConfigUpdate,
+				ResourceName:      "consul_acl_role.test",
+				ImportStateId:     "baz",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config:        testResourceACLRoleConfigUpdate,
+				ResourceName:  "consul_acl_role.test",
+				ImportStateId: "unknown",
+				ImportState:   true,
+				ExpectError:   regexp.MustCompile("role 'unknown' does not exist"),
+			},
+			{
+				// A role deleted outside of Terraform must be created again
+				PreConfig: func() {
+					role, _, err := client.ACL().RoleReadByName("baz", nil)
+					if err != nil || role == nil {
+						t.Fatalf("failed to read role: %v", err)
+					}
+					if _, err := client.ACL().RoleDelete(role.ID, nil); err != nil {
+						t.Fatalf("failed to delete role: %v", err)
+					}
+				},
+				Config:             testResourceACLRoleConfigUpdate,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testResourceACLRoleConfigUpdate,
+				Check:  resource.TestCheckResourceAttr("consul_acl_role.test", "name", "baz"),
+			},
 		},
 	})
 }
@@ -4,16 +4,25 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
+// aclTokenPropagationTimeout is how long we wait for a new token to be
+// replicated when rotating a token.
+const aclTokenPropagationTimeout = time.Minute
+
+// anonymousTokenAccessorID is the well-known accessor ID of the anonymous
+// token used by Consul for the requests made without a token.
+const anonymousTokenAccessorID = "00000000-0000-0000-0000-000000000002"
+
 func resourceConsulACLToken() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceConsulACLTokenCreate,
@@ -24,6 +33,8 @@ func resourceConsulACLToken() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		CustomizeDiff: resourceConsulACLTokenCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"accessor_id": {
 				Type:        schema.TypeString,
@@ -32,6 +43,23 @@ func resourceConsulACLToken() *schema.Resource {
 				Optional:    true,
 				Description: "The token id.",
 			},
+			"secret_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The secret of the token.",
+			},
+			"rotate_trigger": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"accessor_id"},
+				Description:   "An arbitrary value that rotates the token when it changes. A new token is created and the previous one is destroyed during the next apply.",
+			},
+			"previous_accessor_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The accessor ID of the token replaced during the last rotation, it is destroyed during the next apply.",
+			},
 			"description": {
 				Type:        schema.TypeString,
 				Optional:    true,
@@ -102,11 +130,25 @@ func resourceConsulACLToken() *schema.Resource {
 			},
 			"expiration_time": {
 				Type:         schema.TypeString,
-				ForceNew:     true,
 				Optional:     true,
+				Computed:     true,
 				ValidateFunc: validation.ValidateRFC3339TimeString,
 				Description:  "If set this represents the point after which a token should be considered revoked and is eligible for destruction.",
 			},
+			"expiration_ttl": {
+				Type:          schema.TypeString,
+				ForceNew:      true,
+				Optional:      true,
+				ConflictsWith: []string{"expiration_time"},
+				ValidateFunc:  validateDurationMinFactory("expiration_ttl", "1s"),
+				Description:   "The duration after which the token expires, Consul computes the `expiration_time` when the token is created. A new token is created once it has expired.",
+			},
+			"allow_delete_anonymous": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the anonymous token can be deleted when this resource is destroyed.",
+			},
 			"namespace": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -128,6 +170,7 @@ func resourceConsulACLTokenCreate(d *schema.ResourceData, meta interface{}) erro
 	log.Printf("[DEBUG] Creating ACL token")
 
 	aclToken := getToken(d)
+	setTokenExpirationTTL(d, aclToken)
 
 	token, _, err := client.ACL().TokenCreate(aclToken, wOpts)
 	if err != nil {
@@ -149,7 +192,7 @@ func resourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) error
 
 	aclToken, _, err := client.ACL().TokenRead(id, qOpts)
 	if err != nil {
-		if strings.Contains(err.Error(), "ACL not found") {
+		if errors.Is(newAPIError(err), ErrNotFound) {
 			log.Printf("[WARN] ACL token not found, removing from state")
 			d.SetId("")
 			return nil
@@ -157,6 +200,14 @@ func resourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("failed to read token '%s': %v", id, err)
 	}
 
+	// Consul may not have removed an expired token yet, it is gone all the
+	// same and a new one must be created
+	if aclToken.ExpirationTime != nil && !aclToken.ExpirationTime.After(time.Now()) {
+		log.Printf("[WARN] ACL token %q expired at %s, removing from state", id, aclToken.ExpirationTime.Format(time.RFC3339))
+		d.SetId("")
+		return nil
+	}
+
 	log.Printf("[DEBUG] Read ACL token %q", id)
 
 	roles := make([]string, 0, len(aclToken.Roles))
@@ -191,6 +242,7 @@ func resourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) error
 
 	sw := newStateWriter(d)
 	sw.set("accessor_id", aclToken.AccessorID)
+	sw.set("secret_id", aclToken.SecretID)
 	sw.set("description", aclToken.Description)
 	sw.set("policies", policies)
 	sw.set("roles", roles)
@@ -199,15 +251,48 @@ func resourceConsulACLTokenRead(d *schema.ResourceData, meta interface{}) error
 	sw.set("local", aclToken.Local)
 	sw.set("expiration_time", expirationTime)
 	sw.set("namespace", aclToken.Namespace)
-	sw.set("partition", aclToken.Partition)
+	sw.set("partition", statePartition(d, meta, aclToken.Partition))
 
 	return sw.error()
 }
 
 func resourceConsulACLTokenUpdate(d *schema.ResourceData, meta interface{}) error {
-	client, _, wOpts := getClient(d, meta)
+	client, qOpts, wOpts := getClient(d, meta)
 
 	id := d.Id()
+
+	// The token replaced during the last rotation is destroyed once the new
+	// one has been in use for a full apply
+	if o, _ := d.GetChange("previous_accessor_id"); o.(string) != "" {
+		if err := deleteACLToken(client, o.(string), wOpts); err != nil {
+			return err
+		}
+		d.Set("previous_accessor_id", "")
+	}
+
+	if d.HasChange("rotate_trigger") {
+		log.Printf("[DEBUG] Rotating ACL token %q", id)
+
+		aclToken := getToken(d)
+		aclToken.AccessorID = ""
+		setTokenExpirationTTL(d, aclToken)
+
+		token, _, err := client.ACL().TokenCreate(aclToken, wOpts)
+		if err != nil {
+			return fmt.Errorf("error creating ACL token to rotate %q: %s", id, err)
+		}
+		log.Printf("[DEBUG] Created ACL token %q to replace %q", token.AccessorID, id)
+
+		d.SetId(token.AccessorID)
+		d.Set("previous_accessor_id", id)
+
+		if err := waitForACLToken(client, token.AccessorID, qOpts); err != nil {
+			return err
+		}
+
+		return resourceConsulACLTokenRead(d, meta)
+	}
+
 	log.Printf("[DEBUG] Updating ACL token %q", id)
 
 	aclToken := getToken(d)
@@ -223,13 +308,87 @@ func resourceConsulACLTokenUpdate(d *schema.ResourceData, meta interface{}) erro
 }
 
 func resourceConsulACLTokenDelete(d *schema.ResourceData, meta interface{}) error {
+	if err := checkACLTokenDeletable(d.Id(), d.Get("allow_delete_anonymous").(bool)); err != nil {
+		return err
+	}
+
 	client, _, wOpts := getClient(d, meta)
 
-	id := d.Id()
+	if previous := d.Get("previous_accessor_id").(string); previous != "" {
+		if err := deleteACLToken(client, previous, wOpts); err != nil {
+			return err
+		}
+	}
+
+	return deleteACLToken(client, d.Id(), wOpts)
+}
+
+// setTokenExpirationTTL makes Consul compute the expiration time of a new
+// token when expiration_ttl is set, the expiration_time in the state is the
+// one of the token being replaced.
+func setTokenExpirationTTL(d *schema.ResourceData, aclToken *consulapi.ACLToken) {
+	ttl := d.Get("expiration_ttl").(string)
+	if ttl == "" {
+		return
+	}
+	// The duration has already been validated by the schema
+	aclToken.ExpirationTTL, _ = time.ParseDuration(ttl)
+	aclToken.ExpirationTime = nil
+}
+
+// resourceConsulACLTokenCustomizeDiff plans the creation of a new token when
+// rotate_trigger changes and the deletion of the token it replaced during the
+// next apply. The token is replaced when its expiration_time changes.
+func resourceConsulACLTokenCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	if d.HasChange("rotate_trigger") {
+		if err := d.SetNewComputed("secret_id"); err != nil {
+			return err
+		}
+		// The new token expires after expiration_ttl from its creation
+		if d.Get("expiration_ttl").(string) != "" {
+			if err := d.SetNewComputed("expiration_time"); err != nil {
+				return err
+			}
+		}
+		return d.SetNewComputed("previous_accessor_id")
+	}
+
+	// expiration_time is not ForceNew since it is computed again when a
+	// token using expiration_ttl is rotated, but the expiration time of an
+	// existing token cannot be updated
+	if d.HasChange("expiration_time") {
+		if err := d.ForceNew("expiration_time"); err != nil {
+			return err
+		}
+	}
 
+	if d.Get("previous_accessor_id").(string) != "" {
+		return d.SetNew("previous_accessor_id", "")
+	}
+	return nil
+}
+
+// checkACLTokenDeletable refuses to delete the anonymous token, which is
+// easily done by mistake after importing it or editing the state and would
+// break every request made without a token.
+func checkACLTokenDeletable(id string, allowAnonymous bool) error {
+	if id == anonymousTokenAccessorID && !allowAnonymous {
+		return fmt.Errorf("refusing to delete the anonymous token %q, set allow_delete_anonymous to true to delete it anyway or remove it from the state with 'terraform state rm'", id)
+	}
+	return nil
+}
+
+func deleteACLToken(client *consulapi.Client, id string, wOpts *consulapi.WriteOptions) error {
 	log.Printf("[DEBUG] Deleting ACL token %q", id)
 	_, err := client.ACL().TokenDelete(id, wOpts)
 	if err != nil {
+		if errors.Is(newAPIError(err), ErrNotFound) {
+			return nil
+		}
 		return fmt.Errorf("error deleting ACL token %q: %s", id, err)
 	}
 	log.Printf("[DEBUG] Deleted ACL token %q", id)
@@ -237,6 +396,28 @@ func resourceConsulACLTokenDelete(d *schema.ResourceData, meta interface{}) erro
 	return nil
 }
 
+// waitForACLToken waits for a new token to be known by the Consul servers
+// before the one it replaces is removed.
+func waitForACLToken(client *consulapi.Client, id string, qOpts *consulapi.QueryOptions) error {
+	opts := *qOpts
+	opts.AllowStale = true
+
+	err := resource.Retry(aclTokenPropagationTimeout, func() *resource.RetryError {
+		_, _, err := client.ACL().TokenRead(id, &opts)
+		if err != nil {
+			if errors.Is(newAPIError(err), ErrNotFound) {
+				return resource.RetryableError(err)
+			}
+			return resource.NonRetryableError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for ACL token %q to be propagated: %s", id, err)
+	}
+	return nil
+}
+
 func getToken(d *schema.ResourceData) *consulapi.ACLToken {
 	aclToken := &consulapi.ACLToken{
 		AccessorID:  d.Get("accessor_id").(string),
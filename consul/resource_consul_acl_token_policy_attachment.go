@@ -4,8 +4,11 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -42,23 +45,19 @@ func resourceConsulACLTokenPolicyAttachmentCreate(d *schema.ResourceData, meta i
 
 	tokenID := d.Get("token_id").(string)
 
-	aclToken, _, err := client.ACL().TokenRead(tokenID, qOpts)
-	if err != nil {
-		return fmt.Errorf("token '%s' not found", tokenID)
-	}
-
 	newPolicyName := d.Get("policy").(string)
-	for _, iPolicy := range aclToken.Policies {
-		if iPolicy.Name == newPolicyName {
-			return fmt.Errorf("policy '%s' already attached to token", newPolicyName)
+	err := updateACLToken(meta, client, tokenID, qOpts, wOpts, func(aclToken *consulapi.ACLToken) (bool, error) {
+		for _, iPolicy := range aclToken.Policies {
+			if iPolicy.Name == newPolicyName {
+				return false, fmt.Errorf("policy '%s' already attached to token", newPolicyName)
+			}
 		}
-	}
 
-	aclToken.Policies = append(aclToken.Policies, &consulapi.ACLTokenPolicyLink{
-		Name: newPolicyName,
+		aclToken.Policies = append(aclToken.Policies, &consulapi.ACLTokenPolicyLink{
+			Name: newPolicyName,
+		})
+		return true, nil
 	})
-
-	_, _, err = client.ACL().TokenUpdate(aclToken, wOpts)
 	if err != nil {
 		return fmt.Errorf("error updating ACL token '%q' to set new policy attachment: '%s'", tokenID, err)
 	}
@@ -82,7 +81,7 @@ func resourceConsulACLTokenPolicyAttachmentRead(d *schema.ResourceData, meta int
 
 	aclToken, _, err := client.ACL().TokenRead(tokenID, qOpts)
 	if err != nil {
-		if strings.Contains(err.Error(), "ACL not found") {
+		if errors.Is(newAPIError(err), ErrNotFound) {
 			d.SetId("")
 			return nil
 		}
@@ -121,26 +120,71 @@ func resourceConsulACLTokenPolicyAttachmentDelete(d *schema.ResourceData, meta i
 		return fmt.Errorf("invalid ACL token policy attachment id '%q'", id)
 	}
 
-	aclToken, _, err := client.ACL().TokenRead(tokenID, qOpts)
-	if err != nil {
-		return fmt.Errorf("token '%s' not found", tokenID)
-	}
-
-	for i, iPolicy := range aclToken.Policies {
-		if iPolicy.Name == policyName {
-			aclToken.Policies = append(aclToken.Policies[:i], aclToken.Policies[i+1:]...)
-			break
+	err = updateACLToken(meta, client, tokenID, qOpts, wOpts, func(aclToken *consulapi.ACLToken) (bool, error) {
+		for i, iPolicy := range aclToken.Policies {
+			if iPolicy.Name == policyName {
+				aclToken.Policies = append(aclToken.Policies[:i], aclToken.Policies[i+1:]...)
+				return true, nil
+			}
 		}
-	}
-
-	_, _, err = client.ACL().TokenUpdate(aclToken, wOpts)
+		// The policy has already been detached, the other policies of the
+		// token are left as they are
+		return false, nil
+	})
 	if err != nil {
+		if errors.Is(newAPIError(err), ErrNotFound) {
+			return nil
+		}
 		return fmt.Errorf("error updating ACL token '%q' to set new policy attachment: '%s'", tokenID, err)
 	}
 
 	return nil
 }
 
+// aclTokenUpdateAttempts is the number of times the update of a token is
+// attempted when it is modified concurrently.
+const aclTokenUpdateAttempts = 5
+
+// updateACLToken reads the token, applies update to it and writes it back
+// when update reports a change.
+//
+// Consul does not support check-and-set operations on the tokens, so the
+// updates made by this provider to the same token are serialized and the
+// ModifyIndex of the token is checked again right before it is written. The
+// update is started again from a fresh read when the token was modified in
+// the meantime so that the attachments made concurrently are not lost.
+func updateACLToken(meta interface{}, client *consulapi.Client, tokenID string, qOpts *consulapi.QueryOptions, wOpts *consulapi.WriteOptions, update func(*consulapi.ACLToken) (bool, error)) error {
+	lock, _ := meta.(*Config).aclTokenLocks.LoadOrStore(tokenID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	for attempt := 1; attempt <= aclTokenUpdateAttempts; attempt++ {
+		aclToken, _, err := client.ACL().TokenRead(tokenID, qOpts)
+		if err != nil {
+			return err
+		}
+		index := aclToken.ModifyIndex
+
+		changed, err := update(aclToken)
+		if err != nil || !changed {
+			return err
+		}
+
+		current, _, err := client.ACL().TokenRead(tokenID, qOpts)
+		if err != nil {
+			return err
+		}
+		if current.ModifyIndex != index {
+			log.Printf("[DEBUG] ACL token %q was modified concurrently (attempt %d/%d)", tokenID, attempt, aclTokenUpdateAttempts)
+			continue
+		}
+
+		_, _, err = client.ACL().TokenUpdate(aclToken, wOpts)
+		return err
+	}
+	return fmt.Errorf("the token was modified concurrently %d times", aclTokenUpdateAttempts)
+}
+
 // return the pieces of id `a:b` as a, b
 func parseTwoPartID(id, resource, name string) (string, string, error) {
 	parts := strings.SplitN(id, ":", 2)
@@ -100,6 +100,60 @@ func TestAccConsulACLTokenPolicyAttachment_basic(t *testing.T) {
 	})
 }
 
+func TestAccConsulACLTokenPolicyAttachment_concurrent(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	checkPolicies := func(expected int) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			tokenID := s.RootModule().Resources["consul_acl_token.test"].Primary.ID
+			token, _, err := client.ACL().TokenRead(tokenID, nil)
+			if err != nil {
+				return err
+			}
+			if len(token.Policies) != expected {
+				return fmt.Errorf("expected %d policies, got %d", expected, len(token.Policies))
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulACLTokenPolicyAttachmentDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				// The attachments are all created in parallel
+				Config: testResourceACLTokenPolicyAttachmentConfigConcurrent,
+				Check:  checkPolicies(5),
+			},
+			{
+				// An attachment removed outside of Terraform is created again
+				PreConfig: func() {
+					tokens, _, err := client.ACL().TokenList(nil)
+					if err != nil {
+						t.Fatalf("failed to list tokens: %v", err)
+					}
+					for _, entry := range tokens {
+						if entry.Description != "concurrent" {
+							continue
+						}
+						token, _, err := client.ACL().TokenRead(entry.AccessorID, nil)
+						if err != nil {
+							t.Fatalf("failed to read token: %v", err)
+						}
+						token.Policies = token.Policies[1:]
+						if _, _, err := client.ACL().TokenUpdate(token, nil); err != nil {
+							t.Fatalf("failed to update token: %v", err)
+						}
+					}
+				},
+				Config: testResourceACLTokenPolicyAttachmentConfigConcurrent,
+				Check:  checkPolicies(5),
+			},
+		},
+	})
+}
+
 func TestAccConsulACLTokenPolicyAttachment_import(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -177,3 +231,27 @@ resource "consul_acl_token_policy_attachment" "test" {
     token_id = "${consul_acl_token.test.id}"
     policy = "${consul_acl_policy.test2.name}"
 }`
+
+const testResourceACLTokenPolicyAttachmentConfigConcurrent = `
+resource "consul_acl_policy" "test" {
+	count = 5
+
+	name = "test-concurrent-${count.index}"
+	rules = "node \"\" { policy = \"read\" }"
+}
+
+resource "consul_acl_token" "test" {
+	description = "concurrent"
+
+	lifecycle {
+		ignore_changes = ["policies"]
+	}
+}
+
+resource "consul_acl_token_policy_attachment" "test" {
+	count = 5
+
+	token_id = consul_acl_token.test.id
+	policy   = consul_acl_policy.test[count.index].name
+}
+`
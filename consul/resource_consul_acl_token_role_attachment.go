@@ -4,8 +4,8 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
-	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -82,7 +82,7 @@ func resourceConsulACLTokenRoleAttachmentRead(d *schema.ResourceData, meta inter
 
 	aclToken, _, err := client.ACL().TokenRead(tokenID, qOpts)
 	if err != nil {
-		if strings.Contains(err.Error(), "ACL not found") {
+		if errors.Is(newAPIError(err), ErrNotFound) {
 			d.SetId("")
 			return nil
 		}
@@ -5,7 +5,9 @@ package consul
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
@@ -155,6 +157,181 @@ func TestAccConsulACLToken_namespaceEE(t *testing.T) {
 	})
 }
 
+func TestAccConsulACLToken_rotate(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	var first, second string
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulACLTokenDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceACLTokenConfigRotate("1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("consul_acl_token.test", "secret_id"),
+					resource.TestCheckResourceAttr("consul_acl_token.test", "previous_accessor_id", ""),
+					testAccSaveACLTokenID(&first),
+				),
+			},
+			{
+				Config: testResourceACLTokenConfigRotate("2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccSaveACLTokenID(&second),
+					resource.TestCheckResourceAttrPtr("consul_acl_token.test", "previous_accessor_id", &first),
+					resource.TestCheckResourceAttr("consul_acl_token.test", "description", "rotated"),
+					testAccCheckACLTokenExists(client, &first, true),
+					testAccCheckACLTokenExists(client, &second, true),
+				),
+				// The previous token is destroyed during the next apply
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testResourceACLTokenConfigRotate("2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPtr("consul_acl_token.test", "id", &second),
+					resource.TestCheckResourceAttr("consul_acl_token.test", "previous_accessor_id", ""),
+					testAccCheckACLTokenExists(client, &first, false),
+					testAccCheckACLTokenExists(client, &second, true),
+				),
+			},
+			{
+				// A token deleted outside of Terraform is recreated
+				PreConfig: func() {
+					if _, err := client.ACL().TokenDelete(second, nil); err != nil {
+						t.Fatalf("failed to delete token: %v", err)
+					}
+				},
+				Config: testResourceACLTokenConfigRotate("2"),
+				Check: func(s *terraform.State) error {
+					if s.RootModule().Resources["consul_acl_token.test"].Primary.ID == second {
+						return fmt.Errorf("ACL token %q should have been recreated", second)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccConsulACLToken_expirationTTL(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	var first, second string
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulACLTokenDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testResourceACLTokenConfigExpirationTTL("1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_acl_token.test", "expiration_ttl", "10m"),
+					func(s *terraform.State) error {
+						raw := s.RootModule().Resources["consul_acl_token.test"].Primary.Attributes["expiration_time"]
+						expiration, err := time.Parse(time.RFC3339, raw)
+						if err != nil {
+							return fmt.Errorf("invalid expiration_time %q: %v", raw, err)
+						}
+						if remaining := time.Until(expiration); remaining <= 0 || remaining > 10*time.Minute {
+							return fmt.Errorf("unexpected expiration_time %q", raw)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: testResourceACLTokenConfigExpirationTTL("1"),
+				Check:  testAccSaveACLTokenID(&first),
+			},
+			{
+				// The rotation creates a new token and keeps the previous one
+				// until the next apply
+				Config: testResourceACLTokenConfigExpirationTTL("2"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccSaveACLTokenID(&second),
+					resource.TestCheckResourceAttrPtr("consul_acl_token.test", "previous_accessor_id", &first),
+					testAccCheckACLTokenExists(client, &first, true),
+					testAccCheckACLTokenExists(client, &second, true),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testResourceACLTokenConfigExpirationTTL("2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPtr("consul_acl_token.test", "id", &second),
+					testAccCheckACLTokenExists(client, &first, false),
+					testAccCheckACLTokenExists(client, &second, true),
+				),
+			},
+			{
+				Config:      testResourceACLTokenConfigExpirationConflict,
+				ExpectError: regexp.MustCompile(`"expiration_ttl": conflicts with expiration_time`),
+			},
+		},
+	})
+}
+
+func TestCheckACLTokenDeletable(t *testing.T) {
+	if err := checkACLTokenDeletable(anonymousTokenAccessorID, false); err == nil {
+		t.Fatal("expected the anonymous token to be protected")
+	}
+	if err := checkACLTokenDeletable(anonymousTokenAccessorID, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkACLTokenDeletable("b5e7ac7d-3ca5-4b0e-8e3a-6b4e1dca1b28", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func testAccSaveACLTokenID(id *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		*id = s.RootModule().Resources["consul_acl_token.test"].Primary.ID
+		return nil
+	}
+}
+
+func testAccCheckACLTokenExists(client *consulapi.Client, id *string, exists bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		token, _, _ := client.ACL().TokenRead(*id, nil)
+		if exists && token == nil {
+			return fmt.Errorf("ACL token %q should exist", *id)
+		}
+		if !exists && token != nil {
+			return fmt.Errorf("ACL token %q should have been destroyed", *id)
+		}
+		return nil
+	}
+}
+
+func testResourceACLTokenConfigRotate(trigger string) string {
+	description := "test"
+	if trigger != "1" {
+		description = "rotated"
+	}
+	return fmt.Sprintf(`
+resource "consul_acl_token" "test" {
+  description    = %q
+  rotate_trigger = %q
+}`, description, trigger)
+}
+
+func testResourceACLTokenConfigExpirationTTL(trigger string) string {
+	return fmt.Sprintf(`
+resource "consul_acl_token" "test" {
+  description    = "short-lived"
+  expiration_ttl = "10m"
+  rotate_trigger = %q
+}`, trigger)
+}
+
+const testResourceACLTokenConfigExpirationConflict = `
+resource "consul_acl_token" "test" {
+  description     = "short-lived"
+  expiration_ttl  = "10m"
+  expiration_time = "2030-01-01T00:00:00Z"
+}`
+
 const testResourceACLTokenConfigBasic = `
 resource "consul_acl_policy" "test" {
 	name = "test-token-basic"
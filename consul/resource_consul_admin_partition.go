@@ -39,7 +39,15 @@ func resourceConsulAdminPartition() *schema.Resource {
 	}
 }
 
+// adminPartitionsVersion is the first version of Consul supporting admin
+// partitions.
+const adminPartitionsVersion = "1.11.0"
+
 func resourceConsulAdminPartitionCreate(d *schema.ResourceData, meta interface{}) error {
+	if err := meta.(*Config).requireVersion("consul_admin_partition", adminPartitionsVersion); err != nil {
+		return err
+	}
+
 	client, _, wOpts := getClient(d, meta)
 	partitions := client.Partitions()
 	name := d.Get("name").(string)
@@ -6,6 +6,7 @@ package consul
 import (
 	"bytes"
 	"fmt"
+	"log"
 	"sort"
 	"strings"
 
@@ -16,10 +17,13 @@ import (
 
 func resourceConsulCatalogEntry() *schema.Resource {
 	return &schema.Resource{
-		Create:             resourceConsulCatalogEntryCreate,
-		Update:             resourceConsulCatalogEntryCreate,
-		Read:               resourceConsulCatalogEntryRead,
-		Delete:             resourceConsulCatalogEntryDelete,
+		Create: resourceConsulCatalogEntryCreate,
+		Update: resourceConsulCatalogEntryCreate,
+		Read:   resourceConsulCatalogEntryRead,
+		Delete: resourceConsulCatalogEntryDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceConsulCatalogEntryImport,
+		},
 		DeprecationMessage: "The consul_catalog_entry resource will be deprecated and removed in a future version. More information: https://github.com/hashicorp/terraform-provider-consul/issues/46",
 
 		Schema: map[string]*schema.Schema{
@@ -42,6 +46,13 @@ func resourceConsulCatalogEntry() *schema.Resource {
 				ForceNew: true,
 			},
 
+			"node_meta": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The metadata of the node. When not set, the metadata already registered for the node is kept.",
+			},
+
 			"service": {
 				Type:     schema.TypeSet,
 				Optional: true,
@@ -80,11 +91,25 @@ func resourceConsulCatalogEntry() *schema.Resource {
 							Elem:     &schema.Schema{Type: schema.TypeString},
 							Set:      resourceConsulCatalogEntryServiceTagsHash,
 						},
+
+						"meta": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							ForceNew: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
 					},
 				},
 				Set: resourceConsulCatalogEntryServicesHash,
 			},
 
+			"unmanaged_service_ids": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the services registered on the node that are not declared in the configuration.",
+			},
+
 			"token": {
 				Type:       schema.TypeString,
 				Optional:   true,
@@ -118,6 +143,20 @@ func resourceConsulCatalogEntryServicesHash(v interface{}) int {
 			buf.WriteString(fmt.Sprintf("%s-", v))
 		}
 	}
+	// The meta is only part of the hash when it is set so that the hash of
+	// the existing services does not change
+	if v, ok := m["meta"]; ok {
+		meta := v.(map[string]interface{})
+		keys := make([]string, 0, len(meta))
+		for k := range meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			buf.WriteString(fmt.Sprintf("%s=%s-", k, meta[k].(string)))
+		}
+	}
 	return hashcode.String(buf.String())
 }
 
@@ -128,6 +167,23 @@ func resourceConsulCatalogEntryCreate(d *schema.ResourceData, meta interface{})
 	address := d.Get("address").(string)
 	node := d.Get("node").(string)
 
+	// Registering the node replaces its metadata, the metadata set outside of
+	// Terraform is kept when node_meta is not used
+	nodeMeta := make(map[string]string)
+	for k, v := range d.Get("node_meta").(map[string]interface{}) {
+		nodeMeta[k] = v.(string)
+	}
+	if len(nodeMeta) == 0 {
+		cNode, _, err := catalog.Node(node, qOpts)
+		if err != nil {
+			return fmt.Errorf("failed to read Consul catalog entry for node '%s' at address '%s' in %s: %v",
+				node, address, qOpts.Datacenter, err)
+		}
+		if cNode != nil && cNode.Node != nil {
+			nodeMeta = cNode.Node.Meta
+		}
+	}
+
 	var serviceIDs []string
 	if service, ok := d.GetOk("service"); ok {
 		serviceList := service.(*schema.Set).List()
@@ -149,16 +205,23 @@ func resourceConsulCatalogEntryCreate(d *schema.ResourceData, meta interface{})
 				}
 			}
 
+			serviceMeta := make(map[string]string)
+			for k, v := range serviceData["meta"].(map[string]interface{}) {
+				serviceMeta[k] = v.(string)
+			}
+
 			registration := &consulapi.CatalogRegistration{
 				Address:    address,
 				Datacenter: wOpts.Datacenter,
 				Node:       node,
+				NodeMeta:   nodeMeta,
 				Service: &consulapi.AgentService{
 					Address: serviceData["address"].(string),
 					ID:      serviceID,
 					Service: serviceData["name"].(string),
 					Port:    serviceData["port"].(int),
 					Tags:    tags,
+					Meta:    serviceMeta,
 				},
 			}
 
@@ -172,6 +235,7 @@ func resourceConsulCatalogEntryCreate(d *schema.ResourceData, meta interface{})
 			Address:    address,
 			Datacenter: wOpts.Datacenter,
 			Node:       node,
+			NodeMeta:   nodeMeta,
 		}
 
 		if _, err := catalog.Register(registration, wOpts); err != nil {
@@ -193,7 +257,7 @@ func resourceConsulCatalogEntryCreate(d *schema.ResourceData, meta interface{})
 
 	d.SetId(fmt.Sprintf("%s-%s-[%s]", node, address, serviceIDsJoined))
 
-	return nil
+	return resourceConsulCatalogEntryRead(d, meta)
 }
 
 func resourceConsulCatalogEntryRead(d *schema.ResourceData, meta interface{}) error {
@@ -207,10 +271,116 @@ func resourceConsulCatalogEntryRead(d *schema.ResourceData, meta interface{}) er
 		return fmt.Errorf("failed to get node '%s' from Consul catalog: %v", node, err)
 	}
 	if cNode == nil || cNode.Node == nil {
+		log.Printf("[WARN] Node '%s' not found in the Consul catalog, removing from state", node)
 		d.SetId("")
+		return nil
 	}
 
-	return nil
+	// Only the services declared in the configuration are managed by this
+	// resource, those that have been deregistered out of band disappear from
+	// the state so that the entry gets registered again.
+	services := make([]interface{}, 0)
+	declared := make(map[string]bool)
+	for _, raw := range d.Get("service").(*schema.Set).List() {
+		m := raw.(map[string]interface{})
+		id := m["id"].(string)
+		if id == "" {
+			id = m["name"].(string)
+		}
+		declared[id] = true
+
+		service, ok := cNode.Services[id]
+		if !ok {
+			log.Printf("[WARN] Service '%s' not found on node '%s' in the Consul catalog", id, node)
+			continue
+		}
+		services = append(services, catalogEntryService(service, m["id"].(string)))
+	}
+
+	unmanaged := make([]string, 0)
+	for id := range cNode.Services {
+		if !declared[id] {
+			unmanaged = append(unmanaged, id)
+		}
+	}
+	sort.Strings(unmanaged)
+	if len(unmanaged) > 0 {
+		log.Printf("[WARN] Node '%s' has services not declared in consul_catalog_entry: %s", node, strings.Join(unmanaged, ", "))
+	}
+
+	sw := newStateWriter(d)
+	sw.set("address", cNode.Node.Address)
+	sw.set("datacenter", qOpts.Datacenter)
+	sw.set("service", schema.NewSet(resourceConsulCatalogEntryServicesHash, services))
+	sw.set("unmanaged_service_ids", unmanaged)
+
+	// The metadata of the node is only tracked when it is managed by this
+	// resource, an empty node_meta cannot be told apart from an unset one
+	if len(d.Get("node_meta").(map[string]interface{})) > 0 {
+		sw.set("node_meta", cNode.Node.Meta)
+	}
+
+	return sw.error()
+}
+
+func resourceConsulCatalogEntryImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client, qOpts, _ := getClient(d, meta)
+
+	node := d.Id()
+	cNode, _, err := client.Catalog().Node(node, qOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node '%s' from Consul catalog: %v", node, err)
+	}
+	if cNode == nil || cNode.Node == nil {
+		return nil, fmt.Errorf("node '%s' not found in the Consul catalog", node)
+	}
+
+	// All the services of the node are adopted
+	services := make([]interface{}, 0, len(cNode.Services))
+	serviceIDs := make([]string, 0, len(cNode.Services))
+	for id, service := range cNode.Services {
+		services = append(services, catalogEntryService(service, id))
+		serviceIDs = append(serviceIDs, id)
+	}
+	sort.Strings(serviceIDs)
+
+	sw := newStateWriter(d)
+	sw.set("node", node)
+	sw.set("address", cNode.Node.Address)
+	if len(cNode.Node.Meta) > 0 {
+		sw.set("node_meta", cNode.Node.Meta)
+	}
+	sw.set("service", schema.NewSet(resourceConsulCatalogEntryServicesHash, services))
+	if err := sw.error(); err != nil {
+		return nil, err
+	}
+
+	d.SetId(fmt.Sprintf("%s-%s-[%s]", node, cNode.Node.Address, strings.Join(serviceIDs, ",")))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// catalogEntryService returns the representation of a service in the state,
+// id is left empty when it was not set in the configuration.
+func catalogEntryService(service *consulapi.AgentService, id string) map[string]interface{} {
+	tags := make([]interface{}, 0, len(service.Tags))
+	for _, tag := range service.Tags {
+		tags = append(tags, tag)
+	}
+
+	meta := make(map[string]interface{}, len(service.Meta))
+	for k, v := range service.Meta {
+		meta[k] = v
+	}
+
+	return map[string]interface{}{
+		"address": service.Address,
+		"id":      id,
+		"name":    service.Service,
+		"port":    service.Port,
+		"tags":    schema.NewSet(resourceConsulCatalogEntryServiceTagsHash, tags),
+		"meta":    meta,
+	}
 }
 
 func resourceConsulCatalogEntryDelete(d *schema.ResourceData, meta interface{}) error {
@@ -36,6 +36,63 @@ func TestAccConsulCatalogEntry_basic(t *testing.T) {
 					testAccCheckConsulCatalogEntryValue("consul_catalog_entry.app", "service.3112399829.tags.4151227546", "tag1"),
 				),
 			},
+			{
+				Config:            testAccConsulCatalogEntryConfig,
+				ResourceName:      "consul_catalog_entry.app",
+				ImportState:       true,
+				ImportStateId:     "bastion",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccConsulCatalogEntry_drift(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulCatalogEntryDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulCatalogEntryConfig,
+			},
+			{
+				// A service registered out of band is reported
+				PreConfig: func() {
+					_, err := client.Catalog().Register(&consulapi.CatalogRegistration{
+						Node:    "bastion",
+						Address: "127.0.0.1",
+						Service: &consulapi.AgentService{
+							ID:      "other",
+							Service: "other",
+						},
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to register service: %v", err)
+					}
+				},
+				Config: testAccConsulCatalogEntryConfig,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulCatalogEntryValue("consul_catalog_entry.app", "unmanaged_service_ids.#", "1"),
+					testAccCheckConsulCatalogEntryValue("consul_catalog_entry.app", "unmanaged_service_ids.0", "other"),
+				),
+			},
+			{
+				// A declared service deregistered out of band must be registered again
+				PreConfig: func() {
+					_, err := client.Catalog().Deregister(&consulapi.CatalogDeregistration{
+						Node:      "bastion",
+						ServiceID: "google1",
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to deregister service: %v", err)
+					}
+				},
+				Config:             testAccConsulCatalogEntryConfig,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
 		},
 	})
 }
@@ -70,6 +127,81 @@ func TestAccConsulCatalogEntry_extremove(t *testing.T) {
 	})
 }
 
+func TestAccConsulCatalogEntry_meta(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() {},
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulCatalogEntryDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulCatalogEntryConfigMeta("v1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_catalog_entry.app", "node_meta.%", "1"),
+					resource.TestCheckResourceAttr("consul_catalog_entry.app", "node_meta.rack", "v1"),
+					func(s *terraform.State) error {
+						node, _, err := client.Catalog().Node("bastion", nil)
+						if err != nil {
+							return err
+						}
+						if node.Node.Meta["rack"] != "v1" {
+							return fmt.Errorf("unexpected node meta: %v", node.Node.Meta)
+						}
+						service, ok := node.Services["google1"]
+						if !ok {
+							return fmt.Errorf("service google1 not found")
+						}
+						if service.Meta["version"] != "v1" {
+							return fmt.Errorf("unexpected service meta: %v", service.Meta)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: testAccConsulCatalogEntryConfigMeta("v2"),
+				Check:  resource.TestCheckResourceAttr("consul_catalog_entry.app", "node_meta.rack", "v2"),
+			},
+			{
+				// The metadata changed outside of Terraform is detected
+				PreConfig: func() {
+					_, err := client.Catalog().Register(&consulapi.CatalogRegistration{
+						Node:     "bastion",
+						Address:  "127.0.0.1",
+						NodeMeta: map[string]string{"rack": "other"},
+						Service: &consulapi.AgentService{
+							ID:      "google1",
+							Service: "google",
+							Address: "www.google.com",
+							Port:    80,
+							Meta:    map[string]string{"version": "other"},
+						},
+					}, nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:             testAccConsulCatalogEntryConfigMeta("v2"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulCatalogEntryConfigMeta("v2"),
+			},
+			{
+				// Removing the metadata of the services does not produce a
+				// perpetual diff
+				Config: testAccConsulCatalogEntryConfig,
+			},
+			{
+				Config:   testAccConsulCatalogEntryConfig,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
 func testAccCheckConsulCatalogEntryDestroy(client *consulapi.Client) func(s *terraform.State) error {
 	return func(s *terraform.State) error {
 		catalog := client.Catalog()
@@ -161,3 +293,27 @@ resource "consul_catalog_entry" "app" {
 	}
 }
 `
+
+func testAccConsulCatalogEntryConfigMeta(version string) string {
+	return fmt.Sprintf(`
+resource "consul_catalog_entry" "app" {
+	address = "127.0.0.1"
+	node = "bastion"
+
+	node_meta = {
+		rack = "%[1]s"
+	}
+
+	service {
+		address = "www.google.com"
+		id = "google1"
+		name = "google"
+		port = 80
+
+		meta = {
+			version = "%[1]s"
+		}
+	}
+}
+`, version)
+}
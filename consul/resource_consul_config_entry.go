@@ -83,6 +83,12 @@ func resourceConsulConfigEntry() *schema.Resource {
 				Optional:         true,
 				DiffSuppressFunc: diffConfigJSON,
 			},
+
+			"modify_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The index of the last modification of the config entry, it is used to detect concurrent changes.",
+			},
 		},
 	}
 }
@@ -110,9 +116,22 @@ func resourceConsulConfigEntryUpdate(d *schema.ResourceData, meta interface{}) e
 		return err
 	}
 
-	if _, _, err := configEntries.Set(configEntry, wOpts); err != nil {
+	// The config entry is only written if it has not been changed since it was
+	// last read, an index of 0 means that it must not exist yet
+	var index uint64
+	if d.Id() != "" {
+		index = uint64(d.Get("modify_index").(int))
+	}
+	ok, _, err := configEntries.CAS(configEntry, index, wOpts)
+	if err != nil {
 		return fmt.Errorf("failed to set '%s' config entry: %v", name, err)
 	}
+	if !ok {
+		if index == 0 {
+			return fmt.Errorf("failed to set '%s' config entry: it already exists, import it to manage it with Terraform", name)
+		}
+		return fmt.Errorf("failed to set '%s' config entry: it has been modified outside of Terraform since it was last read", name)
+	}
 	_, _, err = configEntries.Get(configEntry.GetKind(), configEntry.GetName(), qOpts)
 	if err != nil {
 		if strings.Contains(err.Error(), "Unexpected response code: 404") {
@@ -151,22 +170,35 @@ func resourceConsulConfigEntryRead(d *schema.ResourceData, meta interface{}) err
 		return fmt.Errorf("failed to parse ConfigEntry: %v", err)
 	}
 
-	if err = d.Set("config_json", string(configJSON)); err != nil {
-		return fmt.Errorf("failed to set 'config_json': %v", err)
-	}
+	sw := newStateWriter(d)
+	sw.set("config_json", configJSON)
+	sw.set("modify_index", int(configEntry.GetModifyIndex()))
 
-	return nil
+	return sw.error()
 }
 
 func resourceConsulConfigEntryDelete(d *schema.ResourceData, meta interface{}) error {
-	client, _, wOpts := getClient(d, meta)
+	client, qOpts, wOpts := getClient(d, meta)
 	configEntries := client.ConfigEntries()
 	configKind := d.Get("kind").(string)
 	configName := d.Get("name").(string)
+	index := uint64(d.Get("modify_index").(int))
 
-	if _, err := configEntries.Delete(configKind, configName, wOpts); err != nil {
+	ok, _, err := configEntries.DeleteCAS(configKind, configName, index, wOpts)
+	if err != nil {
 		return fmt.Errorf("failed to delete '%s' config entry: %v", configName, err)
 	}
+	if !ok {
+		// The CAS also fails when the config entry does not exist anymore
+		fixQOptsForConfigEntry(configName, configKind, qOpts)
+		_, _, err := configEntries.Get(configKind, configName, qOpts)
+		if err == nil {
+			return fmt.Errorf("failed to delete '%s' config entry: it has been modified outside of Terraform since it was last read", configName)
+		}
+		if !strings.Contains(err.Error(), "Unexpected response code: 404") {
+			return fmt.Errorf("failed to fetch '%s' config entry: %v", configName, err)
+		}
+	}
 	d.SetId("")
 	return nil
 }
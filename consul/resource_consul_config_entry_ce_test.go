@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"testing"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 )
 
@@ -137,6 +138,43 @@ func TestAccConsulConfigEntryCE_basic(t *testing.T) {
 	})
 }
 
+func TestAccConsulConfigEntryCE_CAS(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:  func() { skipTestOnConsulEnterpriseEdition(t) },
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					entry := &consulapi.ServiceConfigEntry{
+						Kind:     consulapi.ServiceDefaults,
+						Name:     "foo",
+						Protocol: "http",
+					}
+					if _, _, err := client.ConfigEntries().Set(entry, nil); err != nil {
+						t.Fatalf("failed to create config entry: %v", err)
+					}
+				},
+				Config:      testAccConsulConfigEntryCE_ServiceDefaults,
+				ExpectError: regexp.MustCompile("failed to set 'foo' config entry: it already exists, import it to manage it with Terraform"),
+			},
+			{
+				PreConfig: func() {
+					if _, err := client.ConfigEntries().Delete(consulapi.ServiceDefaults, "foo", nil); err != nil {
+						t.Fatalf("failed to delete config entry: %v", err)
+					}
+				},
+				Config: testAccConsulConfigEntryCE_ServiceDefaults,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_config_entry.foo", "config_json", "{\"Expose\":{},\"MeshGateway\":{},\"Protocol\":\"https\",\"TransparentProxy\":{}}"),
+					resource.TestCheckResourceAttrSet("consul_config_entry.foo", "modify_index"),
+				),
+			},
+		},
+	})
+}
+
 func TestAccConsulConfigEntryCE_ServicesExported(t *testing.T) {
 	providers, _ := startTestServer(t)
 
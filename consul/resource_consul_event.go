@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"log"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// maxEventPayloadSize is the default limit enforced by the Consul agents on
+// the payload of a user event.
+const maxEventPayloadSize = 512
+
+func resourceConsulEvent() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulEventCreate,
+		Read:   resourceConsulEventRead,
+		Delete: resourceConsulEventDelete,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the event.",
+			},
+
+			"payload": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateEventPayload,
+				Description:  "The payload of the event, at most 512 bytes.",
+			},
+
+			"node_filter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "A regular expression to filter the nodes receiving the event by name.",
+			},
+
+			"service_filter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "A regular expression to filter the nodes receiving the event by the services they run.",
+			},
+
+			"tag_filter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "A regular expression to filter the nodes receiving the event by the tags of the service, `service_filter` must also be set.",
+			},
+
+			"triggers": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values that fire the event again when they change.",
+			},
+
+			"event_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the event returned by Consul.",
+			},
+
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func resourceConsulEventCreate(d *schema.ResourceData, meta interface{}) error {
+	client, _, wOpts := getClient(d, meta)
+
+	event := &consulapi.UserEvent{
+		Name:          d.Get("name").(string),
+		NodeFilter:    d.Get("node_filter").(string),
+		ServiceFilter: d.Get("service_filter").(string),
+		TagFilter:     d.Get("tag_filter").(string),
+	}
+	if event.TagFilter != "" && event.ServiceFilter == "" {
+		return fmt.Errorf("service_filter must be set to use tag_filter")
+	}
+	if payload := d.Get("payload").(string); payload != "" {
+		event.Payload = []byte(payload)
+	}
+
+	log.Printf("[DEBUG] Firing event '%s' in %s", event.Name, wOpts.Datacenter)
+	id, _, err := client.Event().Fire(event, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to fire event '%s': %v", event.Name, err)
+	}
+
+	d.SetId(id)
+
+	sw := newStateWriter(d)
+	sw.set("event_id", id)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", wOpts.Datacenter)
+
+	return sw.error()
+}
+
+// resourceConsulEventRead does nothing, the events are fire-and-forget and
+// are only kept for a short time by the agents.
+func resourceConsulEventRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+// resourceConsulEventDelete only removes the resource from the state, an
+// event cannot be revoked once fired.
+func resourceConsulEventDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+func validateEventPayload(v interface{}, key string) (warnings []string, errors []error) {
+	if size := len(v.(string)); size > maxEventPayloadSize {
+		errors = append(errors, fmt.Errorf("%s must be at most %d bytes, got %d bytes", key, maxEventPayloadSize, size))
+	}
+	return warnings, errors
+}
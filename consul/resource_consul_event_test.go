@@ -0,0 +1,93 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulEvent_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	var firstID string
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulEventConfig(strings.Repeat("a", 513), "1"),
+				ExpectError: regexp.MustCompile("payload must be at most 512 bytes, got 513 bytes"),
+			},
+			{
+				Config: testAccConsulEventConfig("v1.2.3", "1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("consul_event.deploy", "event_id"),
+					resource.TestCheckResourceAttr("consul_event.deploy", "datacenter", "dc1"),
+					func(s *terraform.State) error {
+						firstID = s.RootModule().Resources["consul_event.deploy"].Primary.ID
+						return testAccCheckConsulEventFired(client, firstID, "v1.2.3")
+					},
+				),
+			},
+			{
+				// The event is fired again when the trigger changes
+				Config: testAccConsulEventConfig("v1.2.3", "2"),
+				Check: func(s *terraform.State) error {
+					id := s.RootModule().Resources["consul_event.deploy"].Primary.ID
+					if id == firstID {
+						return fmt.Errorf("the event has not been fired again")
+					}
+					return testAccCheckConsulEventFired(client, id, "v1.2.3")
+				},
+			},
+		},
+	})
+}
+
+func testAccCheckConsulEventFired(client *consulapi.Client, id, payload string) error {
+	events, _, err := client.Event().List("deploy", nil)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if event.ID == id {
+			if string(event.Payload) != payload {
+				return fmt.Errorf("unexpected payload %q", event.Payload)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("event %q not found", id)
+}
+
+func TestValidateEventPayload(t *testing.T) {
+	if _, errors := validateEventPayload(strings.Repeat("é", 256), "payload"); len(errors) != 0 {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+
+	_, errors := validateEventPayload(strings.Repeat("é", 257), "payload")
+	if len(errors) != 1 || errors[0].Error() != "payload must be at most 512 bytes, got 514 bytes" {
+		t.Fatalf("unexpected errors: %v", errors)
+	}
+}
+
+func testAccConsulEventConfig(payload, trigger string) string {
+	return fmt.Sprintf(`
+resource "consul_event" "deploy" {
+  name    = "deploy"
+  payload = %q
+
+  triggers = {
+    version = %q
+  }
+}
+`, payload, trigger)
+}
@@ -4,9 +4,13 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
@@ -79,6 +83,11 @@ Please see https://registry.terraform.io/providers/hashicorp/consul/latest/docs/
 	}
 }
 
+// intentionsConfigEntryVersion is the first version of Consul storing the
+// intentions as service-intentions config entries. These intentions are
+// managed using their source and destination instead of an ID.
+var intentionsConfigEntryVersion = version.Must(version.NewVersion("1.9.0"))
+
 func resourceConsulIntentionCreate(d *schema.ResourceData, meta interface{}) error {
 	client, _, wOpts := getClient(d, meta)
 	connect := client.Connect()
@@ -88,13 +97,26 @@ func resourceConsulIntentionCreate(d *schema.ResourceData, meta interface{}) err
 		return err
 	}
 
-	id, _, err := connect.IntentionCreate(intention, wOpts)
+	configEntries, err := intentionsUseConfigEntries(meta.(*Config))
 	if err != nil {
-		return fmt.Errorf("failed to create intention (dc: '%s'): %v", wOpts.Datacenter, err)
+		return err
+	}
+
+	if !configEntries {
+		id, _, err := connect.IntentionCreate(intention, wOpts)
+		if err != nil {
+			return fmt.Errorf("failed to create intention (dc: '%s'): %v", wOpts.Datacenter, err)
+		}
+
+		d.SetId(id)
+		return resourceConsulIntentionRead(d, meta)
 	}
 
-	d.SetId(id)
+	if err := upsertIntention(connect, intention, wOpts); err != nil {
+		return fmt.Errorf("failed to create intention (dc: '%s'): %v", wOpts.Datacenter, err)
+	}
 
+	d.SetId(intentionExactID(intention))
 	return resourceConsulIntentionRead(d, meta)
 }
 
@@ -106,12 +128,33 @@ func resourceConsulIntentionUpdate(d *schema.ResourceData, meta interface{}) err
 	if err != nil {
 		return err
 	}
-	intention.ID = d.Id()
 
-	if _, err := connect.IntentionUpdate(intention, wOpts); err != nil {
+	if _, _, ok := parseIntentionExactID(d.Id()); !ok {
+		intention.ID = d.Id()
+		if _, err := connect.IntentionUpdate(intention, wOpts); err != nil {
+			return fmt.Errorf("failed to update intention (dc: '%s'): %v", wOpts.Datacenter, err)
+		}
+
+		return resourceConsulIntentionRead(d, meta)
+	}
+
+	// The source is part of the identity of the intention, changing it means
+	// removing the previous one
+	if d.HasChanges("source_name", "source_namespace") {
+		oldName, _ := d.GetChange("source_name")
+		oldNamespace, _ := d.GetChange("source_namespace")
+		source := intentionExactName(oldNamespace.(string), oldName.(string))
+		destination := intentionExactName(intention.DestinationNS, intention.DestinationName)
+		if _, err := connect.IntentionDeleteExact(source, destination, wOpts); err != nil {
+			return fmt.Errorf("failed to delete intention from '%s' to '%s' (dc: '%s'): %v", source, destination, wOpts.Datacenter, err)
+		}
+	}
+
+	if err := upsertIntention(connect, intention, wOpts); err != nil {
 		return fmt.Errorf("failed to update intention (dc: '%s'): %v", wOpts.Datacenter, err)
 	}
 
+	d.SetId(intentionExactID(intention))
 	return resourceConsulIntentionRead(d, meta)
 }
 
@@ -121,7 +164,13 @@ func resourceConsulIntentionRead(d *schema.ResourceData, meta interface{}) error
 
 	id := d.Id()
 
-	intention, _, err := connect.IntentionGet(id, qOpts)
+	var intention *consulapi.Intention
+	var err error
+	if source, destination, ok := parseIntentionExactID(id); ok {
+		intention, _, err = connect.IntentionGetExact(source, destination, qOpts)
+	} else {
+		intention, _, err = connect.IntentionGet(id, qOpts)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to retrieve intention (dc: '%s'): %v", qOpts.Datacenter, err)
 	}
@@ -131,13 +180,22 @@ func resourceConsulIntentionRead(d *schema.ResourceData, meta interface{}) error
 		return nil
 	}
 
+	sourceNamespace := intention.SourceNS
+	if sourceNamespace == "" {
+		sourceNamespace = consulapi.IntentionDefaultNamespace
+	}
+	destinationNamespace := intention.DestinationNS
+	if destinationNamespace == "" {
+		destinationNamespace = consulapi.IntentionDefaultNamespace
+	}
+
 	sw := newStateWriter(d)
 
 	sw.set("datacenter", qOpts.Datacenter)
 	sw.set("source_name", intention.SourceName)
-	sw.set("source_namespace", intention.SourceNS)
+	sw.set("source_namespace", sourceNamespace)
 	sw.set("destination_name", intention.DestinationName)
-	sw.set("destination_namespace", intention.DestinationNS)
+	sw.set("destination_namespace", destinationNamespace)
 	sw.set("description", intention.Description)
 	sw.set("action", string(intention.Action))
 	sw.set("meta", intention.Meta)
@@ -150,7 +208,15 @@ func resourceConsulIntentionDelete(d *schema.ResourceData, meta interface{}) err
 	connect := client.Connect()
 	id := d.Id()
 
-	if _, err := connect.IntentionDelete(id, wOpts); err != nil {
+	var err error
+	if source, destination, ok := parseIntentionExactID(id); ok {
+		_, err = connect.IntentionDeleteExact(source, destination, wOpts)
+	} else {
+		_, err = connect.IntentionDelete(id, wOpts)
+	}
+
+	// The intention may have already been removed outside of Terraform
+	if err != nil && !isIntentionNotFound(err) {
 		return fmt.Errorf("failed to delete intention with id '%s' in %s: %v",
 			id, wOpts.Datacenter, err)
 	}
@@ -160,6 +226,64 @@ func resourceConsulIntentionDelete(d *schema.ResourceData, meta interface{}) err
 	return nil
 }
 
+// intentionsUseConfigEntries returns whether the Consul servers store the
+// intentions as config entries.
+func intentionsUseConfigEntries(config *Config) (bool, error) {
+	v, err := config.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+
+	return v.Core().GreaterThanOrEqual(intentionsConfigEntryVersion), nil
+}
+
+// upsertIntention writes the intention using the exact endpoint, the default
+// namespace is left out so that it also works with Consul Community Edition.
+func upsertIntention(connect *consulapi.Connect, intention *consulapi.Intention, wOpts *consulapi.WriteOptions) error {
+	ixn := *intention
+	if ixn.SourceNS == consulapi.IntentionDefaultNamespace {
+		ixn.SourceNS = ""
+	}
+	if ixn.DestinationNS == consulapi.IntentionDefaultNamespace {
+		ixn.DestinationNS = ""
+	}
+
+	_, err := connect.IntentionUpsert(&ixn, wOpts)
+	return err
+}
+
+func isIntentionNotFound(err error) bool {
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return true
+	}
+	return strings.Contains(err.Error(), "Intention not found")
+}
+
+// intentionExactName returns the name used by the exact intention endpoints
+// for a service.
+func intentionExactName(namespace, name string) string {
+	if namespace == "" || namespace == consulapi.IntentionDefaultNamespace {
+		return name
+	}
+	return namespace + "/" + name
+}
+
+// intentionExactID returns the ID of an intention stored in a config entry,
+// these intentions do not have an ID in Consul.
+func intentionExactID(intention *consulapi.Intention) string {
+	return intentionExactName(intention.SourceNS, intention.SourceName) + "=>" +
+		intentionExactName(intention.DestinationNS, intention.DestinationName)
+}
+
+func parseIntentionExactID(id string) (string, string, bool) {
+	parts := strings.Split(id, "=>")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
 func getIntention(d *schema.ResourceData) (*consulapi.Intention, error) {
 	sourceName := d.Get("source_name").(string)
 	sourceNamespace := d.Get("source_namespace").(string)
@@ -119,39 +119,35 @@ func testAccCheckConsulIntentionDestroy(client *consulapi.Client) func(s *terraf
 
 func testAccRemoveConsulIntention(t *testing.T, client *consulapi.Client) func() {
 	return func() {
-		connect := client.Connect()
-		qOpts := &consulapi.QueryOptions{}
-		iM := &consulapi.IntentionMatch{
-			By:    consulapi.IntentionMatchSource,
-			Names: []string{"api"},
-		}
-
-		resp, _, err := connect.IntentionMatch(iM, qOpts)
+		// This works both for the intentions created with an ID and for the
+		// ones stored in a config entry
+		_, err := client.Connect().IntentionDeleteExact("api", "db", &consulapi.WriteOptions{})
 		if err != nil {
-			t.Fatalf("Failed to retrieve intentions by match err: %v", err)
+			t.Errorf("Failed to delete the intention. err: %s", err)
 		}
+	}
+}
 
-		intentions, hasMatch := resp["api"]
-		if !hasMatch {
-			t.Fatalf("No intention with source api was found")
-		}
+func TestParseIntentionExactID(t *testing.T) {
+	cases := map[string]struct {
+		id          string
+		source      string
+		destination string
+		ok          bool
+	}{
+		"config entry": {"api=>db", "api", "db", true},
+		"namespaces":   {"ns/api=>other/db", "ns/api", "other/db", true},
+		"legacy":       {"6f0b3e2a-7a3b-4d1c-9c6e-2f7c1e6b8d10", "", "", false},
+		"missing":      {"api=>", "", "", false},
+	}
 
-		var iid string
-		for _, i := range intentions {
-			if _, ok := i.Meta["is_tf_acc_test"]; ok {
-				iid = i.ID
-				break
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			source, destination, ok := parseIntentionExactID(tc.id)
+			if source != tc.source || destination != tc.destination || ok != tc.ok {
+				t.Fatalf("unexpected result %q, %q, %v", source, destination, ok)
 			}
-		}
-
-		if iid == "" {
-			t.Fatalf("Failed to find the intention created by Terraform")
-		}
-
-		_, err = connect.IntentionDelete(iid, &consulapi.WriteOptions{})
-		if err != nil {
-			t.Errorf("Failed to delete the intention. err: %s", err)
-		}
+		})
 	}
 }
 
@@ -5,7 +5,10 @@ package consul
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
@@ -15,11 +18,48 @@ func resourceConsulKeyPrefix() *schema.Resource {
 		Update: resourceConsulKeyPrefixUpdate,
 		Read:   resourceConsulKeyPrefixRead,
 		Delete: resourceConsulKeyPrefixDelete,
+
+		CustomizeDiff: resourceConsulKeyPrefixCustomizeDiff,
+
 		Importer: &schema.ResourceImporter{
 			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
-				if err := d.Set("path_prefix", d.Id()); err != nil {
-					return nil, fmt.Errorf("failed to set 'path_prefix': %v", err)
+				partition, namespace, pathPrefix := parseKeyPrefixImportID(d.Id())
+
+				d.SetId(keyPrefixID(partition, namespace, pathPrefix))
+
+				sw := newStateWriter(d)
+				sw.set("path_prefix", pathPrefix)
+				sw.set("namespace", namespace)
+				sw.set("partition", partition)
+				if err := sw.error(); err != nil {
+					return nil, err
+				}
+
+				// The subkeys attribute cannot hold flags so the keys that
+				// have some are imported as subkey blocks instead, Read will
+				// then keep them there.
+				keyClient := newKeyClient(d, meta)
+				ctx := stopContext(meta)
+				pairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
+				if err != nil {
+					return nil, err
 				}
+
+				subKeySet := make([]interface{}, 0)
+				for _, pair := range pairs {
+					if keyClient.readFlags(pair.Flags, 0) == 0 {
+						continue
+					}
+					subKeySet = append(subKeySet, map[string]interface{}{
+						"path":  pair.Key[len(pathPrefix):],
+						"value": string(pair.Value),
+						"flags": int(pair.Flags),
+					})
+				}
+				if err := d.Set("subkey", subKeySet); err != nil {
+					return nil, fmt.Errorf("failed to set 'subkey': %v", err)
+				}
+
 				return []*schema.ResourceData{d}, nil
 			},
 		},
@@ -69,9 +109,10 @@ func resourceConsulKeyPrefix() *schema.Resource {
 						},
 
 						"flags": {
-							Type:     schema.TypeInt,
-							Optional: true,
-							Default:  0,
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validateKVFlags,
 						},
 					},
 				},
@@ -87,12 +128,139 @@ func resourceConsulKeyPrefix() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+
+			"delete_extra": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the keys under the prefix that are not declared in the resource are deleted. When false only the declared keys are managed and the other ones are left untouched.",
+			},
+
+			"added_keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The subkeys that do not exist yet under the prefix and that are written by the last change.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"updated_keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The subkeys whose value or flags are modified by the last change.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"removed_keys": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The subkeys that are deleted by the last change.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 		},
 	}
 }
 
+// resourceConsulKeyPrefixCustomizeDiff compares the desired subkeys with the
+// keys currently stored under the prefix so that the plan lists the subkeys
+// that will be added, updated and removed.
+func resourceConsulKeyPrefixCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.HasChange("subkeys") && !d.HasChange("subkey") {
+		return nil
+	}
+
+	diffKeys := []string{"added_keys", "updated_keys", "removed_keys"}
+	if !d.NewValueKnown("path_prefix") || !d.NewValueKnown("subkeys") || !d.NewValueKnown("subkey") {
+		for _, k := range diffKeys {
+			if err := d.SetNewComputed(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	type subKey struct {
+		value string
+		flags int
+	}
+
+	desired := map[string]subKey{}
+	for k, vI := range d.Get("subkeys").(map[string]interface{}) {
+		desired[k] = subKey{value: vI.(string), flags: 0}
+	}
+	for _, rawSubkey := range d.Get("subkey").(*schema.Set).List() {
+		subkeyData := rawSubkey.(map[string]interface{})
+		desired[subkeyData["path"].(string)] = subKey{
+			value: subkeyData["value"].(string),
+			flags: subkeyData["flags"].(int),
+		}
+	}
+
+	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+	pathPrefix := d.Get("path_prefix").(string)
+	pairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
+	if err != nil {
+		return err
+	}
+
+	// When delete_extra is false, the keys that were not declared before the
+	// change are not managed and will never be removed.
+	o, _ := d.GetChange("subkeys")
+	oldSubkeys, _ := o.(map[string]interface{})
+	oldSet, _ := d.GetChange("subkey")
+	oldSubkeySet, _ := oldSet.(*schema.Set)
+	managed := declaredSubkeys(oldSubkeys, oldSubkeySet)
+	deleteExtra := d.Get("delete_extra").(bool)
+
+	current := map[string]subKey{}
+	for _, pair := range pairs {
+		name := pair.Key[len(pathPrefix):]
+		_, isManaged := managed[name]
+		_, isDesired := desired[name]
+		if !deleteExtra && !isManaged && !isDesired {
+			continue
+		}
+		current[name] = subKey{
+			value: string(pair.Value),
+			flags: keyClient.readFlags(pair.Flags, desired[name].flags),
+		}
+	}
+
+	added := []string{}
+	updated := []string{}
+	removed := []string{}
+	for name, subkey := range desired {
+		currentSubkey, ok := current[name]
+		if !ok {
+			added = append(added, name)
+		} else if currentSubkey != subkey {
+			updated = append(updated, name)
+		}
+	}
+	for name := range current {
+		if _, ok := desired[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	for i, keys := range [][]string{added, updated, removed} {
+		sort.Strings(keys)
+		if err := d.SetNew(diffKeys[i], keys); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func resourceConsulKeyPrefixCreate(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
 
 	type subKey struct {
 		value string
@@ -124,10 +292,21 @@ func resourceConsulKeyPrefixCreate(d *schema.ResourceData, meta interface{}) err
 	// To reduce the impact of mistakes, we will only "create" a prefix that
 	// is currently empty. This way we are less likely to accidentally
 	// conflict with other mechanisms managing the same prefix.
-	currentKVPairs, err := keyClient.GetUnderPrefix(pathPrefix)
+	currentKVPairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
 	if err != nil {
 		return err
 	}
+	if !d.Get("delete_extra").(bool) {
+		// Only the declared keys are managed, the other ones can be
+		// written by other systems sharing the prefix.
+		declared := currentKVPairs[:0]
+		for _, pair := range currentKVPairs {
+			if _, ok := subKeys[pair.Key[len(pathPrefix):]]; ok {
+				declared = append(declared, pair)
+			}
+		}
+		currentKVPairs = declared
+	}
 	if len(currentKVPairs) > 0 {
 		return fmt.Errorf(
 			"%d keys already exist under %s; delete them before managing this prefix with Terraform",
@@ -141,11 +320,7 @@ func resourceConsulKeyPrefixCreate(d *schema.ResourceData, meta interface{}) err
 	// do anything and that way we can recover from errors by doing an
 	// Update on subsequent runs, rather than re-attempting Create with
 	// some keys possibly already present.
-	if pathPrefix == "" {
-		d.SetId("/")
-	} else {
-		d.SetId(pathPrefix)
-	}
+	d.SetId(keyPrefixID(keyClient.qOpts.Partition, keyClient.qOpts.Namespace, pathPrefix))
 
 	// Store the datacenter on this resource, which can be helpful for reference
 	// in case it was read from the provider
@@ -155,12 +330,16 @@ func resourceConsulKeyPrefixCreate(d *schema.ResourceData, meta interface{}) err
 	// that nothing should need deleting yet, as long as there isn't some
 	// other program racing us to write values... which we'll catch on a
 	// subsequent Read.
+	pairs := make([]consulapi.KVPair, 0, len(subKeys))
 	for name, subkey := range subKeys {
-		fullPath := pathPrefix + name
-		err := keyClient.Put(fullPath, subkey.value, subkey.flags)
-		if err != nil {
-			return fmt.Errorf("error while writing %s: %s", fullPath, err)
-		}
+		pairs = append(pairs, consulapi.KVPair{
+			Key:   pathPrefix + name,
+			Value: []byte(subkey.value),
+			Flags: uint64(subkey.flags),
+		})
+	}
+	if err := keyClient.PutBatch(ctx, pairs); err != nil {
+		return fmt.Errorf("error while writing keys under %s: %s", pathPrefix, err)
 	}
 
 	return nil
@@ -168,6 +347,7 @@ func resourceConsulKeyPrefixCreate(d *schema.ResourceData, meta interface{}) err
 
 func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
 
 	pathPrefix := d.Get("path_prefix").(string)
 
@@ -197,7 +377,7 @@ func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) err
 		for k, vI := range nm {
 			v := vI.(string)
 			fullPath := pathPrefix + k
-			err := keyClient.Put(fullPath, v, 0)
+			err := keyClient.Put(ctx, fullPath, v, 0)
 			if err != nil {
 				return fmt.Errorf("error while writing %s: %s", fullPath, err)
 			}
@@ -209,7 +389,7 @@ func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) err
 				continue
 			}
 			fullPath := pathPrefix + k
-			err := keyClient.Delete(fullPath)
+			err := keyClient.Delete(ctx, fullPath)
 			if err != nil {
 				return fmt.Errorf("error while deleting %s: %s", fullPath, err)
 			}
@@ -247,7 +427,7 @@ func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) err
 			delete(oldSubKeys, name)
 
 			fullPath := pathPrefix + name
-			err := keyClient.Put(fullPath, value, flags)
+			err := keyClient.Put(ctx, fullPath, value, flags)
 			if err != nil {
 				return fmt.Errorf("error while writing %s: %s", fullPath, err)
 			}
@@ -256,7 +436,7 @@ func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) err
 		// Remove remaining old subkey
 		for path := range oldSubKeys {
 			fullPath := pathPrefix + path
-			err := keyClient.Delete(fullPath)
+			err := keyClient.Delete(ctx, fullPath)
 			if err != nil {
 				return fmt.Errorf("error while deleting %s: %s", fullPath, err)
 			}
@@ -272,10 +452,11 @@ func resourceConsulKeyPrefixUpdate(d *schema.ResourceData, meta interface{}) err
 
 func resourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
 
 	pathPrefix := d.Get("path_prefix").(string)
 
-	pairs, err := keyClient.GetUnderPrefix(pathPrefix)
+	pairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
 	if err != nil {
 		return err
 	}
@@ -287,10 +468,15 @@ func resourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) error
 	//   - everything whose path matches a given subkey in subkeyList goes in subkeySet
 	//   - everything else goes into the subkeys attribute
 	subkeyList := d.Get("subkey").(*schema.Set).List()
+	deleteExtra := d.Get("delete_extra").(bool)
+	declared := declaredSubkeys(d.Get("subkeys").(map[string]interface{}), d.Get("subkey").(*schema.Set))
 	for _, pair := range pairs {
 		name := pair.Key[len(pathPrefix):]
+		if _, ok := declared[name]; !ok && !deleteExtra {
+			// This key is not managed by Terraform
+			continue
+		}
 		value := string(pair.Value)
-		flags := int(pair.Flags)
 		isSubkey := false
 
 		for _, rawSubkey := range subkeyList {
@@ -300,7 +486,7 @@ func resourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) error
 				subkey := map[string]interface{}{
 					"path":  name,
 					"value": value,
-					"flags": flags,
+					"flags": keyClient.readFlags(pair.Flags, subkeyData["flags"].(int)),
 				}
 				subKeySet = append(subKeySet, subkey)
 				break
@@ -326,12 +512,27 @@ func resourceConsulKeyPrefixRead(d *schema.ResourceData, meta interface{}) error
 
 func resourceConsulKeyPrefixDelete(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
 
 	pathPrefix := d.Get("path_prefix").(string)
 
+	if !d.Get("delete_extra").(bool) {
+		// Only delete the keys declared in the resource so that the keys
+		// written by other systems under the prefix are kept.
+		declared := declaredSubkeys(d.Get("subkeys").(map[string]interface{}), d.Get("subkey").(*schema.Set))
+		for name := range declared {
+			fullPath := pathPrefix + name
+			if err := keyClient.Delete(ctx, fullPath); err != nil {
+				return fmt.Errorf("error while deleting %s: %s", fullPath, err)
+			}
+		}
+		d.SetId("")
+		return nil
+	}
+
 	// Delete everything under our prefix, since the entire set of keys under
 	// the given prefix is considered to be managed exclusively by Terraform.
-	err := keyClient.DeleteUnderPrefix(pathPrefix)
+	err := keyClient.DeleteUnderPrefix(ctx, pathPrefix)
 	if err != nil {
 		return err
 	}
@@ -340,3 +541,61 @@ func resourceConsulKeyPrefixDelete(d *schema.ResourceData, meta interface{}) err
 
 	return nil
 }
+
+// declaredSubkeys returns the names of the subkeys declared in the subkeys and
+// subkey attributes.
+func declaredSubkeys(subkeys map[string]interface{}, subkeySet *schema.Set) map[string]struct{} {
+	declared := map[string]struct{}{}
+	for name := range subkeys {
+		declared[name] = struct{}{}
+	}
+	if subkeySet != nil {
+		for _, rawSubkey := range subkeySet.List() {
+			declared[rawSubkey.(map[string]interface{})["path"].(string)] = struct{}{}
+		}
+	}
+	return declared
+}
+
+// parseKeyPrefixImportID returns the partition, the namespace and the path
+// prefix of an import ID. The namespace and the partition can be given as
+// "namespace=<namespace>:" and "partition=<partition>:" prefixes so that the
+// same path can be imported from different namespaces, any other ID is the
+// path prefix itself since it may contain colons.
+func parseKeyPrefixImportID(id string) (partition, namespace, pathPrefix string) {
+	for {
+		var value *string
+		switch {
+		case strings.HasPrefix(id, "partition="):
+			value = &partition
+		case strings.HasPrefix(id, "namespace="):
+			value = &namespace
+		default:
+			return partition, namespace, id
+		}
+		end := strings.Index(id, ":")
+		if end == -1 {
+			return partition, namespace, id
+		}
+		*value = id[strings.Index(id, "=")+1 : end]
+		id = id[end+1:]
+	}
+}
+
+// keyPrefixID returns the ID of a consul_key_prefix resource. The partition and
+// the namespace are part of the ID when they are set, in the form accepted by
+// parseKeyPrefixImportID, so that the same prefix managed in two namespaces
+// does not get the same ID and the ID can be used to import the resource
+// again.
+func keyPrefixID(partition, namespace, pathPrefix string) string {
+	if pathPrefix == "" {
+		pathPrefix = "/"
+	}
+	if namespace != "" {
+		pathPrefix = fmt.Sprintf("namespace=%s:%s", namespace, pathPrefix)
+	}
+	if partition != "" {
+		pathPrefix = fmt.Sprintf("partition=%s:%s", partition, pathPrefix)
+	}
+	return pathPrefix
+}
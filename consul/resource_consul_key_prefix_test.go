@@ -31,6 +31,9 @@ func TestAccConsulKeyPrefix_basic(t *testing.T) {
 			{
 				Config: testAccConsulKeyPrefixConfig,
 				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "added_keys.#", "4"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "updated_keys.#", "0"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.#", "0"),
 					testAccCheckConsulKeyPrefixKeyValue(client, "cheese", "chevre", 0),
 					testAccCheckConsulKeyPrefixKeyValue(client, "bread", "baguette", 0),
 					testAccCheckConsulKeyPrefixKeyValue(client, "condiment/first", "tomato", 2),
@@ -52,6 +55,16 @@ func TestAccConsulKeyPrefix_basic(t *testing.T) {
 			{
 				Config: testAccConsulKeyPrefixConfig_Update,
 				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "added_keys.#", "2"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "added_keys.0", "condiment/third"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "added_keys.1", "meat"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "updated_keys.#", "2"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "updated_keys.0", "bread"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "updated_keys.1", "condiment/second"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.#", "3"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.0", "cheese"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.1", "condiment/first"),
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.2", "species"),
 					testAccCheckConsulKeyPrefixKeyValue(client, "meat", "ham", 0),
 					testAccCheckConsulKeyPrefixKeyValue(client, "bread", "batard", 0),
 					testAccCheckConsulKeyPrefixKeyValue(client, "condiment/second", "mayonnaise", 4),
@@ -93,7 +106,7 @@ func TestAccCheckConsulKeyPrefix_Import(t *testing.T) {
 				ResourceName:            "consul_key_prefix.app",
 				ImportState:             true,
 				ImportStateVerify:       true,
-				ImportStateVerifyIgnore: []string{"namespace", "partition"},
+				ImportStateVerifyIgnore: []string{"namespace", "partition", "added_keys", "updated_keys", "removed_keys"},
 			},
 		},
 	})
@@ -108,12 +121,110 @@ func TestAccConsulKeyPrefix_namespaceCE(t *testing.T) {
 		Steps: []resource.TestStep{
 			{
 				Config:      testAccConsulKeyPrefixConfig_namespaceCE,
-				ExpectError: regexp.MustCompile("Unexpected response code: 400"),
+				ExpectError: namespaceEnterpriseFeature,
 			},
 		},
 	})
 }
 
+func TestAccConsulKeyPrefix_deleteExtra(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccCheckConsulKeyPrefixKeyAbsent(client, "bread"),
+			testAccCheckConsulKeyPrefixKeyValue(client, "external", "kept", 0),
+		),
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAccAddConsulKeyPrefixRogue(client, "external", "kept")(nil)
+				},
+				Config: testAccConsulKeyPrefixConfig_deleteExtra("batard"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "subkeys.%", "1"),
+					testAccCheckConsulKeyPrefixKeyValue(client, "bread", "batard", 0),
+					testAccCheckConsulKeyPrefixKeyValue(client, "external", "kept", 0),
+				),
+			},
+			{
+				Config: testAccConsulKeyPrefixConfig_deleteExtra("baguette"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "removed_keys.#", "0"),
+					testAccCheckConsulKeyPrefixKeyValue(client, "bread", "baguette", 0),
+					testAccCheckConsulKeyPrefixKeyValue(client, "external", "kept", 0),
+				),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeyPrefix_partitionCE(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		PreCheck:  func() { skipTestOnConsulEnterpriseEdition(t) },
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeyPrefixConfig_partitionCE,
+				ExpectError: regexp.MustCompile(`partition "test-key-prefix" cannot be used, admin partitions are a Consul Enterprise feature`),
+			},
+		},
+	})
+}
+
+func TestParseKeyPrefixImportID(t *testing.T) {
+	cases := []struct {
+		id, partition, namespace, pathPrefix string
+	}{
+		{"prefix/", "", "", "prefix/"},
+		{"app:v1/", "", "", "app:v1/"},
+		{"namespace=ns:prefix/", "", "ns", "prefix/"},
+		{"namespace=ns:app:v1/", "", "ns", "app:v1/"},
+		{"partition=part:namespace=ns:prefix/", "part", "ns", "prefix/"},
+		{"partition=part:prefix/", "part", "", "prefix/"},
+		{"namespace=ns", "", "", "namespace=ns"},
+	}
+
+	for _, c := range cases {
+		partition, namespace, pathPrefix := parseKeyPrefixImportID(c.id)
+		if partition != c.partition || namespace != c.namespace || pathPrefix != c.pathPrefix {
+			t.Fatalf("unexpected result for %q: %q, %q, %q", c.id, partition, namespace, pathPrefix)
+		}
+	}
+}
+
+func TestKeyPrefixID(t *testing.T) {
+	cases := []struct {
+		partition, namespace, pathPrefix, expected string
+	}{
+		{"", "", "", "/"},
+		{"", "", "prefix/", "prefix/"},
+		{"", "ns", "prefix/", "namespace=ns:prefix/"},
+		{"part", "", "prefix/", "partition=part:prefix/"},
+		{"part", "ns", "prefix/", "partition=part:namespace=ns:prefix/"},
+		{"part", "ns", "app:v1/", "partition=part:namespace=ns:app:v1/"},
+	}
+
+	for _, c := range cases {
+		id := keyPrefixID(c.partition, c.namespace, c.pathPrefix)
+		if id != c.expected {
+			t.Fatalf("expected %q for %#v, got %q", c.expected, c, id)
+		}
+
+		// The ID can be used to import the resource again
+		partition, namespace, pathPrefix := parseKeyPrefixImportID(id)
+		if c.pathPrefix == "" {
+			c.pathPrefix = "/"
+		}
+		if partition != c.partition || namespace != c.namespace || pathPrefix != c.pathPrefix {
+			t.Fatalf("unexpected import of %q: %q, %q, %q", id, partition, namespace, pathPrefix)
+		}
+	}
+}
+
 func TestAccConsulKeyPrefix_namespaceEE(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -124,6 +235,22 @@ func TestAccConsulKeyPrefix_namespaceEE(t *testing.T) {
 			{
 				Config: testAccConsulKeyPrefixConfig_namespaceEE,
 			},
+			{
+				Config:                  testAccConsulKeyPrefixConfig_namespaceEE,
+				ResourceName:            "consul_key_prefix.test",
+				ImportState:             true,
+				ImportStateId:           "namespace=test-key-prefix:prefix_test/",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"added_keys", "updated_keys", "removed_keys"},
+			},
+			{
+				// The ID of the resource can be used to import it again
+				Config:                  testAccConsulKeyPrefixConfig_namespaceEE,
+				ResourceName:            "consul_key_prefix.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"added_keys", "updated_keys", "removed_keys"},
+			},
 		},
 	})
 }
@@ -178,6 +305,27 @@ func TestAccConsulKeyPrefix_deleted(t *testing.T) {
 	})
 }
 
+// TestAccConsulKeyPrefix_manyKeys checks that prefixes with more subkeys than
+// can fit in a single transaction are correctly written
+func TestAccConsulKeyPrefix_manyKeys(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeyPrefixConfig_manyKeys,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_key_prefix.app", "subkeys.%", "150"),
+					testAccCheckConsulKeyPrefixKeyValue(client, "key0", "value0", 0),
+					testAccCheckConsulKeyPrefixKeyValue(client, "key64", "value64", 0),
+					testAccCheckConsulKeyPrefixKeyValue(client, "key149", "value149", 0),
+				),
+			},
+		},
+	})
+}
+
 func TestAccConsulKeyPrefix_datacenter(t *testing.T) {
 	providers, client := startRemoteDatacenterTestServer(t)
 
@@ -335,6 +483,12 @@ resource "consul_key_prefix" "app" {
         bread = "batard"
         meat = "ham"
     }
+
+	subkey {
+		path  = "condiment/first"
+		value = "tomato"
+		flags = 2
+	}
 }
 `
 
@@ -349,6 +503,28 @@ resource "consul_key_prefix" "test" {
   }
 }`
 
+func testAccConsulKeyPrefixConfig_deleteExtra(bread string) string {
+	return fmt.Sprintf(`
+resource "consul_key_prefix" "app" {
+  path_prefix  = "prefix_test/"
+  delete_extra = false
+
+  subkeys = {
+    bread = "%s"
+  }
+}`, bread)
+}
+
+const testAccConsulKeyPrefixConfig_partitionCE = `
+resource "consul_key_prefix" "test" {
+  path_prefix = "prefix_test/"
+  partition   = "test-key-prefix"
+
+  subkeys = {
+    bread = "batard"
+  }
+}`
+
 const testAccConsulKeyPrefixConfig_namespaceEE = `
 resource "consul_namespace" "test" {
   name = "test-key-prefix"
@@ -381,6 +557,15 @@ resource "consul_key_prefix" "app" {
 }
 `
 
+const testAccConsulKeyPrefixConfig_manyKeys = `
+resource "consul_key_prefix" "app" {
+	datacenter = "dc1"
+    path_prefix = "prefix_test/"
+
+	subkeys = { for i in range(150) : "key${i}" => "value${i}" }
+}
+`
+
 const testAccConsulKeyPrefixConfig_datacenter = `
 resource "consul_key_prefix" "dc1" {
     path_prefix = "foo/"
@@ -4,14 +4,43 @@
 package consul
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+// The supported values of the value_type attribute of a key
+const (
+	keyValueTypeString = "string"
+	keyValueTypeJSON   = "json"
+)
+
+// The supported values of the update_mode attribute of a key
+const (
+	keyUpdateModeCAS      = "cas"
+	keyUpdateModeCASRetry = "cas_retry"
+)
+
+// The supported values of the merge_mode attribute of a key
+const (
+	keyMergeModeReplace   = "replace"
+	keyMergeModeDeepMerge = "deep_merge"
 )
 
 func resourceConsulKeys() *schema.Resource {
-	return &schema.Resource{
+	r := &schema.Resource{
 		Create: resourceConsulKeysCreateUpdate,
 		Update: resourceConsulKeysCreateUpdate,
 		Read:   resourceConsulKeysRead,
@@ -20,9 +49,112 @@ func resourceConsulKeys() *schema.Resource {
 		SchemaVersion: 1,
 		MigrateState:  resourceConsulKeysMigrateState,
 
-		CustomizeDiff: func(d *schema.ResourceDiff, _ interface{}) error {
+		CustomizeDiff: func(d *schema.ResourceDiff, meta interface{}) error {
+			for _, raw := range d.Get("key").(*schema.Set).List() {
+				sub := raw.(map[string]interface{})
+				if sub["path_url_encoded"].(bool) {
+					path, err := keyPath(sub)
+					if err != nil {
+						return err
+					}
+					if err := checkKVPath(path); err != nil {
+						return fmt.Errorf("invalid path for key %q: %v", sub["path"].(string), err)
+					}
+				}
+				if err := checkAllowedFlags(sub); err != nil {
+					return err
+				}
+				if sub["value"].(string) != "" && sub["value_base64"].(string) != "" {
+					return fmt.Errorf("only one of value and value_base64 can be set for key %q", sub["path"].(string))
+				}
+				if sub["value_source_file"].(string) != "" && (sub["value"].(string) != "" || sub["value_base64"].(string) != "") {
+					return fmt.Errorf("value_source_file cannot be used with value or value_base64 for key %q", sub["path"].(string))
+				}
+				if sub["value_env"].(string) != "" && (sub["value"].(string) != "" || sub["value_base64"].(string) != "" || sub["value_source_file"].(string) != "") {
+					return fmt.Errorf("value_env cannot be used with value, value_base64 or value_source_file for key %q", sub["path"].(string))
+				}
+				// The value of a named key is exported in var, which is not
+				// sensitive
+				if sub["value_env"].(string) != "" && sub["name"].(string) != "" {
+					return fmt.Errorf("value_env cannot be used with name for key %q", sub["path"].(string))
+				}
+				if sub["encrypt"].(bool) && len(meta.(*Config).encryptionKey) == 0 {
+					return fmt.Errorf("encryption_key must be set in the provider configuration to encrypt key %q", sub["path"].(string))
+				}
+				if sub["ttl"].(string) != "" && len(sub["datacenters"].([]interface{})) > 0 {
+					return fmt.Errorf("datacenters cannot be used with ttl for key %q", sub["path"].(string))
+				}
+				if len(sub["precondition"].([]interface{})) > 0 && (sub["ttl"].(string) != "" || sub["update_mode"].(string) == keyUpdateModeCASRetry) {
+					return fmt.Errorf("precondition cannot be used with ttl or update_mode %q for key %q", keyUpdateModeCASRetry, sub["path"].(string))
+				}
+				if sub["ttl"].(string) != "" && sub["update_mode"].(string) == keyUpdateModeCASRetry {
+					return fmt.Errorf("update_mode %q cannot be used with ttl for key %q", keyUpdateModeCASRetry, sub["path"].(string))
+				}
+				if d.Get("create_only").(bool) && sub["ttl"].(string) != "" {
+					return fmt.Errorf("create_only cannot be used with ttl for key %q", sub["path"].(string))
+				}
+				if sub["expected_modify_index"].(int) != 0 && (sub["ttl"].(string) != "" || sub["update_mode"].(string) == keyUpdateModeCASRetry || d.Get("create_only").(bool)) {
+					return fmt.Errorf("expected_modify_index cannot be used with ttl, create_only or update_mode %q for key %q", keyUpdateModeCASRetry, sub["path"].(string))
+				}
+				if sub["session"].(string) != "" && (sub["ttl"].(string) != "" || len(sub["datacenters"].([]interface{})) > 0 || len(sub["precondition"].([]interface{})) > 0 || sub["expected_modify_index"].(int) != 0 || sub["update_mode"].(string) == keyUpdateModeCASRetry || d.Get("create_only").(bool)) {
+					return fmt.Errorf("session cannot be used with ttl, datacenters, precondition, expected_modify_index, create_only or update_mode %q for key %q", keyUpdateModeCASRetry, sub["path"].(string))
+				}
+				if sub["merge_mode"].(string) == keyMergeModeDeepMerge {
+					if sub["ttl"].(string) != "" || sub["session"].(string) != "" || len(sub["datacenters"].([]interface{})) > 0 || len(sub["precondition"].([]interface{})) > 0 || sub["expected_modify_index"].(int) != 0 || d.Get("create_only").(bool) ||
+						sub["value_base64"].(string) != "" || sub["value_source_file"].(string) != "" || sub["value_env"].(string) != "" {
+						return fmt.Errorf("merge_mode %q cannot be used with ttl, session, datacenters, precondition, expected_modify_index, create_only, value_base64, value_source_file or value_env for key %q", keyMergeModeDeepMerge, sub["path"].(string))
+					}
+					if _, err := jsonObject(sub["value"].(string)); err != nil {
+						return fmt.Errorf("merge_mode %q requires the value of key %q to be a JSON object: %v", keyMergeModeDeepMerge, sub["path"].(string), err)
+					}
+				}
+				// The size of the compressed and encrypted values is only
+				// known once they are written
+				if max := meta.(*Config).KVMaxValueBytes; max > 0 && !sub["compress"].(bool) && !sub["encrypt"].(bool) && len(sub["value"].(string)) > max {
+					return fmt.Errorf("invalid value for key %q: %w", sub["path"].(string), valueTooLargeError(len(sub["value"].(string)), max))
+				}
+				if sub["value_type"].(string) == keyValueTypeJSON && sub["value"].(string) != "" {
+					if _, err := canonicalJSON(sub["value"].(string)); err != nil {
+						return fmt.Errorf("the value of key %q is not valid JSON: %v", sub["path"].(string), err)
+					}
+				}
+			}
+
+			// The keys written with create_only are never overwritten, changing
+			// them would silently do nothing
+			if d.Get("create_only").(bool) && d.Id() != "" && d.HasChange("key") {
+				return fmt.Errorf("create_only is set, the keys cannot be updated once they have been created")
+			}
+
 			if d.HasChange("key") {
 				d.SetNewComputed("var")
+				d.SetNewComputed("sessions")
+				d.SetNewComputed("session_renewals")
+				d.SetNewComputed("cas_attempts")
+				d.SetNewComputed("content_sha256")
+			}
+
+			// The files and environment variables are read during the plan so
+			// that a change of their content is shown even when the key itself
+			// did not change
+			if !d.NewValueKnown("key") {
+				return d.SetNewComputed("value_source_sha256")
+			}
+			hashes, err := keySourceHashes(d.Get("key").(*schema.Set).List())
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(hashes, d.Get("value_source_sha256").(map[string]interface{})) {
+				if err := d.SetNew("value_source_sha256", hashes); err != nil {
+					return err
+				}
+			}
+
+			// The sessions of the keys with a TTL are renewed once half of
+			// their TTL has elapsed
+			if keySessionsExpiring(d.Get("key").(*schema.Set).List(), d.Get("sessions").(map[string]interface{}), d.Get("session_renewals").(map[string]interface{}), time.Now()) {
+				d.SetNewComputed("sessions")
+				d.SetNewComputed("session_renewals")
 			}
 			return nil
 		},
@@ -55,8 +187,18 @@ func resourceConsulKeys() *schema.Resource {
 						},
 
 						"path": {
-							Type:     schema.TypeString,
-							Required: true,
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateKVPath,
+						},
+
+						// The paths of the keys containing characters that
+						// are hard to write in the configuration can be
+						// given URL-encoded
+						"path_url_encoded": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
 						},
 
 						"value": {
@@ -65,10 +207,51 @@ func resourceConsulKeys() *schema.Resource {
 							Computed: true,
 						},
 
+						"value_base64": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validation.StringIsBase64,
+						},
+
+						"value_source_file": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"value_env": {
+							Type:      schema.TypeString,
+							Optional:  true,
+							Sensitive: true,
+						},
+
+						"allow_empty": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+
 						"flags": {
-							Type:     schema.TypeInt,
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Computed:     true,
+							ValidateFunc: validateKVFlags,
+							// The flags of the key are kept as they are when
+							// they are not declared, or declared as 0 since the
+							// SDK cannot tell both apart in the blocks of a set:
+							// flags = 0 means that they are not managed
+							DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+								return new == "0"
+							},
+						},
+
+						"allowed_flags": {
+							Type:     schema.TypeList,
 							Optional: true,
-							Default:  0,
+							Elem: &schema.Schema{
+								Type:         schema.TypeInt,
+								ValidateFunc: validation.IntAtLeast(1),
+							},
 						},
 
 						"default": {
@@ -82,10 +265,172 @@ func resourceConsulKeys() *schema.Resource {
 							Optional: true,
 							Default:  false,
 						},
+
+						"compress": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+
+						"encrypt": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+
+						"modify_index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+
+						"ttl": {
+							Type:     schema.TypeString,
+							Optional: true,
+							ValidateFunc: makeValidationFunc("ttl", []interface{}{
+								validateDurationMin("10s"),
+							}),
+						},
+
+						"session": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"value_schema": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateJSONSchemaDocument,
+						},
+
+						"value_type": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{keyValueTypeString, keyValueTypeJSON}, false),
+						},
+
+						"datacenters": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+
+						"update_mode": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      keyUpdateModeCAS,
+							ValidateFunc: validation.StringInSlice([]string{keyUpdateModeCAS, keyUpdateModeCASRetry}, false),
+						},
+
+						"max_cas_retries": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      5,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+
+						"expected_modify_index": {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(0),
+						},
+
+						"merge_mode": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice([]string{keyMergeModeReplace, keyMergeModeDeepMerge}, false),
+						},
+
+						"precondition": {
+							Type:     schema.TypeList,
+							Optional: true,
+							MaxItems: 1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"check_key": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"check_value": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+								},
+							},
+						},
 					},
 				},
 			},
 
+			"lock_delay": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateDurationMinFactory("lock_delay", "0s"),
+				Description:  "The lock delay of the sessions used for the keys with a `ttl`. The keys with the same `ttl` and `lock_delay` share the same session, even across resources.",
+			},
+
+			"allow_stale": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the keys can be refreshed from any Consul server instead of the leader.",
+			},
+
+			"consistent_delete_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether a key read with allow_stale that contradicts the state, like a deleted key that is still returned, is read again from the leader before being reported.",
+			},
+
+			"create_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the keys must only be created, the creation fails if one of them already exists and the keys cannot be updated afterwards.",
+			},
+
+			"sessions": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"session_renewals": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The time at which the session of each key with a `ttl` was last renewed, indexed by path.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"cas_attempts": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeInt,
+				},
+			},
+
+			"value_source_sha256": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"content_sha256": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The SHA-256 checksum of the bytes stored in Consul for each key written by the resource, indexed by path. The keys whose stored value is unchanged are not written again.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
 			"var": {
 				Type:     schema.TypeMap,
 				Computed: true,
@@ -107,78 +452,393 @@ func resourceConsulKeys() *schema.Resource {
 			},
 		},
 	}
+
+	key := r.Schema["key"]
+	key.Set = keyHash(key.Elem.(*schema.Resource))
+	return r
+}
+
+// keyHash returns the hash function of the key blocks. The flags are left out
+// of the hash since they are also read from Consul: the flags set outside of
+// Terraform on a key that does not declare them are then not a change of its
+// block, while a change of the declared flags is reported as an in-place
+// update of the block.
+func keyHash(elem *schema.Resource) schema.SchemaSetFunc {
+	hashed := make(map[string]*schema.Schema, len(elem.Schema))
+	for k, s := range elem.Schema {
+		if k != "flags" {
+			hashed[k] = s
+		}
+	}
+	return schema.HashResource(&schema.Resource{Schema: hashed})
 }
 
 func resourceConsulKeysCreateUpdate(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+
+	// With create_only the keys are written using a check-and-set operation
+	// with index 0 so that Consul only creates them if they do not exist yet
+	createOnly := d.Get("create_only").(bool) && d.IsNewResource()
+
+	o, n := d.GetChange("key")
+	if o == nil {
+		o = new(schema.Set)
+	}
+	if n == nil {
+		n = new(schema.Set)
+	}
 
-	if d.HasChange("key") {
-		o, n := d.GetChange("key")
-		if o == nil {
-			o = new(schema.Set)
+	os := o.(*schema.Set)
+	ns := n.(*schema.Set)
+
+	remove := os.Difference(ns).List()
+	add := ns.Difference(os)
+
+	// The keys whose path changed are moved in a single transaction
+	renames := keyRenames(os, ns)
+	renamed := make(map[string]bool, len(renames))
+	for _, from := range renames {
+		renamed[from] = true
+	}
+
+	// Validate all the values before writing anything so that a malformed
+	// value does not leave the keys partially updated.
+	for _, raw := range add.List() {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+		document := sub["value_schema"].(string)
+		if document == "" {
+			continue
+		}
+		value, err := keyValue(sub)
+		if err != nil {
+			return err
+		}
+		if err := validateJSONSchemaValue(document, value); err != nil {
+			return fmt.Errorf("failed to validate the value of Consul key '%s': %s", path, err)
+		}
+	}
+
+	// Keys with a TTL are written using a session that will delete them
+	// once it expires. The sessions are renewed on each apply and we
+	// release those of the keys that no longer have a TTL.
+	sessions, created, err := renewKeySessions(ctx, keyClient, &meta.(*Config).sessionPool, d, ns)
+	if err != nil {
+		return err
+	}
+	if err := d.Set("sessions", sessions); err != nil {
+		return err
+	}
+	renewals := make(map[string]string, len(sessions))
+	for path := range sessions {
+		renewals[path] = time.Now().UTC().Format(time.RFC3339)
+	}
+	if err := d.Set("session_renewals", renewals); err != nil {
+		return err
+	}
+
+	// The keys whose file or environment variable changed must be written
+	// again even if their block did not change
+	oldHashes, _ := d.GetChange("value_source_sha256")
+	hashes, err := keySourceHashes(ns.List())
+	if err != nil {
+		return err
+	}
+	sourceChanged := make(map[string]bool)
+	for path, hash := range hashes {
+		sourceChanged[path] = oldHashes.(map[string]interface{})[path] != hash
+	}
+	if err := d.Set("value_source_sha256", hashes); err != nil {
+		return err
+	}
+
+	// The keys are written using check-and-set operations against the
+	// index we last read so that concurrent modifications are detected.
+	modifyIndexes := make(map[string]int)
+	oldFlags := make(map[string]int)
+	oldEncodings := make(map[string]int)
+	for _, raw := range os.List() {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+		if index, ok := sub["modify_index"].(int); ok {
+			modifyIndexes[path] = index
 		}
-		if n == nil {
-			n = new(schema.Set)
+		if flags, ok := sub["flags"].(int); ok {
+			oldFlags[path] = flags
 		}
+		oldEncodings[path] = keyEncodingFlags(sub)
+	}
 
-		os := o.(*schema.Set)
-		ns := n.(*schema.Set)
+	// The checksums of the values stored in Consul during the last read, the
+	// keys that already have the value to write are left untouched
+	storedHashes, _ := d.GetChange("content_sha256")
+	var batch, retried []casOp
+	var merges []keyMerge
 
-		remove := os.Difference(ns).List()
-		add := ns.Difference(os).List()
+	// The sub-keys previously declared by the keys using the deep_merge mode
+	// are removed from their value when they are no longer declared
+	previousMerges := make(map[string]string)
+	for _, raw := range remove {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+		if sub["merge_mode"].(string) == keyMergeModeDeepMerge && sub["delete"].(bool) {
+			previousMerges[path] = sub["value"].(string)
+		}
+	}
+	var replicas []keyReplica
+	replicatedTo := make(map[string][]string)
 
-		// We'll keep track of what keys we add so that if a key is
-		// in both the "remove" and "add" sets -- which will happen if
-		// its value is changed in-place -- we will avoid writing the
-		// value and then immediately removing it.
-		addedPaths := make(map[string]bool)
+	// We'll keep track of what keys we add so that if a key is
+	// in both the "remove" and "add" sets -- which will happen if
+	// its value is changed in-place -- we will avoid writing the
+	// value and then immediately removing it.
+	addedPaths := make(map[string]bool)
 
-		// We add before we remove because then it's possible to change
-		// a key name (which will result in both an add and a remove)
-		// without very temporarily having *neither* value in the store.
-		// Instead, both will briefly be present, which should be less
-		// disruptive in most cases.
-		for _, raw := range add {
-			_, path, sub, err := parseKey(raw)
-			if err != nil {
-				return err
-			}
+	// We add before we remove because then it's possible to change
+	// a key name (which will result in both an add and a remove)
+	// without very temporarily having *neither* value in the store.
+	// Instead, both will briefly be present, which should be less
+	// disruptive in most cases.
+	maxCasRetries := make(map[string]int)
+	for _, raw := range ns.List() {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+		if sub["update_mode"].(string) == keyUpdateModeCASRetry {
+			maxCasRetries[path] = sub["max_cas_retries"].(int)
+		}
 
-			// The name attribute is set when using consul_keys to read values
-			// from the KV store. We must not overwrite the value when are
-			// reading.
-			name := sub["name"].(string)
-			value := sub["value"].(string)
-			if name != "" && value == "" {
-				continue
+		// Keys whose session has been replaced must be written again
+		// to be attached to the new one. The flags are not part of the
+		// hash of the blocks, see keyHash, the keys whose declared flags
+		// changed must be written again too.
+		declaredFlags, _ := sub["flags"].(int)
+		previousFlags, existed := oldFlags[path]
+		flagsChanged := existed && declaredFlags != 0 && declaredFlags != previousFlags
+		if !add.Contains(raw) && created[path] == "" && !sourceChanged[path] && !flagsChanged {
+			continue
+		}
+
+		// The name attribute is set when using consul_keys to read values
+		// from the KV store. We must not overwrite the value when are
+		// reading.
+		name := sub["name"].(string)
+		if name != "" && sub["value"].(string) == "" && sub["value_base64"].(string) == "" {
+			continue
+		}
+		value, err := keyValue(sub)
+		if err != nil {
+			return err
+		}
+		if sub["value_type"].(string) == keyValueTypeJSON {
+			value, err = canonicalJSON(value)
+			if err != nil {
+				return fmt.Errorf("the value of Consul key '%s' is not valid JSON: %v", path, err)
 			}
+		}
 
-			flags := sub["flags"].(int)
+		// Only the plain writes can be skipped, the keys using a session, a
+		// merge, a rename or a replication need the write itself. The
+		// checksum is the one of the bytes stored in Consul, there is none
+		// for the encrypted keys and those using value_env.
+		encoded, err := encodeValue(value, keyEncodingFlags(sub)&kvFlagCompressed)
+		if err != nil {
+			return fmt.Errorf("failed to write Consul key '%s': %v", path, err)
+		}
+		storedHash, _ := storedHashes.(map[string]interface{})[path].(string)
+		previousEncoding, existed := oldEncodings[path]
+		if existed && previousEncoding == keyEncodingFlags(sub) && !flagsChanged && !createOnly &&
+			storedHash != "" && storedHash == contentHash(encoded) &&
+			sessions[path] == "" && sub["session"].(string) == "" && sub["merge_mode"].(string) != keyMergeModeDeepMerge &&
+			renames[path] == "" && len(keyDatacenters(sub, keyClient)) == 0 {
+			keyClient.logf("DEBUG", "set", path, "The value stored in Consul is unchanged, skipping the write")
+			addedPaths[path] = true
+			continue
+		}
 
-			if err := keyClient.Put(path, value, flags); err != nil {
+		flags := sub["flags"].(int)
+		if flags == 0 && !createOnly {
+			// The flags are not managed by Terraform when they are not set,
+			// those already stored on the key are kept.
+			pair, err := keyClient.getPair(ctx, path)
+			if err != nil {
 				return err
 			}
+			if pair != nil {
+				flags = int(pair.Flags) &^ kvFlagCompressed &^ kvFlagEncrypted
+			}
+		}
+		flags |= keyEncodingFlags(sub)
+
+		if sub["merge_mode"].(string) == keyMergeModeDeepMerge {
+			merges = append(merges, keyMerge{
+				path:       path,
+				value:      value,
+				previous:   previousMerges[path],
+				flags:      flags,
+				maxRetries: sub["max_cas_retries"].(int),
+			})
 			addedPaths[path] = true
+			continue
 		}
 
-		for _, raw := range remove {
-			_, path, sub, err := parseKey(raw)
+		sessionID := sessions[path]
+		if session := sub["session"].(string); session != "" {
+			sessionID = session
+		}
+		if sessionID != "" {
+			acquired, err := keyClient.PutAcquire(ctx, path, value, flags, sessionID)
 			if err != nil {
 				return err
 			}
+			if !acquired {
+				entry, _, err := keyClient.Get(ctx, path)
+				if err != nil {
+					return err
+				}
+				if entry.session != "" && entry.session != sessionID {
+					return fmt.Errorf("failed to write Consul key '%s': its lock is held by session '%s'", path, entry.session)
+				}
+				return fmt.Errorf("failed to write Consul key '%s': the lock could not be acquired with session '%s'", path, sessionID)
+			}
+		} else {
+			cas := modifyIndexes[path]
+			if expected := sub["expected_modify_index"].(int); expected != 0 {
+				// The key is written against the index pinned in the
+				// configuration rather than the one last read, the CAS
+				// operation still protects against a change made after this
+				// check.
+				pair, err := keyClient.getPair(ctx, path)
+				if err != nil {
+					return err
+				}
+				var current uint64
+				if pair != nil {
+					current = pair.ModifyIndex
+				}
+				if current != uint64(expected) {
+					return fmt.Errorf("the modify index of Consul key '%s' is %d but expected_modify_index is %d, it has been modified since", path, current, expected)
+				}
+				cas = expected
+			} else if cas == 0 && !createOnly {
+				// This key was not managed by the resource yet
+				entry, _, err := keyClient.Get(ctx, path)
+				if err != nil {
+					return err
+				}
+				cas = int(entry.modifyIndex)
+			}
+			op := casOp{Path: path, Value: value, Flags: flags, Cas: cas, RenamedFrom: renames[path]}
+			if preconditions := sub["precondition"].([]interface{}); len(preconditions) > 0 && preconditions[0] != nil {
+				check, err := checkPrecondition(ctx, keyClient, path, preconditions[0].(map[string]interface{}))
+				if err != nil {
+					return err
+				}
+				op.Checks = append(op.Checks, check)
+			}
+			if sub["update_mode"].(string) == keyUpdateModeCASRetry && !createOnly {
+				retried = append(retried, op)
+			} else {
+				batch = append(batch, op)
+			}
+		}
+		addedPaths[path] = true
 
-			// Don't delete something we've just added.
-			// (See explanation at the declaration of this variable above.)
-			if addedPaths[path] {
-				continue
+		datacenters := keyDatacenters(sub, keyClient)
+		replicatedTo[path] = datacenters
+		if len(datacenters) > 0 {
+			replicas = append(replicas, keyReplica{path: path, value: value, flags: flags, datacenters: datacenters})
+		}
+	}
+
+	if len(batch) > 0 {
+		if _, err := keyClient.CasBatch(ctx, batch); err != nil {
+			if createOnly {
+				return fmt.Errorf("create_only is set and some keys already exist: %s", err)
 			}
+			return err
+		}
+	}
+
+	// The keys expected to be modified concurrently are written one by one,
+	// and written again against the latest modify index on a conflict.
+	attempts := make(map[string]interface{})
+	for path, count := range d.Get("cas_attempts").(map[string]interface{}) {
+		attempts[path] = count
+	}
+	for _, op := range retried {
+		count, err := casRetry(ctx, keyClient, op, maxCasRetries[op.Path])
+		if err != nil {
+			return err
+		}
+		attempts[op.Path] = count
+	}
 
-			shouldDelete, ok := sub["delete"].(bool)
-			if !ok || !shouldDelete {
+	// The keys using the deep_merge mode are read again and merged one by one
+	for _, m := range merges {
+		if err := mergeKey(ctx, keyClient, m); err != nil {
+			return err
+		}
+	}
+	for path := range attempts {
+		if _, ok := maxCasRetries[path]; !ok {
+			delete(attempts, path)
+		}
+	}
+	if err := d.Set("cas_attempts", attempts); err != nil {
+		return err
+	}
+
+	// The keys are then copied to the other datacenters they are replicated to
+	for _, replica := range replicas {
+		if err := replicateKey(d, meta, keyClient, replica); err != nil {
+			return err
+		}
+	}
+
+	for _, raw := range remove {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return err
+		}
+
+		shouldDelete, ok := sub["delete"].(bool)
+		if !ok || !shouldDelete || renamed[path] {
+			continue
+		}
+
+		// Only the sub-keys declared by the resource are removed from a key
+		// using the deep_merge mode
+		if sub["merge_mode"].(string) == keyMergeModeDeepMerge {
+			if addedPaths[path] {
 				continue
 			}
+			if err := mergeKey(ctx, keyClient, unmergeKey(path, sub)); err != nil {
+				return err
+			}
+			continue
+		}
 
-			if err := keyClient.Delete(path); err != nil {
+		// Don't delete something we've just added.
+		// (See explanation at the declaration of this variable above.)
+		// It must still be removed from the datacenters it is no longer
+		// replicated to.
+		datacenters := keyDatacenters(sub, keyClient)
+		if addedPaths[path] {
+			datacenters = stringsDifference(datacenters, replicatedTo[path])
+		} else if err := keyClient.Delete(ctx, path); err != nil {
+			return err
+		}
+		for _, dc := range datacenters {
+			if err := newKeyClient(d, meta, withDatacenter(dc), withRequestID(keyClient.requestID)).Delete(ctx, path); err != nil {
 				return err
 			}
 		}
@@ -193,13 +853,341 @@ func resourceConsulKeysCreateUpdate(d *schema.ResourceData, meta interface{}) er
 	// with some value to indicate the resource has been created.
 	d.SetId("consul")
 
-	return resourceConsulKeysRead(d, meta)
+	// The keys just written are read from the leader, a follower may not
+	// have seen the writes yet
+	return readConsulKeys(d, meta, false)
+}
+
+// checkPrecondition returns an error when the check key of the precondition
+// of a key does not have the expected value. Otherwise the modify index of the
+// check key is returned so that the write can be made only if it has not been
+// modified since.
+func checkPrecondition(ctx context.Context, keyClient *keyClient, path string, precondition map[string]interface{}) (indexCheck, error) {
+	checkKey := precondition["check_key"].(string)
+	checkValue := precondition["check_value"].(string)
+
+	entry, _, err := keyClient.Get(ctx, checkKey)
+	if err != nil {
+		return indexCheck{}, err
+	}
+	if entry.modifyIndex == 0 {
+		return indexCheck{}, fmt.Errorf("precondition of Consul key '%s' failed: the check key '%s' does not exist", path, checkKey)
+	}
+	if entry.value != checkValue {
+		return indexCheck{}, fmt.Errorf("precondition of Consul key '%s' failed: the check key '%s' is %q, expected %q", path, checkKey, entry.value, checkValue)
+	}
+	return indexCheck{Path: checkKey, Index: entry.modifyIndex}, nil
+}
+
+// casRetry writes the key using a check-and-set operation, when the key has
+// been modified since its modify index was read it is read again and the
+// write is retried up to maxRetries times. It returns the number of attempts
+// made.
+func casRetry(ctx context.Context, keyClient *keyClient, op casOp, maxRetries int) (int, error) {
+	cas := uint64(op.Cas)
+	for attempt := 1; ; attempt++ {
+		written, err := keyClient.Cas(ctx, op.Path, op.Value, op.Flags, cas)
+		if err != nil {
+			return attempt, err
+		}
+		if written {
+			return attempt, nil
+		}
+		if attempt > maxRetries {
+			return attempt, fmt.Errorf("failed to write Consul key '%s': it was modified concurrently during each of the %d attempts", op.Path, attempt)
+		}
+
+		keyClient.logf("DEBUG", "cas", op.Path, "Key has been modified since index %d, retrying the write", cas)
+		entry, _, err := keyClient.Get(ctx, op.Path)
+		if err != nil {
+			return attempt, err
+		}
+		cas = entry.modifyIndex
+	}
+}
+
+// keyMerge is a key written with the deep_merge mode, only the sub-keys of
+// its value are managed by the resource. previous holds the sub-keys it
+// declared before the change so that those no longer declared are removed.
+type keyMerge struct {
+	path       string
+	value      string
+	previous   string
+	flags      int
+	maxRetries int
+}
+
+// unmergeKey returns the keyMerge removing the sub-keys declared by sub from
+// its key.
+func unmergeKey(path string, sub map[string]interface{}) keyMerge {
+	flags := sub["flags"].(int)
+	if sub["compress"].(bool) {
+		flags |= kvFlagCompressed
+	}
+	if sub["encrypt"].(bool) {
+		flags |= kvFlagEncrypted
+	}
+	return keyMerge{
+		path:       path,
+		previous:   sub["value"].(string),
+		flags:      flags,
+		maxRetries: sub["max_cas_retries"].(int),
+	}
+}
+
+// mergeKey removes from the current value of the key the sub-keys of
+// m.previous that m.value no longer declares, deep-merges m.value into it and
+// writes the result using a check-and-set operation. When the key has been
+// modified since it was read, the merge is made again on its new value up to
+// m.maxRetries times. The key is deleted once it holds no sub-keys anymore.
+func mergeKey(ctx context.Context, keyClient *keyClient, m keyMerge) error {
+	declared, err := jsonObject(m.value)
+	if err != nil {
+		return fmt.Errorf("failed to merge Consul key '%s': %v", m.path, err)
+	}
+	previous, err := jsonObject(m.previous)
+	if err != nil {
+		return fmt.Errorf("failed to merge Consul key '%s': %v", m.path, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		entry, _, err := keyClient.Get(ctx, m.path)
+		if err != nil {
+			return err
+		}
+		current, err := jsonObject(entry.value)
+		if err != nil {
+			return fmt.Errorf("failed to merge Consul key '%s': its current value is not a JSON object", m.path)
+		}
+
+		removeJSONKeys(current, previous, declared)
+		mergeJSON(current, declared)
+
+		// The flags of the key are kept when none are declared
+		flags := m.flags
+		if flags&^(kvFlagCompressed|kvFlagEncrypted) == 0 {
+			flags |= entry.flags &^ kvFlagCompressed &^ kvFlagEncrypted
+		}
+
+		var written bool
+		if len(current) == 0 {
+			if entry.modifyIndex == 0 {
+				return nil
+			}
+			written, err = keyClient.DeleteCas(ctx, m.path, entry.modifyIndex)
+		} else {
+			// The keys of the objects are sorted by json.Marshal
+			value, _ := json.Marshal(current)
+			written, err = keyClient.Cas(ctx, m.path, string(value), flags, entry.modifyIndex)
+		}
+		if err != nil {
+			return err
+		}
+		if written {
+			return nil
+		}
+		if attempt > m.maxRetries {
+			return fmt.Errorf("failed to merge Consul key '%s': it was modified concurrently during each of the %d attempts", m.path, attempt)
+		}
+		keyClient.logf("DEBUG", "cas", m.path, "Key has been modified since index %d, merging it again", entry.modifyIndex)
+	}
+}
+
+// jsonObject decodes a JSON object, an empty value is an empty object.
+func jsonObject(value string) (map[string]interface{}, error) {
+	object := make(map[string]interface{})
+	if value == "" {
+		return object, nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return nil, err
+	}
+	object, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON object, got %s", value)
+	}
+	return object, nil
+}
+
+// mergeJSON deep-merges src into dst, the values of src replace those of dst
+// unless both are objects.
+func mergeJSON(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if srcObject, ok := v.(map[string]interface{}); ok {
+			if dstObject, ok := dst[k].(map[string]interface{}); ok {
+				mergeJSON(dstObject, srcObject)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// removeJSONKeys removes from current the leaves of previous that declared
+// does not have, the objects left empty are removed as well. The sub-keys
+// written by others under the same objects are kept.
+func removeJSONKeys(current, previous, declared map[string]interface{}) {
+	for k, v := range previous {
+		declaredValue, isDeclared := declared[k]
+		previousObject, ok := v.(map[string]interface{})
+		currentObject, isObject := current[k].(map[string]interface{})
+		if ok && isObject {
+			declaredObject, isDeclaredObject := declaredValue.(map[string]interface{})
+			if isDeclared && !isDeclaredObject {
+				// The object is replaced by the declared value
+				continue
+			}
+			removeJSONKeys(currentObject, previousObject, declaredObject)
+			if len(currentObject) == 0 {
+				delete(current, k)
+			}
+			continue
+		}
+		if !isDeclared {
+			delete(current, k)
+		}
+	}
+}
+
+// projectedValue returns the parts of the JSON object stored in value that
+// are declared in the configured one, or value itself when it is not a JSON
+// object.
+func projectedValue(value, configured string) string {
+	current, err := jsonObject(value)
+	if err != nil || value == "" {
+		return value
+	}
+	declared, err := jsonObject(configured)
+	if err != nil {
+		return value
+	}
+	projected, _ := json.Marshal(projectJSON(current, declared))
+	return string(projected)
+}
+
+func projectJSON(current, declared map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(declared))
+	for k, v := range declared {
+		currentValue, ok := current[k]
+		if !ok {
+			continue
+		}
+		declaredObject, isDeclaredObject := v.(map[string]interface{})
+		currentObject, isObject := currentValue.(map[string]interface{})
+		if isDeclaredObject && isObject {
+			result[k] = projectJSON(currentObject, declaredObject)
+			continue
+		}
+		result[k] = currentValue
+	}
+	return result
+}
+
+// renewKeySessions renews or creates the sessions of the keys that have a
+// TTL and destroys the sessions that are no longer used. It returns the
+// sessions to use for each path, and the ones that have been newly created.
+func renewKeySessions(ctx context.Context, keyClient *keyClient, pool *sessionPool, d *schema.ResourceData, keys *schema.Set) (map[string]string, map[string]string, error) {
+	ttls := make(map[string]string)
+	for _, raw := range keys.List() {
+		_, path, sub, err := parseKey(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ttl := sub["ttl"].(string); ttl != "" {
+			ttls[path] = ttl
+		}
+	}
+	// The duration has already been validated by the schema
+	lockDelay, _ := time.ParseDuration(d.Get("lock_delay").(string))
+
+	oldSessions := d.Get("sessions").(map[string]interface{})
+	for path, id := range oldSessions {
+		if _, ok := ttls[path]; ok {
+			continue
+		}
+		if err := releaseKeySession(ctx, keyClient, pool, path, id.(string)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The keys with the same TTL share the session of the other keys
+	// written with the same parameters during this run
+	sessions := make(map[string]string)
+	created := make(map[string]string)
+	for path, ttl := range ttls {
+		oldID, _ := oldSessions[path].(string)
+		id, err := pool.acquire(keyClient.sessions, oldID, ttl, consulapi.SessionBehaviorDelete, lockDelay)
+		if err != nil {
+			return nil, nil, err
+		}
+		sessions[path] = id
+		if id == oldID {
+			continue
+		}
+		created[path] = id
+
+		// The key will be acquired by its new session, the previous one must
+		// let it go if it is still used by other keys
+		if oldID != "" {
+			if err := releaseKeySession(ctx, keyClient, pool, path, oldID); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return sessions, created, nil
+}
+
+// releaseKeySession lets the session of the key at path go, and unlocks the
+// key when the session is kept for other keys.
+func releaseKeySession(ctx context.Context, keyClient *keyClient, pool *sessionPool, path, id string) error {
+	destroyed, err := pool.release(ctx, keyClient, id, path)
+	if err != nil || destroyed {
+		return err
+	}
+	_, err = keyClient.ReleaseLock(ctx, path, id)
+	return err
+}
+
+// keySessionsExpiring returns whether the session of one of the keys with a
+// TTL must be renewed: it is missing, or half of its TTL has elapsed since it
+// was last renewed. The sessions that were renewed recently are left as they
+// are so that the plan is empty when nothing else changed.
+func keySessionsExpiring(keys []interface{}, sessions, renewals map[string]interface{}, now time.Time) bool {
+	for _, raw := range keys {
+		sub := raw.(map[string]interface{})
+		if sub["ttl"].(string) == "" {
+			continue
+		}
+		// The paths and the TTLs have already been validated
+		path, _ := keyPath(sub)
+		ttl, _ := time.ParseDuration(sub["ttl"].(string))
+		if _, ok := sessions[path]; !ok {
+			return true
+		}
+		renewal, _ := renewals[path].(string)
+		renewedAt, err := time.Parse(time.RFC3339, renewal)
+		if err != nil || now.Sub(renewedAt) >= ttl/2 {
+			return true
+		}
+	}
+	return false
 }
 
 func resourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
-	keyClient := newKeyClient(d, meta)
+	return readConsulKeys(d, meta, d.Get("allow_stale").(bool))
+}
+
+// readConsulKeys refreshes the keys of the resource, reading them from any
+// server when allowStale is set.
+func readConsulKeys(d *schema.ResourceData, meta interface{}, allowStale bool) error {
+	keyClient := newKeyClient(d, meta, withAllowStale(allowStale))
+	ctx := stopContext(meta)
 
 	vars := make(map[string]string)
+	sessions := d.Get("sessions").(map[string]interface{})
+	sourceHashes := make(map[string]string)
+	contentHashes := make(map[string]string)
 
 	keys := d.Get("key").(*schema.Set).List()
 	for _, raw := range keys {
@@ -208,13 +1196,54 @@ func resourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
 			return err
 		}
 
-		value, flags, err := keyClient.Get(path)
+		entry, _, err := keyClient.Get(ctx, path)
 		if err != nil {
 			return err
 		}
-		sub["flags"] = flags
 
-		value = attributeValue(sub, value)
+		// The deletion of a key takes some time to reach all the servers, a
+		// stale read that contradicts the state is confirmed by the leader so
+		// that the plans do not flap between the two versions.
+		if index, _ := sub["modify_index"].(int); allowStale && d.Get("consistent_delete_check").(bool) && isStaleKeyRead(entry, index) {
+			keyClient.logf("DEBUG", "get", path, "The stale read at index %d contradicts the index %d of the state, reading the key from the leader", entry.modifyIndex, index)
+			entry, _, err = newKeyClient(d, meta, withAllowStale(false), withRequestID(keyClient.requestID)).Get(ctx, path)
+			if err != nil {
+				return err
+			}
+		}
+		sub["modify_index"] = int(entry.modifyIndex)
+
+		// A key with a TTL that is no longer held by its session has expired
+		// and must be written again.
+		if sessionID, ok := sessions[path]; ok && entry.session != sessionID.(string) {
+			delete(sessions, path)
+			entry.value = ""
+		}
+
+		// The session holding the lock of the key is reported so that a lost
+		// lock shows as a drift and the key is acquired again
+		if session, ok := sub["session"].(string); ok && session != "" {
+			sub["session"] = entry.session
+		}
+
+		// The value of a replicated key must be the same in all its
+		// datacenters, we report the first one that diverges as a drift.
+		if name == "" {
+			for _, dc := range keyDatacenters(sub, keyClient) {
+				replica, _, err := newKeyClient(d, meta, withDatacenter(dc), withRequestID(keyClient.requestID)).Get(ctx, path)
+				if err != nil {
+					return err
+				}
+				if replica.value != entry.value || replica.flags != entry.flags {
+					keyClient.logf("WARN", "get", path, "The key differs in datacenter '%s'", dc)
+					entry.value = replica.value
+					entry.flags = replica.flags
+					break
+				}
+			}
+		}
+
+		value := attributeValue(sub, entry.value)
 		if name != "" {
 			// If 'name' is set then we'll update vars, for backward-compatibilty
 			// with the pre-0.7 capability to read from Consul with this
@@ -228,7 +1257,56 @@ func resourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
 			// written by Terraform.
 			// We don't do this for "read" blocks; that causes confusing diffs
 			// because "value" should not be set for read-only key blocks.
-			sub["value"] = value
+			//
+			// Binary values are stored in value_base64 so that they do not
+			// end up as invalid UTF-8 in the state.
+			// JSON values are written in their canonical form, we keep the
+			// configured one as long as they are semantically equal.
+			//
+			// The values read from a file or an environment variable are only
+			// tracked by their checksum to not store their content in the
+			// state.
+			//
+			// Only the sub-keys declared by a key using the deep_merge mode are
+			// compared, those written by others are not a drift.
+			//
+			// The checksum of the stored value would reveal the secrets of
+			// value_env, and is not stable for the encrypted values.
+			encrypted, _ := sub["encrypt"].(bool)
+			valueEnv, _ := sub["value_env"].(string)
+			if entry.modifyIndex != 0 && !encrypted && valueEnv == "" {
+				contentHashes[path] = entry.storedHash
+			}
+
+			merged := sub["merge_mode"].(string) == keyMergeModeDeepMerge
+			if merged {
+				value = projectedValue(value, sub["value"].(string))
+			}
+			if (merged || sub["value_type"].(string) == keyValueTypeJSON) && jsonEqual(value, sub["value"].(string)) {
+				value = sub["value"].(string)
+			}
+
+			if sub["value_source_file"].(string) != "" || sub["value_env"].(string) != "" {
+				sourceHashes[path] = contentHash([]byte(value))
+			} else if sub["value_base64"].(string) != "" || !utf8.ValidString(value) {
+				sub["value"] = ""
+				sub["value_base64"] = base64.StdEncoding.EncodeToString([]byte(value))
+			} else {
+				sub["value"] = value
+			}
+
+			// The flags are part of the key too, they are always reported so
+			// that they can be referenced and ignored with ignore_changes. A
+			// change made outside of Terraform to the flags is only a drift
+			// when they are declared in the configuration, the flags of a key
+			// that does not set them are left as they are.
+			sub["flags"] = entry.flags
+			if compress, ok := sub["compress"].(bool); ok && compress {
+				sub["flags"] = sub["flags"].(int) &^ kvFlagCompressed
+			}
+			if encrypt, ok := sub["encrypt"].(bool); ok && encrypt {
+				sub["flags"] = sub["flags"].(int) &^ kvFlagEncrypted
+			}
 		}
 	}
 
@@ -238,6 +1316,15 @@ func resourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
 	if err := d.Set("key", keys); err != nil {
 		return err
 	}
+	if err := d.Set("sessions", sessions); err != nil {
+		return err
+	}
+	if err := d.Set("value_source_sha256", sourceHashes); err != nil {
+		return err
+	}
+	if err := d.Set("content_sha256", contentHashes); err != nil {
+		return err
+	}
 
 	// Store the datacenter on this resource, which can be helpful for reference
 	// in case it was read from the provider
@@ -246,8 +1333,19 @@ func resourceConsulKeysRead(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
+// isStaleKeyRead returns whether the key read from a follower may be out of
+// date compared to the state, whose last read returned the key at index:
+// either only one of them has the key, or the read returned an older version.
+func isStaleKeyRead(entry keyEntry, index int) bool {
+	if (entry.modifyIndex == 0) != (index == 0) {
+		return true
+	}
+	return entry.modifyIndex < uint64(index)
+}
+
 func resourceConsulKeysDelete(d *schema.ResourceData, meta interface{}) error {
 	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
 
 	// Clean up any keys that we're explicitly managing
 	keys := d.Get("key").(*schema.Set).List()
@@ -263,9 +1361,42 @@ func resourceConsulKeysDelete(d *schema.ResourceData, meta interface{}) error {
 			continue
 		}
 
-		if err := keyClient.Delete(path); err != nil {
+		if merge, ok := sub["merge_mode"].(string); ok && merge == keyMergeModeDeepMerge {
+			if err := mergeKey(ctx, keyClient, unmergeKey(path, sub)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		deleted, err := keyClient.DeleteReport(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			keyClient.logf("WARN", "delete", path, "Key was already removed from Consul")
+		}
+		for _, dc := range keyDatacenters(sub, keyClient) {
+			if err := newKeyClient(d, meta, withDatacenter(dc), withRequestID(keyClient.requestID)).Delete(ctx, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Release the sessions of the keys with a TTL, the keys they hold are
+	// deleted by Consul along with them. A session still used by other keys,
+	// of this resource or of others, is kept and the key is deleted
+	// explicitly instead.
+	pool := &meta.(*Config).sessionPool
+	for path, id := range d.Get("sessions").(map[string]interface{}) {
+		destroyed, err := pool.release(ctx, keyClient, id.(string), path)
+		if err != nil {
 			return err
 		}
+		if !destroyed {
+			if err := keyClient.Delete(ctx, path); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Clear the ID
@@ -282,13 +1413,307 @@ func parseKey(raw interface{}) (string, string, map[string]interface{}, error) {
 
 	key := sub["name"].(string)
 
-	path, ok := sub["path"].(string)
-	if !ok {
+	if _, ok := sub["path"].(string); !ok {
 		return "", "", nil, fmt.Errorf("failed to get path for key '%s'", key)
 	}
+	path, err := keyPath(sub)
+	if err != nil {
+		return "", "", nil, err
+	}
 	return key, path, sub, nil
 }
 
+// keyPath returns the path of the key in Consul, the path given in the
+// configuration is URL-decoded first when path_url_encoded is set.
+func keyPath(sub map[string]interface{}) (string, error) {
+	path := sub["path"].(string)
+	if encoded, ok := sub["path_url_encoded"].(bool); !ok || !encoded {
+		return path, nil
+	}
+	decoded, err := url.PathUnescape(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to URL-decode the path %q: %v", path, err)
+	}
+	return decoded, nil
+}
+
+// keyRenames returns the previous path of the keys whose path is the only
+// attribute that changed, indexed by their new path. Those keys are written
+// under their new path and deleted from the previous one in the same
+// transaction, so that the value is always present under exactly one of them.
+//
+// Only the keys written with a plain check-and-set operation in a single
+// datacenter can be moved this way, the others are written and deleted
+// separately.
+func keyRenames(os, ns *schema.Set) map[string]string {
+	// The paths have already been checked during the plan
+	pathOf := func(sub map[string]interface{}) string {
+		path, _ := keyPath(sub)
+		return path
+	}
+	oldPaths := make(map[string]bool)
+	for _, raw := range os.List() {
+		oldPaths[pathOf(raw.(map[string]interface{}))] = true
+	}
+	newPaths := make(map[string]bool)
+	for _, raw := range ns.List() {
+		newPaths[pathOf(raw.(map[string]interface{}))] = true
+	}
+
+	renamable := func(sub map[string]interface{}) bool {
+		return sub["name"].(string) == "" && sub["ttl"].(string) == "" &&
+			sub["session"].(string) == "" && sub["merge_mode"].(string) != keyMergeModeDeepMerge &&
+			len(sub["datacenters"].([]interface{})) == 0 &&
+			sub["update_mode"].(string) == keyUpdateModeCAS
+	}
+	withoutPath := func(sub map[string]interface{}) map[string]interface{} {
+		result := make(map[string]interface{}, len(sub))
+		for k, v := range sub {
+			if k != "path" && k != "path_url_encoded" && k != "modify_index" {
+				result[k] = v
+			}
+		}
+		return result
+	}
+
+	renames := make(map[string]string)
+	used := make(map[string]bool)
+	for _, rawNew := range ns.Difference(os).List() {
+		newSub := rawNew.(map[string]interface{})
+		newPath := pathOf(newSub)
+		if oldPaths[newPath] || !renamable(newSub) {
+			continue
+		}
+		for _, rawOld := range os.Difference(ns).List() {
+			oldSub := rawOld.(map[string]interface{})
+			oldPath := pathOf(oldSub)
+			if newPaths[oldPath] || used[oldPath] || !oldSub["delete"].(bool) || !renamable(oldSub) {
+				continue
+			}
+			if reflect.DeepEqual(withoutPath(oldSub), withoutPath(newSub)) {
+				renames[newPath] = oldPath
+				used[oldPath] = true
+				break
+			}
+		}
+	}
+	return renames
+}
+
+// keyValue returns the value to write for a key, decoding value_base64 or
+// reading value_source_file or the environment variable of value_env when
+// they are used.
+func keyValue(sub map[string]interface{}) (string, error) {
+	if file := sub["value_source_file"].(string); file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read value_source_file of key '%s': %v", sub["path"].(string), err)
+		}
+		return string(content), nil
+	}
+	if name := sub["value_env"].(string); name != "" {
+		value := os.Getenv(name)
+		if value == "" && !sub["allow_empty"].(bool) {
+			return "", fmt.Errorf("the environment variable %s used as the value of key '%s' is not set or empty", name, sub["path"].(string))
+		}
+		return value, nil
+	}
+	if encoded := sub["value_base64"].(string); encoded != "" {
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode value_base64 of key '%s': %v", sub["path"].(string), err)
+		}
+		return string(decoded), nil
+	}
+	return sub["value"].(string), nil
+}
+
+// keySourceHashes returns the checksum of the value written for each key
+// using value_source_file or value_env, indexed by path.
+func keySourceHashes(keys []interface{}) (map[string]interface{}, error) {
+	hashes := make(map[string]interface{})
+	for _, raw := range keys {
+		sub := raw.(map[string]interface{})
+		if sub["value_source_file"].(string) == "" && sub["value_env"].(string) == "" {
+			continue
+		}
+
+		value, err := keyValue(sub)
+		if err != nil {
+			return nil, err
+		}
+		if sub["value_type"].(string) == keyValueTypeJSON {
+			value, err = canonicalJSON(value)
+			if err != nil {
+				return nil, fmt.Errorf("the value of Consul key '%s' is not valid JSON: %v", sub["path"].(string), err)
+			}
+		}
+		path, err := keyPath(sub)
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = contentHash([]byte(value))
+	}
+	return hashes, nil
+}
+
+// checkAllowedFlags returns an error when a key declares flags that are not
+// part of its allowed_flags. The flags left to 0 are the flags of a key that
+// does not set them, they are never checked.
+func checkAllowedFlags(sub map[string]interface{}) error {
+	allowed := sub["allowed_flags"].([]interface{})
+	flags := sub["flags"].(int)
+	if len(allowed) == 0 || flags == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(allowed))
+	for _, raw := range allowed {
+		if raw.(int) == flags {
+			return nil
+		}
+		values = append(values, strconv.Itoa(raw.(int)))
+	}
+	return fmt.Errorf("the flags of key %q must be one of %s, got %d", sub["path"].(string), strings.Join(values, ", "), flags)
+}
+
+// keyEncodingFlags returns the flags marking how the value of a key is
+// encoded when it is stored in Consul.
+func keyEncodingFlags(sub map[string]interface{}) int {
+	var flags int
+	if compress, ok := sub["compress"].(bool); ok && compress {
+		flags |= kvFlagCompressed
+	}
+	if encrypt, ok := sub["encrypt"].(bool); ok && encrypt {
+		flags |= kvFlagEncrypted
+	}
+	return flags
+}
+
+// keyReplica is a key to copy to other datacenters.
+type keyReplica struct {
+	path        string
+	value       string
+	flags       int
+	datacenters []string
+}
+
+// keyDatacenters returns the datacenters a key is replicated to, besides the
+// one of the resource.
+func keyDatacenters(sub map[string]interface{}, keyClient *keyClient) []string {
+	raw, ok := sub["datacenters"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	seen := map[string]bool{keyClient.wOpts.Datacenter: true}
+	datacenters := make([]string, 0, len(raw))
+	for _, dc := range raw {
+		if dc, ok := dc.(string); ok && !seen[dc] {
+			seen[dc] = true
+			datacenters = append(datacenters, dc)
+		}
+	}
+	return datacenters
+}
+
+// stringsDifference returns the elements of a that are not in b.
+func stringsDifference(a, b []string) []string {
+	res := make([]string, 0, len(a))
+	for _, s := range a {
+		found := false
+		for _, t := range b {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// replicateKey writes the key in each of its datacenters. If a write fails
+// the previous values are restored in the datacenters already written and the
+// error lists the datacenters where the key has been written.
+func replicateKey(d *schema.ResourceData, meta interface{}, primary *keyClient, replica keyReplica) error {
+	type written struct {
+		client   *keyClient
+		dc       string
+		previous keyEntry
+	}
+	var done []written
+
+	ctx := stopContext(meta)
+	var err error
+	for _, dc := range replica.datacenters {
+		client := newKeyClient(d, meta, withDatacenter(dc), withRequestID(primary.requestID))
+
+		var previous keyEntry
+		previous, _, err = client.Get(ctx, replica.path)
+		if err == nil {
+			err = client.Put(ctx, replica.path, replica.value, replica.flags)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to replicate key '%s' to datacenter '%s': %v", replica.path, dc, err)
+			break
+		}
+		done = append(done, written{client: client, dc: dc, previous: previous})
+	}
+	if err == nil {
+		return nil
+	}
+
+	succeeded := []string{primary.wOpts.Datacenter}
+	var rollbackErrors []string
+	for _, w := range done {
+		var rollbackErr error
+		if w.previous.modifyIndex == 0 {
+			rollbackErr = w.client.Delete(ctx, replica.path)
+		} else {
+			rollbackErr = w.client.Put(ctx, replica.path, w.previous.value, w.previous.flags)
+		}
+		if rollbackErr != nil {
+			succeeded = append(succeeded, w.dc)
+			rollbackErrors = append(rollbackErrors, fmt.Sprintf("%s: %v", w.dc, rollbackErr))
+		}
+	}
+
+	err = fmt.Errorf("%v, the key is written in: %s", err, strings.Join(succeeded, ", "))
+	if len(rollbackErrors) > 0 {
+		err = fmt.Errorf("%v (failed to roll back %s)", err, strings.Join(rollbackErrors, "; "))
+	}
+	return err
+}
+
+// canonicalJSON returns the compact form of a JSON document with the keys of
+// its objects sorted.
+func canonicalJSON(value string) (string, error) {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(value), &decoded); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return "", err
+	}
+	return string(canonical), nil
+}
+
+// jsonEqual returns whether both values are the same JSON document.
+func jsonEqual(a, b string) bool {
+	ca, err := canonicalJSON(a)
+	if err != nil {
+		return false
+	}
+	cb, err := canonicalJSON(b)
+	if err != nil {
+		return false
+	}
+	return ca == cb
+}
+
 // attributeValue determines the value for a key, potentially
 // using a default value if provided.
 func attributeValue(sub map[string]interface{}, readValue string) string {
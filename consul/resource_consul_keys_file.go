@@ -0,0 +1,251 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func resourceConsulKeysFile() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulKeysFileCreateUpdate,
+		Update: resourceConsulKeysFileCreateUpdate,
+		Read:   resourceConsulKeysFileRead,
+		Delete: resourceConsulKeysFileDelete,
+
+		CustomizeDiff: resourceConsulKeysFileCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"source_dir": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The local directory to mirror in the key/value store.",
+			},
+
+			"path_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The prefix under which the files are written, the path of each file relative to `source_dir` is appended to it.",
+			},
+
+			"files": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The SHA-256 checksum of the content of each file, indexed by its path relative to `source_dir`.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+// resourceConsulKeysFileCustomizeDiff computes the checksum of the files in
+// source_dir so that the plan only shows the files whose content changed.
+func resourceConsulKeysFileCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source_dir") {
+		return d.SetNewComputed("files")
+	}
+
+	files, err := readSourceDir(d.Get("source_dir").(string))
+	if err != nil {
+		return err
+	}
+
+	hashes := make(map[string]interface{}, len(files))
+	for name, content := range files {
+		hashes[name] = contentHash(content)
+	}
+
+	if reflect.DeepEqual(hashes, d.Get("files").(map[string]interface{})) {
+		return nil
+	}
+	return d.SetNew("files", hashes)
+}
+
+func resourceConsulKeysFileCreateUpdate(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+
+	sourceDir := d.Get("source_dir").(string)
+	pathPrefix := d.Get("path_prefix").(string)
+
+	files, err := readSourceDir(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	o, _ := d.GetChange("files")
+	oldHashes, _ := o.(map[string]interface{})
+
+	// Only the files whose content changed are written
+	var pairs []consulapi.KVPair
+	hashes := make(map[string]string, len(files))
+	for name, content := range files {
+		hash := contentHash(content)
+		hashes[name] = hash
+		if old, ok := oldHashes[name]; ok && old.(string) == hash {
+			continue
+		}
+		pairs = append(pairs, consulapi.KVPair{
+			Key:   pathPrefix + name,
+			Value: content,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	var removed []string
+	for name := range oldHashes {
+		if _, ok := files[name]; !ok {
+			removed = append(removed, pathPrefix+name)
+		}
+	}
+	sort.Strings(removed)
+
+	// We record that the resource was created before writing anything so
+	// that a partial write can be recovered by an Update.
+	if d.Id() == "" {
+		d.SetId(keyPrefixID(keyClient.qOpts.Partition, keyClient.qOpts.Namespace, pathPrefix))
+	}
+
+	if err := keyClient.PutBatch(ctx, pairs); err != nil {
+		return err
+	}
+	if err := keyClient.DeleteBatch(ctx, removed); err != nil {
+		return err
+	}
+
+	sw := newStateWriter(d)
+	sw.set("files", hashes)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	return resourceConsulKeysFileRead(d, meta)
+}
+
+func resourceConsulKeysFileRead(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+
+	pathPrefix := d.Get("path_prefix").(string)
+
+	pairs, _, err := keyClient.GetUnderPrefix(ctx, pathPrefix)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string][]byte, len(pairs))
+	for _, pair := range pairs {
+		current[pair.Key[len(pathPrefix):]] = pair.Value
+	}
+
+	// Only the keys written by the resource are tracked, a key modified or
+	// deleted outside of Terraform will be written again on the next apply.
+	hashes := make(map[string]string)
+	for name := range d.Get("files").(map[string]interface{}) {
+		if value, ok := current[name]; ok {
+			hashes[name] = contentHash(value)
+		}
+	}
+
+	sw := newStateWriter(d)
+	sw.set("files", hashes)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+
+	return sw.error()
+}
+
+func resourceConsulKeysFileDelete(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+
+	pathPrefix := d.Get("path_prefix").(string)
+
+	var paths []string
+	for name := range d.Get("files").(map[string]interface{}) {
+		paths = append(paths, pathPrefix+name)
+	}
+	sort.Strings(paths)
+
+	if err := keyClient.DeleteBatch(ctx, paths); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// readSourceDir returns the content of all the regular files found under
+// dir, indexed by their slash-separated path relative to dir.
+func readSourceDir(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the files in %q: %v", dir, err)
+	}
+	return files, nil
+}
+
+// contentHash returns the hex-encoded SHA-256 checksum of content.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
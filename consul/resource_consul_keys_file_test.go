@@ -0,0 +1,141 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulKeysFile_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "app.json", `{"port": 8080}`)
+	writeTestFile(t, dir, "db/host", "localhost")
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccCheckConsulKeysFileKeyValue(client, "app.json", ""),
+			testAccCheckConsulKeysFileKeyValue(client, "db/host", ""),
+			testAccCheckConsulKeysFileKeyValue(client, "db/port", ""),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysFileConfig(dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys_file.config", "files.%", "2"),
+					resource.TestCheckResourceAttr("consul_keys_file.config", "files.db/host", contentHash([]byte("localhost"))),
+					testAccCheckConsulKeysFileKeyValue(client, "app.json", `{"port": 8080}`),
+					testAccCheckConsulKeysFileKeyValue(client, "db/host", "localhost"),
+				),
+			},
+			{
+				PreConfig: func() {
+					writeTestFile(t, dir, "db/host", "db.example.com")
+					writeTestFile(t, dir, "db/port", "5432")
+					if err := os.Remove(filepath.Join(dir, "app.json")); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: testAccConsulKeysFileConfig(dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys_file.config", "files.%", "2"),
+					testAccCheckConsulKeysFileKeyValue(client, "app.json", ""),
+					testAccCheckConsulKeysFileKeyValue(client, "db/host", "db.example.com"),
+					testAccCheckConsulKeysFileKeyValue(client, "db/port", "5432"),
+				),
+			},
+			{
+				// A key modified outside of Terraform is written again
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "config/db/port", Value: []byte("1234")}, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: testAccConsulKeysFileConfig(dir),
+				Check:  testAccCheckConsulKeysFileKeyValue(client, "db/port", "5432"),
+			},
+		},
+	})
+}
+
+func TestReadSourceDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a", "1")
+	writeTestFile(t, dir, "b/c/d", "2")
+	if err := os.MkdirAll(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := readSourceDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]byte{
+		"a":     []byte("1"),
+		"b/c/d": []byte("2"),
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("unexpected files, got %v, expected %v", files, expected)
+	}
+
+	if _, err := readSourceDir(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testAccCheckConsulKeysFileKeyValue checks the value of a key written by
+// consul_keys_file, an empty value means the key must not exist.
+func testAccCheckConsulKeysFileKeyValue(client *consulapi.Client, name, value string) resource.TestCheckFunc {
+	fullName := "config/" + name
+	return func(s *terraform.State) error {
+		pair, _, err := client.KV().Get(fullName, nil)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			if pair != nil {
+				return fmt.Errorf("key %v exists, but shouldn't", fullName)
+			}
+			return nil
+		}
+		if pair == nil {
+			return fmt.Errorf("key %v doesn't exist, but should", fullName)
+		}
+		if string(pair.Value) != value {
+			return fmt.Errorf("key %v has value %q; want %q", fullName, pair.Value, value)
+		}
+		return nil
+	}
+}
+
+func testAccConsulKeysFileConfig(dir string) string {
+	return fmt.Sprintf(`
+resource "consul_keys_file" "config" {
+  source_dir  = %q
+  path_prefix = "config/"
+}
+`, dir)
+}
@@ -0,0 +1,255 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// resourceConsulKeysImport writes the keys of a file in the format of the
+// consul kv export command, like consul kv import does.
+func resourceConsulKeysImport() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulKeysImportCreateUpdate,
+		Update: resourceConsulKeysImportCreateUpdate,
+		Read:   resourceConsulKeysImportRead,
+		Delete: resourceConsulKeysImportDelete,
+
+		CustomizeDiff: resourceConsulKeysImportCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"source_file": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The path of a file written by `consul kv export`.",
+			},
+
+			"keys": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "The SHA-256 checksum of the flags and the value of each key of the file, indexed by its path.",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+// kvExportEntry is an entry of the files written by consul kv export.
+type kvExportEntry struct {
+	Key   string `json:"key"`
+	Flags uint64 `json:"flags"`
+	Value string `json:"value"`
+}
+
+// resourceConsulKeysImportCustomizeDiff computes the checksum of the keys of
+// source_file so that the plan only shows the keys that changed.
+func resourceConsulKeysImportCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source_file") {
+		return d.SetNewComputed("keys")
+	}
+
+	pairs, err := readExportFile(d.Get("source_file").(string))
+	if err != nil {
+		return err
+	}
+
+	hashes := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		hashes[pair.Key] = exportEntryHash(pair.Flags, pair.Value)
+	}
+
+	if reflect.DeepEqual(hashes, d.Get("keys").(map[string]interface{})) {
+		return nil
+	}
+	return d.SetNew("keys", hashes)
+}
+
+func resourceConsulKeysImportCreateUpdate(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta, withRawValues())
+	ctx := stopContext(meta)
+
+	pairs, err := readExportFile(d.Get("source_file").(string))
+	if err != nil {
+		return err
+	}
+
+	o, _ := d.GetChange("keys")
+	oldHashes, _ := o.(map[string]interface{})
+
+	// Only the keys whose flags or value changed are written
+	var changed []consulapi.KVPair
+	hashes := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		hash := exportEntryHash(pair.Flags, pair.Value)
+		hashes[pair.Key] = hash
+		if old, ok := oldHashes[pair.Key]; ok && old.(string) == hash {
+			continue
+		}
+		changed = append(changed, pair)
+	}
+
+	// The keys that were imported but are no longer in the file are removed
+	var removed []string
+	for path := range oldHashes {
+		if _, ok := hashes[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+
+	// We record that the resource was created before writing anything so
+	// that a partial write can be recovered by an Update.
+	if d.Id() == "" {
+		d.SetId(resource.UniqueId())
+	}
+
+	if err := keyClient.PutBatch(ctx, changed); err != nil {
+		return err
+	}
+	if err := keyClient.DeleteBatch(ctx, removed); err != nil {
+		return err
+	}
+
+	sw := newStateWriter(d)
+	sw.set("keys", hashes)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+	if err := sw.error(); err != nil {
+		return err
+	}
+
+	return resourceConsulKeysImportRead(d, meta)
+}
+
+func resourceConsulKeysImportRead(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta, withRawValues())
+	ctx := stopContext(meta)
+
+	tracked := d.Get("keys").(map[string]interface{})
+	paths := make([]string, 0, len(tracked))
+	for path := range tracked {
+		paths = append(paths, path)
+	}
+
+	// The keys are listed under their longest common prefix rather than read
+	// one by one
+	current := make(map[string]string, len(paths))
+	if len(paths) > 0 {
+		err := keyClient.ForEachUnderPrefix(ctx, commonPrefix(paths), func(pair consulapi.KVPair) error {
+			if _, ok := tracked[pair.Key]; ok {
+				current[pair.Key] = exportEntryHash(pair.Flags, pair.Value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only the keys written by the resource are tracked, a key modified or
+	// deleted outside of Terraform will be written again on the next apply.
+	sw := newStateWriter(d)
+	sw.set("keys", current)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+
+	return sw.error()
+}
+
+func resourceConsulKeysImportDelete(d *schema.ResourceData, meta interface{}) error {
+	keyClient := newKeyClient(d, meta)
+	ctx := stopContext(meta)
+
+	var paths []string
+	for path := range d.Get("keys").(map[string]interface{}) {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	if err := keyClient.DeleteBatch(ctx, paths); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// readExportFile returns the keys of a file written by consul kv export with
+// their decoded values, sorted by path.
+func readExportFile(path string) ([]consulapi.KVPair, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %v", path, err)
+	}
+
+	var entries []kvExportEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf(`failed to parse %q, expected the format of consul kv export: a JSON array of {"key", "flags", "value"} objects with base64 encoded values: %v`, path, err)
+	}
+
+	pairs := make([]consulapi.KVPair, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if entry.Key == "" {
+			return nil, fmt.Errorf("failed to parse %q: entry %d has no key", path, i)
+		}
+		if seen[entry.Key] {
+			return nil, fmt.Errorf("failed to parse %q: key %q is present more than once", path, entry.Key)
+		}
+		seen[entry.Key] = true
+
+		value, err := base64.StdEncoding.DecodeString(entry.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: the value of key %q is not base64 encoded: %v", path, entry.Key, err)
+		}
+		pairs = append(pairs, consulapi.KVPair{
+			Key:   entry.Key,
+			Flags: entry.Flags,
+			Value: value,
+		})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs, nil
+}
+
+// exportEntryHash returns the checksum of a key of an export file, the flags
+// are part of it so that a change of the flags alone is written too.
+func exportEntryHash(flags uint64, value []byte) string {
+	return contentHash(append([]byte(strconv.FormatUint(flags, 10)+":"), value...))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulKeysImport_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.json")
+	// "d2Vi" is "web", "ODA4MA==" is "8080" and "NTQzMg==" is "5432"
+	writeTestFile(t, dir, "export.json", `[
+  {"key": "import/app/name", "flags": 0, "value": "d2Vi"},
+  {"key": "import/app/port", "flags": 42, "value": "ODA4MA=="}
+]`)
+
+	checkKey := func(key, value string, flags uint64) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get(key, nil)
+			if err != nil {
+				return err
+			}
+			if value == "" {
+				if pair != nil {
+					return fmt.Errorf("key %v exists, but shouldn't", key)
+				}
+				return nil
+			}
+			if pair == nil || string(pair.Value) != value || pair.Flags != flags {
+				return fmt.Errorf("unexpected key %v: %#v", key, pair)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			checkKey("import/app/name", "", 0),
+			checkKey("import/app/port", "", 0),
+			checkKey("import/db/port", "", 0),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysImportConfig(path),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys_import.app", "keys.%", "2"),
+					checkKey("import/app/name", "web", 0),
+					checkKey("import/app/port", "8080", 42),
+				),
+			},
+			{
+				// The keys removed from the file are deleted
+				PreConfig: func() {
+					writeTestFile(t, dir, "export.json", `[
+  {"key": "import/app/name", "flags": 0, "value": "d2Vi"},
+  {"key": "import/db/port", "flags": 0, "value": "NTQzMg=="}
+]`)
+				},
+				Config: testAccConsulKeysImportConfig(path),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys_import.app", "keys.%", "2"),
+					checkKey("import/app/port", "", 0),
+					checkKey("import/db/port", "5432", 0),
+				),
+			},
+			{
+				// A key modified outside of Terraform is written again
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "import/db/port", Value: []byte("1234")}, nil)
+					if err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: testAccConsulKeysImportConfig(path),
+				Check:  checkKey("import/db/port", "5432", 0),
+			},
+			{
+				PreConfig: func() {
+					writeTestFile(t, dir, "export.json", `{"key": "import/app/name"}`)
+				},
+				Config:      testAccConsulKeysImportConfig(path),
+				ExpectError: regexp.MustCompile("expected the format of consul kv export"),
+			},
+		},
+	})
+}
+
+func TestReadExportFile(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := map[string]string{
+		"": `[
+  {"key": "b", "flags": 3, "value": "d2Vi"},
+  {"key": "a", "flags": 0, "value": ""}
+]`,
+		"expected the format of consul kv export": `{"key": "a"}`,
+		"entry 1 has no key":                      `[{"key": "a"}, {"value": "d2Vi"}]`,
+		`key "a" is present more than once`:       `[{"key": "a"}, {"key": "a"}]`,
+		`the value of key "a" is not base64`:      `[{"key": "a", "value": "web!"}]`,
+	}
+	for expected, content := range cases {
+		writeTestFile(t, dir, "export.json", content)
+		pairs, err := readExportFile(filepath.Join(dir, "export.json"))
+		if expected != "" {
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Fatalf("expected an error containing %q, got %v", expected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(pairs) != 2 || pairs[0].Key != "a" || pairs[1].Key != "b" || pairs[1].Flags != 3 || string(pairs[1].Value) != "web" {
+			t.Fatalf("unexpected pairs: %#v", pairs)
+		}
+	}
+
+	if exportEntryHash(1, []byte("web")) == exportEntryHash(2, []byte("web")) {
+		t.Fatal("the flags must be part of the checksum")
+	}
+}
+
+func testAccConsulKeysImportConfig(path string) string {
+	return fmt.Sprintf(`
+resource "consul_keys_import" "app" {
+  source_file = %q
+}
+`, path)
+}
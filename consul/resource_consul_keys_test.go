@@ -4,8 +4,16 @@
 package consul
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
@@ -26,7 +34,8 @@ func TestAccConsulKeys_basic(t *testing.T) {
 					testAccCheckConsulKeysValue("consul_keys.app", "enabled", "true"),
 					testAccCheckConsulKeysValue("consul_keys.app", "set", "acceptance"),
 					testAccCheckConsulKeysValue("consul_keys.app", "remove_one", "hello"),
-					resource.TestCheckResourceAttr("consul_keys.app", "key.4258512057.flags", "0"),
+					resource.TestCheckResourceAttr("consul_keys.app", "key.2701276727.flags", "0"),
+					resource.TestCheckResourceAttrSet("consul_keys.app", "key.2701276727.modify_index"),
 				),
 			},
 			{
@@ -56,6 +65,1196 @@ func TestAccConsulKeys_EmptyValue(t *testing.T) {
 	})
 }
 
+func TestAccConsulKeys_TTL(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysTTL,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.ttl", "sessions.%", "1"),
+					resource.TestCheckResourceAttr("consul_keys.ttl", "session_renewals.%", "1"),
+					testAccCheckConsulKeysSession(client, "test/ttl", "consul_keys.ttl"),
+				),
+			},
+			{
+				// Destroying the session removes the key, as if its TTL
+				// expired, and Terraform must write it again.
+				PreConfig: func() {
+					pair, _, err := client.KV().Get("test/ttl", nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					if pair == nil {
+						t.Fatal("key 'test/ttl' does not exist")
+					}
+					if _, err := client.Session().Destroy(pair.Session, nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config: testAccConsulKeysTTL,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.ttl", "sessions.%", "1"),
+					testAccCheckConsulKeysSession(client, "test/ttl", "consul_keys.ttl"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_SharedSession(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysSharedSession,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysSession(client, "test/shared/a", "consul_keys.a"),
+					testAccCheckConsulKeysSession(client, "test/shared/b", "consul_keys.b"),
+					testAccCheckConsulKeysSession(client, "test/shared/c", "consul_keys.c"),
+					resource.TestCheckResourceAttrPair("consul_keys.a", "sessions.test/shared/a", "consul_keys.b", "sessions.test/shared/b"),
+					func(s *terraform.State) error {
+						attrs := s.RootModule().Resources["consul_keys.c"].Primary.Attributes
+						if attrs["sessions.test/shared/c"] == s.RootModule().Resources["consul_keys.a"].Primary.Attributes["sessions.test/shared/a"] {
+							return fmt.Errorf("the keys with a different lock_delay should not share the session")
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Removing one of the resources must keep the session of the
+				// other one
+				Config: testAccConsulKeysSharedSessionRemoved,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysSession(client, "test/shared/a", "consul_keys.a"),
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/shared/b", nil)
+						if err != nil {
+							return err
+						}
+						if pair != nil {
+							return fmt.Errorf("key 'test/shared/b' should have been deleted")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestKeySessionsExpiring(t *testing.T) {
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	keys := []interface{}{
+		map[string]interface{}{"path": "app/static", "ttl": ""},
+		map[string]interface{}{"path": "app/ttl", "ttl": "60s"},
+	}
+	sessions := map[string]interface{}{"app/ttl": "6c6e9e3c-2e43-4b40-8fca-4a4a8b5e8e8f"}
+
+	cases := map[string]struct {
+		sessions map[string]interface{}
+		renewal  string
+		expected bool
+	}{
+		"recently renewed": {sessions, "2023-07-01T11:59:40Z", false},
+		"half ttl elapsed": {sessions, "2023-07-01T11:59:30Z", true},
+		"never renewed":    {sessions, "", true},
+		"missing session":  {map[string]interface{}{}, "2023-07-01T11:59:40Z", true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			renewals := map[string]interface{}{}
+			if c.renewal != "" {
+				renewals["app/ttl"] = c.renewal
+			}
+			if expiring := keySessionsExpiring(keys, c.sessions, renewals, now); expiring != c.expected {
+				t.Fatalf("expected %v, got %v", c.expected, expiring)
+			}
+		})
+	}
+}
+
+func TestAccConsulKeys_ValueSchema(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysValueSchema(`{"port": "8500"}`),
+				ExpectError: regexp.MustCompile(`(?s)failed to validate the value of Consul key 'test/service'.*/port: expected integer, but got string`),
+			},
+			{
+				Config: testAccConsulKeysValueSchema(`{"port": 8500}`),
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/service", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil || string(pair.Value) != `{"port": 8500}` {
+						return fmt.Errorf("unexpected value for 'test/service': %v", pair)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ValueBase64(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysValueBase64Conflict,
+				ExpectError: regexp.MustCompile(`only one of value and value_base64 can be set for key "test/binary"`),
+			},
+			{
+				Config: testAccConsulKeysValueBase64,
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/binary", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil {
+						return fmt.Errorf("Key 'test/binary' does not exist")
+					}
+					if !bytes.Equal(pair.Value, []byte{0x30, 0x82, 0xff, 0x00, 0xfe}) {
+						return fmt.Errorf("wrong value %v", pair.Value)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_Compress(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysCompress,
+				Check: resource.ComposeTestCheckFunc(
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/compressed", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil {
+							return fmt.Errorf("Key 'test/compressed' does not exist")
+						}
+						if pair.Flags != 3 {
+							return fmt.Errorf("wrong flags %d", pair.Flags)
+						}
+						if !bytes.HasPrefix(pair.Value, []byte{0x1f, 0x8b}) {
+							return fmt.Errorf("value is not compressed: %q", pair.Value)
+						}
+						return nil
+					},
+					testAccCheckConsulKeysValue("data.consul_keys.read", "compressed", strings.Repeat("consul", 100)),
+				),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_Encrypt(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccConsulKeysEncrypt, testAccConsulKeysEncryptionKey),
+				Check: resource.ComposeTestCheckFunc(
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/encrypted", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil {
+							return fmt.Errorf("Key 'test/encrypted' does not exist")
+						}
+						if pair.Flags != kvFlagEncrypted|2 {
+							return fmt.Errorf("wrong flags %d", pair.Flags)
+						}
+						if bytes.Contains(pair.Value, []byte("secret")) {
+							return fmt.Errorf("value is not encrypted: %q", pair.Value)
+						}
+						return nil
+					},
+					resource.TestCheckResourceAttr("consul_keys.encrypted", "key.#", "1"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "encrypted", "secret"),
+				),
+			},
+			{
+				// The values cannot be read with another key
+				Config:      fmt.Sprintf(testAccConsulKeysEncrypt, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x43}, 32))),
+				ExpectError: regexp.MustCompile("it was not encrypted with the current encryption_key, the key may have been rotated"),
+			},
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysEncrypt, "dG9vIHNob3J0"),
+				ExpectError: regexp.MustCompile("encryption_key must be 32 bytes long, got 9 bytes"),
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysEncrypt, testAccConsulKeysEncryptionKey),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_Session(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysSession,
+				Check: func(s *terraform.State) error {
+					session := s.RootModule().Resources["consul_session.leader"].Primary.ID
+					pair, _, err := client.KV().Get("test/leader", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil || pair.Session != session || string(pair.Value) != "node-1" {
+						return fmt.Errorf("the key is not locked by session %q: %#v", session, pair)
+					}
+					return nil
+				},
+			},
+			{
+				// The lost lock is detected and acquired again
+				PreConfig: func() {
+					pair, _, err := client.KV().Get("test/leader", nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					if _, _, err := client.KV().Release(pair, nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:             testAccConsulKeysSession,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulKeysSession,
+			},
+			{
+				Config:   testAccConsulKeysSession,
+				PlanOnly: true,
+			},
+			{
+				// The lock is held by another session
+				PreConfig: func() {
+					id, _, err := client.Session().Create(&consulapi.SessionEntry{Name: "other"}, nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					if _, _, err := client.KV().Acquire(&consulapi.KVPair{Key: "test/follower", Session: id}, nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:      testAccConsulKeysSessionConflict,
+				ExpectError: regexp.MustCompile("failed to write Consul key 'test/follower': its lock is held by session"),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_DefaultFlags(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	flagsOf := func(path string, expected uint64) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get(path, nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil {
+				return fmt.Errorf("key %q not found", path)
+			}
+			if pair.Flags != expected {
+				return fmt.Errorf("the flags of %q are %d, expected %d", path, pair.Flags, expected)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				// The key predates the setting
+				Config: fmt.Sprintf(testAccConsulKeysDefaultFlags, 0, "v1"),
+				Check:  flagsOf("test/default", 0),
+			},
+			{
+				Config:   fmt.Sprintf(testAccConsulKeysDefaultFlags, 256, "v1"),
+				PlanOnly: true,
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysDefaultFlags, 256, "v2"),
+				Check: resource.ComposeTestCheckFunc(
+					flagsOf("test/default", 256),
+					flagsOf("test/explicit", 3),
+				),
+			},
+			{
+				Config:   fmt.Sprintf(testAccConsulKeysDefaultFlags, 256, "v2"),
+				PlanOnly: true,
+			},
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysDefaultFlags, 536870912, "v2"),
+				ExpectError: regexp.MustCompile("default_kv_flags cannot use the bits 536870912 and 1073741824"),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_CASRetry(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccConsulKeysCASRetry, "1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.counter", "cas_attempts.%", "1"),
+					resource.TestCheckResourceAttr("consul_keys.counter", "cas_attempts.test/counter", "1"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "counter", "1"),
+				),
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysCASRetry, "2"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.counter", "cas_attempts.test/counter", "1"),
+					testAccCheckConsulKeysValue("data.consul_keys.read", "counter", "2"),
+				),
+			},
+			{
+				Config: `
+resource "consul_keys" "counter" {
+  key {
+    path        = "test/counter"
+    value       = "1"
+    ttl         = "30s"
+    update_mode = "cas_retry"
+  }
+}`,
+				ExpectError: regexp.MustCompile(`update_mode "cas_retry" cannot be used with ttl for key "test/counter"`),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_Precondition(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccConsulKeysPrecondition, "test/gate", "on", "1"),
+				Check:  testAccCheckConsulKeysValue("data.consul_keys.read", "gated", "1"),
+			},
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysPrecondition, "test/gate", "off", "2"),
+				ExpectError: regexp.MustCompile(`precondition of Consul key 'test/gated' failed: the check key 'test/gate' is "on", expected "off"`),
+			},
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysPrecondition, "test/missing", "on", "2"),
+				ExpectError: regexp.MustCompile(`precondition of Consul key 'test/gated' failed: the check key 'test/missing' does not exist`),
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysPrecondition, "test/gate", "on", "2"),
+				Check:  testAccCheckConsulKeysValue("data.consul_keys.read", "gated", "2"),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_CreateOnly(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "test/singleton", Value: []byte("existing")}, nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:      fmt.Sprintf(testAccConsulKeysCreateOnly, "created"),
+				ExpectError: regexp.MustCompile(`create_only is set and some keys already exist`),
+			},
+			{
+				PreConfig: func() {
+					pair, _, err := client.KV().Get("test/singleton", nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					if pair == nil || string(pair.Value) != "existing" {
+						t.Fatalf("the existing key should not have been overwritten: %#v", pair)
+					}
+					if _, err := client.KV().Delete("test/singleton", nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config: fmt.Sprintf(testAccConsulKeysCreateOnly, "created"),
+				Check:  resource.TestCheckResourceAttr("consul_keys.app", "create_only", "true"),
+			},
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysCreateOnly, "updated"),
+				ExpectError: regexp.MustCompile(`create_only is set, the keys cannot be updated once they have been created`),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ValueEnv(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	t.Setenv("TF_ACC_CONSUL_KEYS_SECRET", "s3cr3t")
+	t.Setenv("TF_ACC_CONSUL_KEYS_EMPTY", "")
+
+	checkValue := func(path, expected string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get(path, nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil || string(pair.Value) != expected {
+				return fmt.Errorf("unexpected key %#v", pair)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_SECRET", `value = "foo"`),
+				ExpectError: regexp.MustCompile(`value_env cannot be used with value, value_base64 or value_source_file for key "test/env"`),
+			},
+			{
+				Config:      testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_SECRET", `name = "secret"`),
+				ExpectError: regexp.MustCompile(`value_env cannot be used with name for key "test/env"`),
+			},
+			{
+				Config:      testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_EMPTY", ""),
+				ExpectError: regexp.MustCompile(`the environment variable TF_ACC_CONSUL_KEYS_EMPTY used as the value of key 'test/env' is not set or empty`),
+			},
+			{
+				Config: testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_EMPTY", "allow_empty = true"),
+				Check:  checkValue("test/env", ""),
+			},
+			{
+				Config: testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_SECRET", ""),
+				Check: resource.ComposeTestCheckFunc(
+					checkValue("test/env", "s3cr3t"),
+					resource.TestCheckResourceAttr("consul_keys.env", "value_source_sha256.test/env", contentHash([]byte("s3cr3t"))),
+				),
+			},
+			{
+				// The key is written again when the variable changes
+				PreConfig: func() {
+					os.Setenv("TF_ACC_CONSUL_KEYS_SECRET", "rotated")
+				},
+				Config: testAccConsulKeysValueEnv("TF_ACC_CONSUL_KEYS_SECRET", ""),
+				Check:  checkValue("test/env", "rotated"),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ValueSourceFile(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	file := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(file, []byte("port = 8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checkValue := func(expected string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get("test/file", nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil || string(pair.Value) != expected {
+				return fmt.Errorf("unexpected key %#v", pair)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysValueSourceFile(file, `value = "foo"`),
+				ExpectError: regexp.MustCompile(`value_source_file cannot be used with value or value_base64 for key "test/file"`),
+			},
+			{
+				Config:      testAccConsulKeysValueSourceFile(file+".missing", ""),
+				ExpectError: regexp.MustCompile(`failed to read value_source_file of key 'test/file': open .*app.conf.missing: no such file or directory`),
+			},
+			{
+				Config: testAccConsulKeysValueSourceFile(file, ""),
+				Check: resource.ComposeTestCheckFunc(
+					checkValue("port = 8080\n"),
+					resource.TestCheckResourceAttr("consul_keys.file", "value_source_sha256.test/file", contentHash([]byte("port = 8080\n"))),
+				),
+			},
+			{
+				// A change of the file is shown in the plan
+				PreConfig: func() {
+					if err := os.WriteFile(file, []byte("port = 9090\n"), 0600); err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config:             testAccConsulKeysValueSourceFile(file, ""),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulKeysValueSourceFile(file, ""),
+				Check:  checkValue("port = 9090\n"),
+			},
+			{
+				// And so is a change made outside of Terraform
+				PreConfig: func() {
+					if _, err := client.KV().Put(&consulapi.KVPair{Key: "test/file", Value: []byte("drift")}, nil); err != nil {
+						t.Fatalf("failed to update key: %v", err)
+					}
+				},
+				Config:             testAccConsulKeysValueSourceFile(file, ""),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_FlagsDrift(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysConfig_Update,
+			},
+			{
+				// Only the flags of the key are changed outside of Terraform
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{
+						Key:   "test/set",
+						Value: []byte("acceptanceUpdated"),
+						Flags: 42,
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to update key: %v", err)
+					}
+				},
+				Config:             testAccConsulKeysConfig_Update,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulKeysConfig_Update,
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/set", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil {
+						return fmt.Errorf("Key 'test/set' does not exist")
+					}
+					if pair.Flags != 64 {
+						return fmt.Errorf("wrong flags %d", pair.Flags)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_AllowedFlags(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysAllowedFlags(5),
+				ExpectError: regexp.MustCompile(`the flags of key "test/flagged" must be one of 2, 4, got 5`),
+			},
+			{
+				Config: testAccConsulKeysAllowedFlags(4),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysValue("consul_keys.app", "flagged", "v1"),
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/flagged", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil {
+							return fmt.Errorf("Key 'test/flagged' does not exist")
+						}
+						if pair.Flags != 4 {
+							return fmt.Errorf("wrong flags %d", pair.Flags)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// The flags left unset are not checked
+				Config: testAccConsulKeysAllowedFlags(0),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ExpectedModifyIndex(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	_, err := client.KV().Put(&consulapi.KVPair{Key: "test/pinned", Value: []byte("old")}, nil)
+	if err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	pair, _, err := client.KV().Get("test/pinned", nil)
+	if err != nil {
+		t.Fatalf("failed to read key: %v", err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysExpectedModifyIndex("new", pair.ModifyIndex+1),
+				ExpectError: regexp.MustCompile(fmt.Sprintf("the modify index of Consul key 'test/pinned' is %d but expected_modify_index is %d", pair.ModifyIndex, pair.ModifyIndex+1)),
+			},
+			{
+				Config: testAccConsulKeysExpectedModifyIndex("new", pair.ModifyIndex),
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/pinned", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil || string(pair.Value) != "new" {
+						return fmt.Errorf("wrong value for 'test/pinned': %v", pair)
+					}
+					return nil
+				},
+			},
+			{
+				// The key has been written since the index was pinned
+				Config:      testAccConsulKeysExpectedModifyIndex("newer", pair.ModifyIndex),
+				ExpectError: regexp.MustCompile("expected_modify_index is"),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_Rename(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysRename("test/rename/old"),
+			},
+			{
+				Config: testAccConsulKeysRename("test/rename/new"),
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/rename/old", nil)
+					if err != nil {
+						return err
+					}
+					if pair != nil {
+						return fmt.Errorf("key 'test/rename/old' still exists")
+					}
+
+					pair, _, err = client.KV().Get("test/rename/new", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil || string(pair.Value) != "moved" || pair.Flags != 7 {
+						return fmt.Errorf("unexpected key 'test/rename/new': %#v", pair)
+					}
+
+					// The index of the prefix accounts for the deletion of the
+					// old key, it is the same as the creation of the new one
+					// only if both were made in the same transaction.
+					_, meta, err := client.KV().List("test/rename/", nil)
+					if err != nil {
+						return err
+					}
+					if meta.LastIndex != pair.CreateIndex {
+						return fmt.Errorf("the key was not renamed atomically: created at index %d, prefix at index %d", pair.CreateIndex, meta.LastIndex)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_PreserveFlags(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	checkFlags := func(value string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get("test/preserve", nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil {
+				return fmt.Errorf("Key 'test/preserve' does not exist")
+			}
+			if string(pair.Value) != value {
+				return fmt.Errorf("wrong value %q", pair.Value)
+			}
+			if pair.Flags != 12 {
+				return fmt.Errorf("wrong flags %d", pair.Flags)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				// The flags of the key are set outside of Terraform
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{
+						Key:   "test/preserve",
+						Value: []byte("old"),
+						Flags: 12,
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config: testAccConsulKeysPreserveFlags("first"),
+				Check:  checkFlags("first"),
+			},
+			{
+				Config: testAccConsulKeysPreserveFlags("second"),
+				Check: resource.ComposeTestCheckFunc(
+					checkFlags("second"),
+					// The flags stored in Consul are reported in the state
+					func(s *terraform.State) error {
+						for k, v := range s.RootModule().Resources["consul_keys.preserve"].Primary.Attributes {
+							if strings.HasSuffix(k, ".flags") && v != "12" {
+								return fmt.Errorf("unexpected flags %s = %s", k, v)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// The flags changed outside of Terraform are not a drift when
+				// they are not declared
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{
+						Key:   "test/preserve",
+						Value: []byte("second"),
+						Flags: 20,
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config:   testAccConsulKeysPreserveFlags("second"),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ContentSHA256(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	var index uint64
+	checkIndex := func(modified bool) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get("test/content", nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil {
+				return fmt.Errorf("Key 'test/content' does not exist")
+			}
+			if modified == (pair.ModifyIndex == index) {
+				return fmt.Errorf("unexpected modify index %d, the previous one was %d", pair.ModifyIndex, index)
+			}
+			index = pair.ModifyIndex
+			return nil
+		}
+	}
+
+	checkStoredHash := func(s *terraform.State) error {
+		pair, _, err := client.KV().Get("test/content", nil)
+		if err != nil {
+			return err
+		}
+		if pair == nil {
+			return fmt.Errorf("Key 'test/content' does not exist")
+		}
+		return resource.TestCheckResourceAttr("consul_keys.content", "content_sha256.test/content", contentHash(pair.Value))(s)
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysContentSHA256("first", ""),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.content", "content_sha256.test/content", contentHash([]byte("first"))),
+					checkIndex(true),
+				),
+			},
+			{
+				// The block changed but not the value, the key is not written
+				Config: testAccConsulKeysContentSHA256("first", "allow_empty = true"),
+				Check:  checkIndex(false),
+			},
+			{
+				Config: testAccConsulKeysContentSHA256("second", "allow_empty = true"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.content", "content_sha256.test/content", contentHash([]byte("second"))),
+					checkIndex(true),
+				),
+			},
+			{
+				// A value changed outside of Terraform is written again
+				PreConfig: func() {
+					if _, err := client.KV().Put(&consulapi.KVPair{Key: "test/content", Value: []byte("changed")}, nil); err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config: testAccConsulKeysContentSHA256("second", "allow_empty = true"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_keys.content", "content_sha256.test/content", contentHash([]byte("second"))),
+					checkIndex(true),
+				),
+			},
+			{
+				// The checksum is the one of the compressed value
+				Config: testAccConsulKeysContentSHA256("second", "compress = true"),
+				Check: resource.ComposeTestCheckFunc(
+					checkStoredHash,
+					checkIndex(true),
+				),
+			},
+			{
+				Config: testAccConsulKeysContentSHA256("second", "compress = true\n\t\tallow_empty = true"),
+				Check: resource.ComposeTestCheckFunc(
+					checkStoredHash,
+					checkIndex(false),
+				),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_ValueTypeJSON(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysValueTypeJSON(`{"port": 8500`),
+				ExpectError: regexp.MustCompile(`the value of key "test/json" is not valid JSON`),
+			},
+			{
+				Config: testAccConsulKeysValueTypeJSON("{\n  \"port\": 8500,\n  \"name\": \"consul\"\n}"),
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/json", nil)
+					if err != nil {
+						return err
+					}
+					if pair == nil || string(pair.Value) != `{"name":"consul","port":8500}` {
+						return fmt.Errorf("unexpected value for 'test/json': %v", pair)
+					}
+					return nil
+				},
+			},
+			{
+				// A change of the formatting alone is not a drift
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{
+						Key:   "test/json",
+						Value: []byte(`{ "port" : 8500, "name" : "consul" }`),
+					}, nil)
+					if err != nil {
+						t.Fatalf("failed to update key: %v", err)
+					}
+				},
+				Config:   testAccConsulKeysValueTypeJSON("{\n  \"port\": 8500,\n  \"name\": \"consul\"\n}"),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	canonical, err := canonicalJSON("{\n  \"b\": [1, 2],\n  \"a\": {\"d\": null, \"c\": true}\n}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if canonical != `{"a":{"c":true,"d":null},"b":[1,2]}` {
+		t.Fatalf("unexpected value %q", canonical)
+	}
+
+	if _, err := canonicalJSON("{"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if !jsonEqual(`{"a": 1, "b": 2}`, `{"b":2,"a":1}`) || jsonEqual(`{"a": 1}`, `{"a": "1"}`) {
+		t.Fatal("unexpected comparison")
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	current, err := jsonObject(`{"owner": "ops", "app": {"port": 80, "debug": true, "extra": 1}, "old": 1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous, _ := jsonObject(`{"app": {"port": 80, "debug": true}, "old": 1}`)
+	declared, _ := jsonObject(`{"app": {"port": 8080}}`)
+
+	removeJSONKeys(current, previous, declared)
+	mergeJSON(current, declared)
+	merged, _ := json.Marshal(current)
+	if string(merged) != `{"app":{"extra":1,"port":8080},"owner":"ops"}` {
+		t.Fatalf("unexpected value %s", merged)
+	}
+
+	if projected := projectedValue(string(merged), `{"app": {"port": 8080}}`); projected != `{"app":{"port":8080}}` {
+		t.Fatalf("unexpected projection %s", projected)
+	}
+	if projected := projectedValue("[1]", `{"app": 1}`); projected != "[1]" {
+		t.Fatalf("unexpected projection %s", projected)
+	}
+
+	if _, err := jsonObject("[1, 2]"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestIsStaleKeyRead(t *testing.T) {
+	cases := []struct {
+		modifyIndex uint64
+		index       int
+		expected    bool
+	}{
+		{modifyIndex: 10, index: 10, expected: false},
+		{modifyIndex: 12, index: 10, expected: false},
+		{modifyIndex: 0, index: 0, expected: false},
+		{modifyIndex: 8, index: 10, expected: true},
+		{modifyIndex: 10, index: 0, expected: true},
+		{modifyIndex: 0, index: 10, expected: true},
+	}
+	for _, c := range cases {
+		if stale := isStaleKeyRead(keyEntry{modifyIndex: c.modifyIndex}, c.index); stale != c.expected {
+			t.Fatalf("isStaleKeyRead(%d, %d) = %t, expected %t", c.modifyIndex, c.index, stale, c.expected)
+		}
+	}
+}
+
+func TestKeyPath(t *testing.T) {
+	cases := []struct {
+		path     string
+		encoded  interface{}
+		expected string
+		err      bool
+	}{
+		{path: "app/my%20key", encoded: nil, expected: "app/my%20key"},
+		{path: "app/my%20key", encoded: false, expected: "app/my%20key"},
+		{path: "app/my%20key", encoded: true, expected: "app/my key"},
+		{path: "team%2Fa/config%3Fv2", encoded: true, expected: "team/a/config?v2"},
+		{path: "app/100%", encoded: true, err: true},
+	}
+	for _, c := range cases {
+		sub := map[string]interface{}{"path": c.path}
+		if c.encoded != nil {
+			sub["path_url_encoded"] = c.encoded
+		}
+		path, err := keyPath(sub)
+		if (err != nil) != c.err {
+			t.Fatalf("keyPath(%q, %v) returned unexpected error: %v", c.path, c.encoded, err)
+		}
+		if path != c.expected {
+			t.Fatalf("keyPath(%q, %v) = %q, expected %q", c.path, c.encoded, path, c.expected)
+		}
+	}
+}
+
+func TestCheckAllowedFlags(t *testing.T) {
+	cases := []struct {
+		flags   int
+		allowed []interface{}
+		err     bool
+	}{
+		{flags: 5, allowed: []interface{}{}},
+		{flags: 0, allowed: []interface{}{2, 4}},
+		{flags: 4, allowed: []interface{}{2, 4}},
+		{flags: 5, allowed: []interface{}{2, 4}, err: true},
+	}
+	for _, c := range cases {
+		sub := map[string]interface{}{
+			"path":          "app/flagged",
+			"flags":         c.flags,
+			"allowed_flags": c.allowed,
+		}
+		if err := checkAllowedFlags(sub); (err != nil) != c.err {
+			t.Fatalf("checkAllowedFlags(%d, %v) returned unexpected error: %v", c.flags, c.allowed, err)
+		}
+	}
+}
+
+func TestAccConsulKeys_AllowStale(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulKeysDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulKeysAllowStale,
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckConsulKeysExists(client),
+					resource.TestCheckResourceAttr("consul_keys.app", "allow_stale", "true"),
+					resource.TestCheckResourceAttr("consul_keys.app", "consistent_delete_check", "true"),
+				),
+			},
+			{
+				// A key deleted outside of Terraform is reported as missing
+				PreConfig: func() {
+					if _, err := client.KV().Delete("test/set", nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:             testAccConsulKeysAllowStale,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulKeysAllowStale,
+				Check:  testAccCheckConsulKeysExists(client),
+			},
+		},
+	})
+}
+
+func TestAccConsulKeys_MergeMode(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	checkValue := func(expected string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get("test/merged", nil)
+			if err != nil {
+				return err
+			}
+			if pair == nil || !jsonEqual(string(pair.Value), expected) {
+				return fmt.Errorf("unexpected value for 'test/merged': %#v", pair)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		PreCheck: func() {
+			_, err := client.KV().Put(&consulapi.KVPair{Key: "test/merged", Value: []byte(`{"owner": "ops", "app": {"debug": true}}`)}, nil)
+			if err != nil {
+				t.Fatalf("failed to write key: %v", err)
+			}
+		},
+		CheckDestroy: checkValue(`{"owner": "ops", "app": {"debug": true}}`),
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(testAccConsulKeysMergeMode, `[\"a\"]`),
+				ExpectError: regexp.MustCompile(`merge_mode "deep_merge" requires the value of key "test/merged" to be a JSON object`),
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysMergeMode, `{\"app\": {\"port\": 80}, \"region\": \"eu\"}`),
+				Check:  checkValue(`{"owner": "ops", "app": {"debug": true, "port": 80}, "region": "eu"}`),
+			},
+			{
+				// The sub-keys that are no longer declared are removed
+				Config: fmt.Sprintf(testAccConsulKeysMergeMode, `{\"app\": {\"port\": 8080}}`),
+				Check:  checkValue(`{"owner": "ops", "app": {"debug": true, "port": 8080}}`),
+			},
+			{
+				// Only a change of the declared sub-keys is a drift
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "test/merged", Value: []byte(`{"owner": "dev", "app": {"debug": true, "port": 8080}}`)}, nil)
+					if err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config:   fmt.Sprintf(testAccConsulKeysMergeMode, `{\"app\": {\"port\": 8080}}`),
+				PlanOnly: true,
+			},
+			{
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "test/merged", Value: []byte(`{"owner": "ops", "app": {"debug": true, "port": 81}}`)}, nil)
+					if err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config:             fmt.Sprintf(testAccConsulKeysMergeMode, `{\"app\": {\"port\": 8080}}`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: fmt.Sprintf(testAccConsulKeysMergeMode, `{\"app\": {\"port\": 8080}}`),
+				Check:  checkValue(`{"owner": "ops", "app": {"debug": true, "port": 8080}}`),
+			},
+		},
+	})
+}
+
 func TestAccConsulKeys_NamespaceCE(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -99,6 +1298,66 @@ func TestAccConsulKeys_Datacenter(t *testing.T) {
 	})
 }
 
+func TestAccConsulKeys_Replicated(t *testing.T) {
+	providers, client := startRemoteDatacenterTestServer(t)
+
+	checkValue := func(dc, expected string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			pair, _, err := client.KV().Get("foo/replicated", &consulapi.QueryOptions{Datacenter: dc})
+			if err != nil {
+				return err
+			}
+			if expected == "" {
+				if pair != nil {
+					return fmt.Errorf("key 'foo/replicated' should not exist in %s", dc)
+				}
+				return nil
+			}
+			if pair == nil || string(pair.Value) != expected {
+				return fmt.Errorf("wrong value in %s: %v", dc, pair)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			checkValue("dc1", ""),
+			checkValue("dc2", ""),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKeysReplicatedTTL,
+				ExpectError: regexp.MustCompile(`datacenters cannot be used with ttl for key "foo/replicated"`),
+			},
+			{
+				Config: testAccConsulKeysReplicated,
+				Check: resource.ComposeTestCheckFunc(
+					checkValue("dc1", "replicated"),
+					checkValue("dc2", "replicated"),
+				),
+			},
+			{
+				// A change in one of the datacenters is a drift
+				PreConfig: func() {
+					_, err := client.KV().Put(&consulapi.KVPair{Key: "foo/replicated", Value: []byte("changed")}, &consulapi.WriteOptions{Datacenter: "dc2"})
+					if err != nil {
+						t.Fatalf("failed to write key: %v", err)
+					}
+				},
+				Config:             testAccConsulKeysReplicated,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulKeysReplicated,
+				Check:  checkValue("dc2", "replicated"),
+			},
+		},
+	})
+}
+
 func testAccCheckConsulKeysDestroy(client *consulapi.Client) func(s *terraform.State) error {
 	return func(s *terraform.State) error {
 		kv := client.KV()
@@ -129,6 +1388,28 @@ func testAccCheckConsulKeysExists(client *consulapi.Client) resource.TestCheckFu
 	}
 }
 
+func testAccCheckConsulKeysSession(client *consulapi.Client, path, name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rn, ok := s.RootModule().Resources[name]
+		if !ok {
+			return fmt.Errorf("Resource not found")
+		}
+		sessionID := rn.Primary.Attributes["sessions."+path]
+
+		pair, _, err := client.KV().Get(path, nil)
+		if err != nil {
+			return err
+		}
+		if pair == nil {
+			return fmt.Errorf("Key '%s' does not exist", path)
+		}
+		if pair.Session != sessionID {
+			return fmt.Errorf("Key '%s' is locked by %q, expected %q", path, pair.Session, sessionID)
+		}
+		return nil
+	}
+}
+
 func testAccCheckConsulKeysValue(n, attr, val string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rn, ok := s.RootModule().Resources[n]
@@ -191,6 +1472,30 @@ func testAccCheckConsulKeysDatacenter(client *consulapi.Client) func(s *terrafor
 	}
 }
 
+const testAccConsulKeysReplicated = `
+resource "consul_keys" "replicated" {
+	datacenter = "dc1"
+
+	key {
+		path        = "foo/replicated"
+		value       = "replicated"
+		datacenters = ["dc1", "dc2"]
+		delete      = true
+	}
+}
+`
+
+const testAccConsulKeysReplicatedTTL = `
+resource "consul_keys" "replicated" {
+	key {
+		path        = "foo/replicated"
+		value       = "replicated"
+		datacenters = ["dc2"]
+		ttl         = "30s"
+	}
+}
+`
+
 const testAccConsulKeysConfig = `
 resource "consul_keys" "app" {
 	datacenter = "dc1"
@@ -232,6 +1537,67 @@ resource "consul_keys" "app" {
 }
 `
 
+func testAccConsulKeysExpectedModifyIndex(value string, index uint64) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "pinned" {
+	key {
+		path                  = "test/pinned"
+		value                 = "%s"
+		expected_modify_index = %d
+		delete                = true
+	}
+}`, value, index)
+}
+
+func testAccConsulKeysAllowedFlags(flags int) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "app" {
+	key {
+		name          = "flagged"
+		path          = "test/flagged"
+		value         = "v1"
+		flags         = %d
+		allowed_flags = [2, 4]
+		delete        = true
+	}
+}`, flags)
+}
+
+func testAccConsulKeysRename(path string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "rename" {
+	key {
+		path   = "%s"
+		value  = "moved"
+		flags  = 7
+		delete = true
+	}
+}`, path)
+}
+
+func testAccConsulKeysPreserveFlags(value string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "preserve" {
+	key {
+		path   = "test/preserve"
+		value  = "%s"
+		delete = true
+	}
+}`, value)
+}
+
+func testAccConsulKeysContentSHA256(value, extra string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "content" {
+	key {
+		path   = "test/content"
+		value  = "%s"
+		delete = true
+		%s
+	}
+}`, value, extra)
+}
+
 const testAccConsulKeysEmptyValue = `
 resource "consul_keys" "consul" {
 	key {
@@ -241,6 +1607,296 @@ resource "consul_keys" "consul" {
 	}
 }`
 
+const testAccConsulKeysTTL = `
+resource "consul_keys" "ttl" {
+  key {
+    path  = "test/ttl"
+    value = "ephemeral"
+    ttl   = "30s"
+  }
+}`
+
+const testAccConsulKeysSharedSession = `
+resource "consul_keys" "a" {
+  key {
+    path  = "test/shared/a"
+    value = "a"
+    ttl   = "30s"
+  }
+}
+
+resource "consul_keys" "b" {
+  key {
+    path  = "test/shared/b"
+    value = "b"
+    ttl   = "30s"
+  }
+}
+
+resource "consul_keys" "c" {
+  lock_delay = "1s"
+
+  key {
+    path  = "test/shared/c"
+    value = "c"
+    ttl   = "30s"
+  }
+}
+`
+
+const testAccConsulKeysSharedSessionRemoved = `
+resource "consul_keys" "a" {
+  key {
+    path  = "test/shared/a"
+    value = "a"
+    ttl   = "30s"
+  }
+}
+`
+
+func testAccConsulKeysValueSchema(value string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "service" {
+  key {
+    path  = "test/service"
+    value = %q
+
+    value_schema = jsonencode({
+      type     = "object"
+      required = ["port"]
+      properties = {
+        port = { type = "integer" }
+      }
+    })
+  }
+}`, value)
+}
+
+func testAccConsulKeysValueTypeJSON(value string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "json" {
+  key {
+    path       = "test/json"
+    value      = %q
+    value_type = "json"
+  }
+}`, value)
+}
+
+func testAccConsulKeysValueEnv(name, extra string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "env" {
+  key {
+    path      = "test/env"
+    value_env = %q
+    delete    = true
+    %s
+  }
+}`, name, extra)
+}
+
+func testAccConsulKeysValueSourceFile(file, extra string) string {
+	return fmt.Sprintf(`
+resource "consul_keys" "file" {
+  key {
+    path              = "test/file"
+    value_source_file = %q
+    delete            = true
+    %s
+  }
+}`, file, extra)
+}
+
+const testAccConsulKeysValueBase64 = `
+resource "consul_keys" "binary" {
+  key {
+    path         = "test/binary"
+    value_base64 = "MIL/AP4="
+  }
+}`
+
+const testAccConsulKeysValueBase64Conflict = `
+resource "consul_keys" "binary" {
+  key {
+    path         = "test/binary"
+    value        = "hello"
+    value_base64 = "MIL/AP4="
+  }
+}`
+
+const testAccConsulKeysCompress = `
+resource "consul_keys" "compressed" {
+  key {
+    path     = "test/compressed"
+    value    = join("", [for i in range(100) : "consul"])
+    flags    = 2
+    compress = true
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.compressed.datacenter
+
+  key {
+    path = "test/compressed"
+    name = "compressed"
+  }
+}`
+
+var testAccConsulKeysEncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x42}, 32))
+
+const testAccConsulKeysEncrypt = `
+provider "consul" {
+  encryption_key = "%s"
+}
+
+resource "consul_keys" "encrypted" {
+  key {
+    path    = "test/encrypted"
+    value   = "secret"
+    flags   = 2
+    encrypt = true
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.encrypted.datacenter
+
+  key {
+    path = "test/encrypted"
+    name = "encrypted"
+  }
+}`
+
+const testAccConsulKeysSession = `
+resource "consul_session" "leader" {
+  name = "leader"
+  ttl  = "60s"
+}
+
+resource "consul_keys" "leader" {
+  key {
+    path    = "test/leader"
+    value   = "node-1"
+    delete  = true
+    session = consul_session.leader.id
+  }
+}`
+
+const testAccConsulKeysSessionConflict = testAccConsulKeysSession + `
+
+resource "consul_keys" "follower" {
+  key {
+    path    = "test/follower"
+    value   = "node-1"
+    session = consul_session.leader.id
+  }
+}`
+
+const testAccConsulKeysAllowStale = `
+resource "consul_keys" "app" {
+  allow_stale = true
+
+  key {
+    path   = "test/set"
+    value  = "acceptance"
+    delete = true
+  }
+}`
+
+const testAccConsulKeysMergeMode = `
+resource "consul_keys" "app" {
+  key {
+    path       = "test/merged"
+    value      = "%s"
+    merge_mode = "deep_merge"
+    delete     = true
+  }
+}`
+
+const testAccConsulKeysDefaultFlags = `
+provider "consul" {
+  default_kv_flags = %d
+}
+
+resource "consul_keys" "app" {
+  key {
+    path  = "test/default"
+    value = "%s"
+  }
+
+  key {
+    path  = "test/explicit"
+    value = "explicit"
+    flags = 3
+  }
+}`
+
+const testAccConsulKeysCASRetry = `
+resource "consul_keys" "counter" {
+  key {
+    path            = "test/counter"
+    value           = "%s"
+    delete          = true
+    update_mode     = "cas_retry"
+    max_cas_retries = 10
+  }
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.counter.datacenter
+
+  key {
+    path = "test/counter"
+    name = "counter"
+  }
+}`
+
+const testAccConsulKeysCreateOnly = `
+resource "consul_keys" "app" {
+  create_only = true
+
+  key {
+    path   = "test/singleton"
+    value  = "%s"
+    delete = true
+  }
+}
+`
+
+const testAccConsulKeysPrecondition = `
+resource "consul_keys" "gate" {
+  key {
+    path   = "test/gate"
+    value  = "on"
+    delete = true
+  }
+}
+
+resource "consul_keys" "gated" {
+  key {
+    path   = "test/gated"
+    value  = "%[3]s"
+    delete = true
+
+    precondition {
+      check_key   = "%[1]s"
+      check_value = "%[2]s"
+    }
+  }
+
+  depends_on = [consul_keys.gate]
+}
+
+data "consul_keys" "read" {
+  datacenter = consul_keys.gated.datacenter
+
+  key {
+    path = "test/gated"
+    name = "gated"
+  }
+}`
+
 const testAccConsulKeysNamespaceCE = `
 resource "consul_keys" "consul" {
   namespace = "test-keys"
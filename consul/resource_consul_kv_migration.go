@@ -0,0 +1,186 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func resourceConsulKVMigration() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulKVMigrationCreate,
+		Read:   resourceConsulKVMigrationRead,
+		Delete: resourceConsulKVMigrationDelete,
+
+		CustomizeDiff: resourceConsulKVMigrationCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"source_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The prefix of the keys to copy.",
+			},
+
+			"dest_prefix": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The prefix under which the keys are copied, it replaces `source_prefix` in their path.",
+			},
+
+			"delete_source": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				ForceNew:    true,
+				Description: "Whether the source keys are deleted once they have been copied and verified.",
+			},
+
+			"migrated_keys": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The number of keys copied under `dest_prefix`.",
+			},
+
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func resourceConsulKVMigrationCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source_prefix") || !d.NewValueKnown("dest_prefix") {
+		return nil
+	}
+
+	source := d.Get("source_prefix").(string)
+	dest := d.Get("dest_prefix").(string)
+	if strings.HasPrefix(source, dest) || strings.HasPrefix(dest, source) {
+		return fmt.Errorf("source_prefix %q and dest_prefix %q must not overlap", source, dest)
+	}
+	return nil
+}
+
+func resourceConsulKVMigrationCreate(d *schema.ResourceData, meta interface{}) error {
+	// The keys are copied as they are stored in Consul so that their flags
+	// are kept as is, default_kv_flags must not be applied to them
+	keyClient := newKeyClient(d, meta, withRawValues())
+	ctx := stopContext(meta)
+
+	source := d.Get("source_prefix").(string)
+	dest := d.Get("dest_prefix").(string)
+
+	pairs, _, err := keyClient.GetUnderPrefix(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	// The source may already have been moved by a previous run, there is
+	// nothing left to do then
+	if len(pairs) == 0 {
+		keyClient.logf("INFO", "migrate", source, "No keys to migrate to '%s'", dest)
+	}
+
+	copied := make([]consulapi.KVPair, 0, len(pairs))
+	sourcePaths := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		copied = append(copied, consulapi.KVPair{
+			Key:   dest + pair.Key[len(source):],
+			Value: pair.Value,
+			Flags: pair.Flags,
+		})
+		sourcePaths = append(sourcePaths, pair.Key)
+	}
+
+	if err := keyClient.PutBatch(ctx, copied); err != nil {
+		return fmt.Errorf("failed to migrate the keys under '%s': %v", source, err)
+	}
+
+	// The copy is checked before anything is deleted so that a partial copy
+	// never loses data
+	if err := verifyKVMigration(ctx, keyClient, dest, copied); err != nil {
+		return err
+	}
+
+	if d.Get("delete_source").(bool) {
+		log.Printf("[DEBUG] Deleting the %d keys migrated from '%s'", len(sourcePaths), source)
+		if err := keyClient.DeleteBatch(ctx, sourcePaths); err != nil {
+			return fmt.Errorf("failed to delete the keys migrated from '%s': %v", source, err)
+		}
+	}
+
+	d.SetId(resource.UniqueId())
+
+	sw := newStateWriter(d)
+	sw.set("migrated_keys", len(copied))
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", keyClient.qOpts.Datacenter)
+
+	return sw.error()
+}
+
+// verifyKVMigration checks that all the copied keys can be read back under
+// dest with the expected values and flags.
+func verifyKVMigration(ctx context.Context, keyClient *keyClient, dest string, copied []consulapi.KVPair) error {
+	pairs, _, err := keyClient.GetUnderPrefix(ctx, dest)
+	if err != nil {
+		return err
+	}
+
+	found := make(map[string]*consulapi.KVPair, len(pairs))
+	for _, pair := range pairs {
+		found[pair.Key] = pair
+	}
+
+	count := 0
+	for _, pair := range copied {
+		p, ok := found[pair.Key]
+		if ok && string(p.Value) == string(pair.Value) && p.Flags == pair.Flags {
+			count++
+		}
+	}
+	if count != len(copied) {
+		return fmt.Errorf("failed to verify the migration: %d keys were copied under '%s' but only %d match", len(copied), dest, count)
+	}
+	return nil
+}
+
+// resourceConsulKVMigrationRead does nothing, the migration is only done once
+// and the keys it wrote are not managed by this resource.
+func resourceConsulKVMigrationRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+// resourceConsulKVMigrationDelete only removes the resource from the state,
+// the keys are not moved back.
+func resourceConsulKVMigrationDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulKVMigration_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	kv := client.KV()
+	for _, pair := range []*consulapi.KVPair{
+		{Key: "migration/old/name", Value: []byte("app")},
+		{Key: "migration/old/db/host", Value: []byte("localhost"), Flags: 42},
+	} {
+		if _, err := kv.Put(pair, nil); err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulKVMigrationConfig("migration/old/", "migration/old/new/"),
+				ExpectError: regexp.MustCompile(`source_prefix "migration/old/" and dest_prefix "migration/old/new/" must not overlap`),
+			},
+			{
+				Config: testAccConsulKVMigrationConfig("migration/old/", "migration/new/"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_kv_migration.test", "migrated_keys", "2"),
+					resource.TestCheckResourceAttr("consul_kv_migration.test", "datacenter", "dc1"),
+					func(s *terraform.State) error {
+						pair, _, err := kv.Get("migration/new/db/host", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil || string(pair.Value) != "localhost" || pair.Flags != 42 {
+							return fmt.Errorf("unexpected key %#v", pair)
+						}
+
+						pairs, _, err := kv.List("migration/old/", nil)
+						if err != nil {
+							return err
+						}
+						if len(pairs) != 0 {
+							return fmt.Errorf("the source keys should have been deleted: %#v", pairs)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Running the migration again once the source is gone is a no-op
+				Taint:  []string{"consul_kv_migration.test"},
+				Config: testAccConsulKVMigrationConfig("migration/old/", "migration/new/"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_kv_migration.test", "migrated_keys", "0"),
+					func(s *terraform.State) error {
+						pairs, _, err := kv.List("migration/new/", nil)
+						if err != nil {
+							return err
+						}
+						if len(pairs) != 2 {
+							return fmt.Errorf("unexpected keys %#v", pairs)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccConsulKVMigration_defaultFlags(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	kv := client.KV()
+	for _, pair := range []*consulapi.KVPair{
+		{Key: "migration/flags/old/none", Value: []byte("none")},
+		{Key: "migration/flags/old/set", Value: []byte("set"), Flags: 42},
+	} {
+		if _, err := kv.Put(pair, nil); err != nil {
+			t.Fatalf("failed to write key: %v", err)
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				// default_kv_flags must not be applied to the copied keys
+				Config: `
+provider "consul" {
+  default_kv_flags = 256
+}
+` + testAccConsulKVMigrationConfig("migration/flags/old/", "migration/flags/new/"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_kv_migration.test", "migrated_keys", "2"),
+					func(s *terraform.State) error {
+						for path, flags := range map[string]uint64{
+							"migration/flags/new/none": 0,
+							"migration/flags/new/set":  42,
+						} {
+							pair, _, err := kv.Get(path, nil)
+							if err != nil {
+								return err
+							}
+							if pair == nil || pair.Flags != flags {
+								return fmt.Errorf("unexpected key %#v", pair)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func testAccConsulKVMigrationConfig(source, dest string) string {
+	return fmt.Sprintf(`
+resource "consul_kv_migration" "test" {
+  source_prefix = %q
+  dest_prefix   = %q
+  delete_source = true
+}
+`, source, dest)
+}
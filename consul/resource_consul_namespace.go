@@ -5,8 +5,10 @@ package consul
 
 import (
 	"fmt"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
@@ -20,6 +22,10 @@ func resourceConsulNamespace() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"name": {
 				Type:     schema.TypeString,
@@ -74,15 +80,17 @@ func resourceConsulNamespaceRead(d *schema.ResourceData, meta interface{}) error
 	name := d.Id()
 
 	namespace, _, err := client.Namespaces().Read(name, qOpts)
-	if namespace == nil {
-		d.SetId("")
-		return nil
-	}
-
 	if err != nil {
 		return fmt.Errorf("failed to read namespace '%s': %v", name, err)
 	}
 
+	// Consul keeps the namespaces marked for deletion until all the resources
+	// they contain have been removed
+	if namespace == nil || namespace.DeletedAt != nil {
+		d.SetId("")
+		return nil
+	}
+
 	sw := newStateWriter(d)
 	sw.set("name", namespace.Name)
 	sw.set("description", namespace.Description)
@@ -99,7 +107,7 @@ func resourceConsulNamespaceRead(d *schema.ResourceData, meta interface{}) error
 		policyDefaults = append(policyDefaults, p.Name)
 	}
 	sw.set("policy_defaults", policyDefaults)
-	sw.set("partition", namespace.Partition)
+	sw.set("partition", statePartition(d, meta, namespace.Partition))
 
 	return sw.error()
 }
@@ -124,6 +132,28 @@ func resourceConsulNamespaceDelete(d *schema.ResourceData, meta interface{}) err
 		return fmt.Errorf("failed to delete namespace '%s': %v", d.Id(), err)
 	}
 
+	// The namespace is only soft-deleted and lingers until Consul has removed
+	// everything it contains, we wait for it to be gone so that a namespace
+	// with the same name can be created right away.
+	qOpts := &consulapi.QueryOptions{
+		Datacenter: wOpts.Datacenter,
+		Partition:  wOpts.Partition,
+		Token:      wOpts.Token,
+	}
+	err = resource.Retry(d.Timeout(schema.TimeoutDelete), func() *resource.RetryError {
+		namespace, _, err := client.Namespaces().Read(d.Id(), qOpts)
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		if namespace != nil {
+			return resource.RetryableError(fmt.Errorf("namespace '%s' is still being deleted", d.Id()))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for namespace '%s' to be deleted: %v", d.Id(), err)
+	}
+
 	d.SetId("")
 	return nil
 }
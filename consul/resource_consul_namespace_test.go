@@ -4,10 +4,12 @@
 package consul
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 )
 
 var namespaceEnterpriseFeature = regexp.MustCompile("(?i)Consul Enterprise feature")
@@ -28,11 +30,22 @@ func TestAccConsulNamespace_FailOnCommunityEdition(t *testing.T) {
 }
 
 func TestAccConsulNamespace(t *testing.T) {
-	providers, _ := startTestServer(t)
+	providers, client := startTestServer(t)
 
 	resource.Test(t, resource.TestCase{
 		PreCheck:  func() { skipTestOnConsulCommunityEdition(t) },
 		Providers: providers,
+		CheckDestroy: func(s *terraform.State) error {
+			// The namespace must be fully removed once the destroy returns
+			namespace, _, err := client.Namespaces().Read("test", nil)
+			if err != nil {
+				return err
+			}
+			if namespace != nil {
+				return fmt.Errorf("namespace 'test' still exists")
+			}
+			return nil
+		},
 		Steps: []resource.TestStep{
 			{
 				Config: testAccConsulNamespace,
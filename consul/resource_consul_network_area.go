@@ -5,6 +5,8 @@ package consul
 
 import (
 	"fmt"
+	"log"
+	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -49,6 +51,20 @@ func resourceConsulNetworkArea() *schema.Resource {
 				Type:     schema.TypeBool,
 				Optional: true,
 			},
+
+			"join_members": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "Whether the servers listed in `retry_join` should be joined right after the network area is created instead of waiting for the next retry of Consul.",
+			},
+
+			"joined_members": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The addresses of `retry_join` that were successfully joined when the network area was created. It is only set when `join_members` is `true`.",
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
 		},
 	}
 }
@@ -77,6 +93,26 @@ func resourceConsulNetworkAreaCreate(d *schema.ResourceData, meta interface{}) e
 	}
 
 	d.SetId(id)
+
+	if d.Get("join_members").(bool) && len(area.RetryJoin) > 0 {
+		responses, _, err := operator.AreaJoin(id, area.RetryJoin, wOpts)
+		if err != nil {
+			return fmt.Errorf("failed to join the members of the '%s' network area: %v", id, err)
+		}
+
+		joined := make([]string, 0, len(responses))
+		for _, r := range responses {
+			if !r.Joined {
+				log.Printf("[WARN] Failed to join %s in the '%s' network area: %s", r.Address, id, r.Error)
+				continue
+			}
+			joined = append(joined, r.Address)
+		}
+		if err := d.Set("joined_members", joined); err != nil {
+			return err
+		}
+	}
+
 	return resourceConsulNetworkAreaRead(d, meta)
 }
 
@@ -86,28 +122,36 @@ func resourceConsulNetworkAreaRead(d *schema.ResourceData, meta interface{}) err
 
 	id := d.Id()
 
-	area, _, err := operator.AreaGet(id, qOpts)
+	// The whole list is fetched so that an area removed out of band is
+	// reliably detected, whatever the version of Consul returns for an
+	// unknown ID
+	areas, _, err := operator.AreaList(qOpts)
 	if err != nil {
-		return fmt.Errorf("failed to get %s area: %v", id, err)
+		return fmt.Errorf("failed to list network areas: %v", err)
 	}
 
-	if len(area) == 0 {
-		d.SetId("")
-		return nil
+	var area *consulapi.Area
+	for _, a := range areas {
+		if strings.EqualFold(a.ID, id) {
+			area = a
+			break
+		}
 	}
 
-	if len(area) != 1 {
-		return fmt.Errorf("there should be only one area")
+	if area == nil {
+		log.Printf("[WARN] Network area '%s' not found, removing it from the state", id)
+		d.SetId("")
+		return nil
 	}
 
-	peerDatacenter := area[0].PeerDatacenter
-	retryJoin := area[0].RetryJoin
-	useTLS := area[0].UseTLS
-
 	sw := newStateWriter(d)
-	sw.set("peer_datacenter", peerDatacenter)
-	sw.set("retry_join", retryJoin)
-	sw.set("use_tls", useTLS)
+	sw.set("peer_datacenter", area.PeerDatacenter)
+	sw.set("retry_join", area.RetryJoin)
+	sw.set("use_tls", area.UseTLS)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
 
 	return sw.error()
 }
@@ -146,7 +190,7 @@ func resourceConsulNetworkAreaUpdate(d *schema.ResourceData, meta interface{}) e
 	}
 
 	d.SetId(id)
-	return nil
+	return resourceConsulNetworkAreaRead(d, meta)
 }
 
 func resourceConsulNetworkAreaDelete(d *schema.ResourceData, meta interface{}) error {
@@ -157,7 +201,7 @@ func resourceConsulNetworkAreaDelete(d *schema.ResourceData, meta interface{}) e
 
 	_, err := operator.AreaDelete(id, wOpts)
 	if err != nil {
-		return fmt.Errorf("failed to delete '%s' network area: %v", err, id)
+		return fmt.Errorf("failed to delete '%s' network area: %v", id, err)
 	}
 
 	d.SetId("")
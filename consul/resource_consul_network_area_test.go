@@ -51,6 +51,43 @@ func TestAccConsulNetworkArea_basic(t *testing.T) {
 	})
 }
 
+func TestAccConsulNetworkArea_join(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { skipTestOnConsulCommunityEdition(t) },
+		Providers:    providers,
+		CheckDestroy: testAccConsulNetworkAreaCheckDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulNetworkAreaJoin,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_network_area.test", "join_members", "true"),
+					// Nothing listens on this address so the join fails
+					resource.TestCheckResourceAttr("consul_network_area.test", "joined_members.#", "0"),
+				),
+			},
+			{
+				// An area removed out of band must be created again
+				PreConfig: func() {
+					areas, _, err := client.Operator().AreaList(nil)
+					if err != nil {
+						t.Fatalf("failed to list network areas: %v", err)
+					}
+					for _, area := range areas {
+						if _, err := client.Operator().AreaDelete(area.ID, nil); err != nil {
+							t.Fatalf("failed to delete network area: %v", err)
+						}
+					}
+				},
+				Config:             testAccConsulNetworkAreaJoin,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
 func TestAccConsulNetworkArea_CommunityEdition(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -178,3 +215,11 @@ resource "consul_network_area" "dc2" {
 	retry_join = []
 }
 `
+
+const testAccConsulNetworkAreaJoin = `
+resource "consul_network_area" "test" {
+	peer_datacenter = "foo"
+	retry_join      = ["127.0.0.1:1"]
+	join_members    = true
+}
+`
@@ -131,7 +131,7 @@ func resourceConsulNodeRead(d *schema.ResourceData, meta interface{}) error {
 
 	sw.set("address", n.Node.Address)
 	sw.set("meta", n.Node.Meta)
-	sw.set("partition", n.Node.Partition)
+	sw.set("partition", statePartition(d, meta, n.Node.Partition))
 
 	return sw.error()
 }
@@ -6,8 +6,11 @@ package consul
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
@@ -29,6 +32,11 @@ The functionality described here is available only in Consul version 1.13.0 and
 			State: schema.ImportStatePassthrough,
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			// In
 			"peer_name": {
@@ -66,8 +74,9 @@ The functionality described here is available only in Consul version 1.13.0 and
 				Computed: true,
 			},
 			"state": {
-				Type:     schema.TypeString,
-				Computed: true,
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The state of the peering, like `PENDING`, `ACTIVE` or `TERMINATED`. A terminated peering is established again on the next apply.",
 			},
 			"peer_id": {
 				Type:     schema.TypeString,
@@ -98,7 +107,11 @@ The functionality described here is available only in Consul version 1.13.0 and
 }
 
 func resourceConsulPeeringCreate(d *schema.ResourceData, meta interface{}) error {
-	client, _, wOpts := getClient(d, meta)
+	if err := meta.(*Config).requireVersion("consul_peering", peeringVersion); err != nil {
+		return err
+	}
+
+	client, qOpts, wOpts := getClient(d, meta)
 	name := d.Get("peer_name").(string)
 
 	m := map[string]string{}
@@ -106,6 +119,19 @@ func resourceConsulPeeringCreate(d *schema.ResourceData, meta interface{}) error
 		m[k] = v.(string)
 	}
 
+	// A terminated peering keeps its name until it has been deleted, it must
+	// be removed before it can be established again
+	existing, _, err := client.Peerings().Read(stopContext(meta), name, qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to read peering %q: %w", name, err)
+	}
+	if existing != nil && existing.State == api.PeeringStateTerminated {
+		log.Printf("[INFO] Deleting the terminated peering %q before establishing it again", name)
+		if err := deletePeering(stopContext(meta), client, name, d.Timeout(schema.TimeoutCreate), qOpts, wOpts); err != nil {
+			return err
+		}
+	}
+
 	req := api.PeeringEstablishRequest{
 		PeerName:     name,
 		PeeringToken: d.Get("peering_token").(string),
@@ -113,7 +139,7 @@ func resourceConsulPeeringCreate(d *schema.ResourceData, meta interface{}) error
 		Partition:    d.Get("partition").(string),
 	}
 
-	_, _, err := client.Peerings().Establish(context.Background(), req, wOpts)
+	_, _, err = client.Peerings().Establish(stopContext(meta), req, wOpts)
 	if err != nil {
 		return fmt.Errorf("failed to create peering: %w", err)
 	}
@@ -135,6 +161,14 @@ func resourceConsulPeeringRead(d *schema.ResourceData, meta interface{}) error {
 		return nil
 	}
 
+	// The peering must be established again once it has been terminated by
+	// the peer or is being deleted
+	if peer.State == api.PeeringStateTerminated || peer.State == api.PeeringStateDeleting {
+		log.Printf("[WARN] Peering %q is %s, removing it from the state", name, peer.State)
+		d.SetId("")
+		return nil
+	}
+
 	var deletedAt string
 	if peer.DeletedAt != nil {
 		deletedAt = peer.DeletedAt.String()
@@ -154,13 +188,37 @@ func resourceConsulPeeringRead(d *schema.ResourceData, meta interface{}) error {
 }
 
 func resourceConsulPeeringDelete(d *schema.ResourceData, meta interface{}) error {
-	client, _, wOpts := getClient(d, meta)
+	client, qOpts, wOpts := getClient(d, meta)
 	name := d.Get("peer_name").(string)
 
-	_, err := client.Peerings().Delete(context.Background(), name, wOpts)
+	if err := deletePeering(stopContext(meta), client, name, d.Timeout(schema.TimeoutDelete), qOpts, wOpts); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// deletePeering deletes the peering and waits until Consul has removed it,
+// the peerings are first marked as DELETING while their data is cleaned up.
+func deletePeering(ctx context.Context, client *api.Client, name string, timeout time.Duration, qOpts *api.QueryOptions, wOpts *api.WriteOptions) error {
+	_, err := client.Peerings().Delete(ctx, name, wOpts)
 	if err != nil {
 		return fmt.Errorf("failed to delete peering %#v: %w", name, err)
 	}
 
+	err = resource.Retry(timeout, func() *resource.RetryError {
+		peer, _, err := client.Peerings().Read(ctx, name, qOpts)
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		if peer != nil {
+			return resource.RetryableError(fmt.Errorf("peering %q is still %s", name, peer.State))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for peering %q to be deleted: %w", name, err)
+	}
 	return nil
 }
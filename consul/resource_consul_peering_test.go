@@ -4,13 +4,18 @@
 package consul
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 )
 
 func TestAccConsulPeering_basic(t *testing.T) {
-	providers, _ := startPeeringTestServers(t)
+	providers, client := startPeeringTestServers(t)
 
 	resource.Test(t, resource.TestCase{
 		Providers: providers,
@@ -39,6 +44,38 @@ func TestAccConsulPeering_basic(t *testing.T) {
 				ImportStateVerify:       true,
 				ImportStateVerifyIgnore: []string{"peering_token"},
 			},
+			{
+				// Deleting the peering on the other side terminates it, both
+				// resources must be created again
+				PreConfig: func() {
+					remote, err := consulapi.NewClient(&consulapi.Config{Address: "http://localhost:9500"})
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					if _, err := remote.Peerings().Delete(context.Background(), "hello-world", nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					for i := 0; i < 30; i++ {
+						peer, _, err := client.Peerings().Read(context.Background(), "test", nil)
+						if err != nil {
+							t.Fatalf("err: %v", err)
+						}
+						if peer != nil && peer.State == consulapi.PeeringStateTerminated {
+							return
+						}
+						time.Sleep(time.Second)
+					}
+					t.Fatal("the peering has not been terminated")
+				},
+				Config: testAccConsulPeeringBasic,
+				Check: func(s *terraform.State) error {
+					state := s.RootModule().Resources["consul_peering.basic"].Primary.Attributes["state"]
+					if state == string(consulapi.PeeringStateTerminated) {
+						return fmt.Errorf("the peering should have been established again")
+					}
+					return nil
+				},
+			},
 		},
 	})
 }
@@ -6,6 +6,7 @@ package consul
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -27,6 +28,10 @@ The functionality described here is available only in Consul version 1.13.0 and
 		Read:   resourceConsulPeeringTokenRead,
 		Delete: resourceConsulPeeringTokenDelete,
 
+		Timeouts: &schema.ResourceTimeout{
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"peer_name": {
 				Type:        schema.TypeString,
@@ -59,7 +64,14 @@ The functionality described here is available only in Consul version 1.13.0 and
 	}
 }
 
+// peeringVersion is the first version of Consul supporting cluster peering.
+const peeringVersion = "1.13.0"
+
 func resourceConsulPeeringTokenCreate(d *schema.ResourceData, meta interface{}) error {
+	if err := meta.(*Config).requireVersion("consul_peering_token", peeringVersion); err != nil {
+		return err
+	}
+
 	client, _, wOpts := getClient(d, meta)
 	name := d.Get("peer_name").(string)
 
@@ -96,7 +108,8 @@ func resourceConsulPeeringTokenRead(d *schema.ResourceData, meta interface{}) er
 		return fmt.Errorf("failed to find peer %q: %s", name, err)
 	}
 
-	if peer == nil {
+	// A new token must be generated once the peering has been terminated
+	if peer == nil || peer.State == api.PeeringStateTerminated || peer.State == api.PeeringStateDeleting {
 		d.SetId("")
 	}
 
@@ -104,12 +117,12 @@ func resourceConsulPeeringTokenRead(d *schema.ResourceData, meta interface{}) er
 }
 
 func resourceConsulPeeringTokenDelete(d *schema.ResourceData, meta interface{}) error {
-	name := d.Id()
-	client, _, wOpts := getClient(d, meta)
+	client, qOpts, wOpts := getClient(d, meta)
 
-	_, err := client.Peerings().Delete(context.Background(), name, wOpts)
-	if err != nil {
-		return fmt.Errorf("failed to delete peer %q: %s", name, err)
+	if err := deletePeering(stopContext(meta), client, d.Id(), d.Timeout(schema.TimeoutDelete), qOpts, wOpts); err != nil {
+		return err
 	}
+
+	d.SetId("")
 	return nil
 }
@@ -4,10 +4,12 @@
 package consul
 
 import (
+	"fmt"
 	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func resourceConsulPreparedQuery() *schema.Resource {
@@ -20,6 +22,8 @@ func resourceConsulPreparedQuery() *schema.Resource {
 			State: schema.ImportStatePassthrough,
 		},
 
+		CustomizeDiff: resourceConsulPreparedQueryCustomizeDiff,
+
 		SchemaVersion: 0,
 
 		Description: `Allows Terraform to manage a Consul prepared query.
@@ -161,9 +165,10 @@ Managing prepared queries is done using Consul's REST API. This resource is usef
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"ttl": {
-							Type:        schema.TypeString,
-							Optional:    true,
-							Description: "The TTL to send when returning DNS results.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateDurationMinFactory("dns.ttl", "0s"),
+							Description:  "The TTL to send when returning DNS results.",
 						},
 					},
 				},
@@ -177,14 +182,16 @@ Managing prepared queries is done using Consul's REST API. This resource is usef
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"type": {
-							Type:        schema.TypeString,
-							Required:    true,
-							Description: "The type of template matching to perform. Currently only `name_prefix_match` is supported.",
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice([]string{"name_prefix_match"}, false),
+							Description:  "The type of template matching to perform. Currently only `name_prefix_match` is supported.",
 						},
 						"regexp": {
-							Type:        schema.TypeString,
-							Required:    true,
-							Description: "The regular expression to match with. When using `name_prefix_match`, this regex is applied against the query name.",
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringIsValidRegExp,
+							Description:  "The regular expression to match with. When using `name_prefix_match`, this regex is applied against the query name.",
 						},
 						"remove_empty_tags": {
 							Type:        schema.TypeBool,
@@ -198,6 +205,19 @@ Managing prepared queries is done using Consul's REST API. This resource is usef
 	}
 }
 
+// resourceConsulPreparedQueryCustomizeDiff rejects the failover options that
+// Consul does not accept together.
+func resourceConsulPreparedQueryCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	targets := d.Get("failover.0.targets").([]interface{})
+	if len(targets) == 0 {
+		return nil
+	}
+	if d.Get("failover.0.nearest_n").(int) > 0 || len(d.Get("failover.0.datacenters").([]interface{})) > 0 {
+		return fmt.Errorf("failover.targets cannot be used with failover.nearest_n or failover.datacenters")
+	}
+	return nil
+}
+
 func resourceConsulPreparedQueryCreate(d *schema.ResourceData, meta interface{}) error {
 	client, _, wOpts := getClient(d, meta)
 	pq := preparedQueryDefinitionFromResourceData(d)
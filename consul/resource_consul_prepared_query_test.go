@@ -6,6 +6,7 @@ package consul
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
@@ -155,6 +156,10 @@ func TestAccConsulPreparedQuery_blocks(t *testing.T) {
 					resource.TestCheckResourceAttr("consul_prepared_query.foo", "failover.0.targets.1.datacenter", "dc3"),
 				),
 			},
+			{
+				Config:      testAccConsulPreparedQueryBlocksInvalidFailover,
+				ExpectError: regexp.MustCompile("failover.targets cannot be used with failover.nearest_n or failover.datacenters"),
+			},
 		},
 	})
 }
@@ -455,3 +460,19 @@ resource "consul_prepared_query" "dc2" {
 	service    = "redis"
 }
 `
+
+const testAccConsulPreparedQueryBlocksInvalidFailover = `
+resource "consul_prepared_query" "foo" {
+	name = "foo"
+	stored_token = "pq-token"
+	service = "redis"
+
+	failover {
+		nearest_n = 3
+
+		targets {
+			peer = "test2"
+		}
+	}
+}
+`
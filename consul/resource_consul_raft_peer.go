@@ -0,0 +1,171 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+var errRaftPeerNotConfirmed = errors.New("removing a server from the Raft peer set can cause an outage, confirm must be set to true")
+
+// resourceConsulRaftPeer removes a failed server from the Raft peer set. The
+// removal is done once when the resource is created, destroying the resource
+// does not add the server back.
+func resourceConsulRaftPeer() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulRaftPeerCreate,
+		Read:   resourceConsulRaftPeerRead,
+		Delete: resourceConsulRaftPeerDelete,
+
+		CustomizeDiff: resourceConsulRaftPeerCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"peer_id": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"peer_id", "address"},
+				Description:  "The Raft ID of the server to remove.",
+			},
+
+			"address": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"peer_id", "address"},
+				Description:  "The Raft address, as IP:port, of the server to remove.",
+			},
+
+			"confirm": {
+				Type:        schema.TypeBool,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Must be set to `true` to acknowledge that the server is removed from the Raft peer set.",
+			},
+
+			"present": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the server is still part of the Raft peer set.",
+			},
+
+			"voter": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the server has a vote in the cluster, it is false once the server has been removed.",
+			},
+
+			"node": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The node name of the server.",
+			},
+		},
+	}
+}
+
+func resourceConsulRaftPeerCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" && d.NewValueKnown("confirm") && !d.Get("confirm").(bool) {
+		return errRaftPeerNotConfirmed
+	}
+	return nil
+}
+
+func resourceConsulRaftPeerCreate(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, wOpts := getClient(d, meta)
+	operator := client.Operator()
+
+	if !d.Get("confirm").(bool) {
+		return errRaftPeerNotConfirmed
+	}
+
+	id := d.Get("peer_id").(string)
+	address := d.Get("address").(string)
+
+	config, err := operator.RaftGetConfiguration(qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to read the Raft configuration: %v", err)
+	}
+
+	// The server may already have been removed by a previous run or by
+	// autopilot, there is nothing left to do then
+	if server := findRaftServer(config, id, address); server == nil {
+		log.Printf("[INFO] Raft peer %q is not in the peer set, nothing to remove", id+address)
+	} else {
+		log.Printf("[WARN] Removing Raft peer %q (%s) from the peer set", server.ID, server.Address)
+		if id != "" {
+			err = operator.RaftRemovePeerByID(id, wOpts)
+		} else {
+			err = operator.RaftRemovePeerByAddress(address, wOpts)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove Raft peer %q: %v", id+address, err)
+		}
+	}
+
+	d.SetId(fmt.Sprintf("%s-%s", qOpts.Datacenter, id+address))
+
+	return resourceConsulRaftPeerRead(d, meta)
+}
+
+// resourceConsulRaftPeerRead reports whether the server is still in the peer
+// set, it is not removed again if it rejoined the cluster.
+func resourceConsulRaftPeerRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+
+	config, err := client.Operator().RaftGetConfiguration(qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to read the Raft configuration: %v", err)
+	}
+
+	server := findRaftServer(config, d.Get("peer_id").(string), d.Get("address").(string))
+
+	sw := newStateWriter(d)
+	sw.set("present", server != nil)
+	if server != nil {
+		sw.set("voter", server.Voter)
+		sw.set("node", server.Node)
+	} else {
+		sw.set("voter", false)
+	}
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
+
+	return sw.error()
+}
+
+// resourceConsulRaftPeerDelete only removes the resource from the state, the
+// server must join the cluster again by itself.
+func resourceConsulRaftPeerDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+// findRaftServer returns the server with the given ID or address in config,
+// or nil when it is not part of the peer set.
+func findRaftServer(config *consulapi.RaftConfiguration, id, address string) *consulapi.RaftServer {
+	for _, server := range config.Servers {
+		if id != "" && server.ID == id {
+			return server
+		}
+		if address != "" && server.Address == address {
+			return server
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccConsulRaftPeer_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulRaftPeerConfig(false),
+				ExpectError: regexp.MustCompile("confirm must be set to true"),
+			},
+			{
+				// The only server of the test cluster cannot be removed, a
+				// peer that is not in the peer set is used instead
+				Config: testAccConsulRaftPeerConfig(true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_raft_peer.failed", "datacenter", "dc1"),
+					resource.TestCheckResourceAttr("consul_raft_peer.failed", "present", "false"),
+					resource.TestCheckResourceAttr("consul_raft_peer.failed", "voter", "false"),
+				),
+			},
+		},
+	})
+}
+
+func testAccConsulRaftPeerConfig(confirm bool) string {
+	return fmt.Sprintf(`
+resource "consul_raft_peer" "failed" {
+  address = "10.254.254.254:8300"
+  confirm = %t
+}`, confirm)
+}
@@ -131,6 +131,12 @@ func resourceConsulService() *schema.Resource {
 					}
 					attrs = append(attrs, headers...)
 
+					// The gRPC attributes are only added when set so that the
+					// hash of the existing checks does not change
+					if grpc := m["grpc"].(string); grpc != "" {
+						attrs = append(attrs, grpc, strconv.FormatBool(m["grpc_use_tls"].(bool)))
+					}
+
 					return hashcode.String(hashcode.Strings(attrs))
 				},
 				Optional: true,
@@ -166,6 +172,17 @@ func resourceConsulService() *schema.Resource {
 							Optional: true,
 						},
 
+						"grpc": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"grpc_use_tls": {
+							Type:     schema.TypeBool,
+							Optional: true,
+							Default:  false,
+						},
+
 						"header": {
 							Type:     schema.TypeSet,
 							Optional: true,
@@ -186,12 +203,12 @@ func resourceConsulService() *schema.Resource {
 
 						"interval": {
 							Type:     schema.TypeString,
-							Required: true,
+							Optional: true,
 						},
 
 						"timeout": {
 							Type:     schema.TypeString,
-							Required: true,
+							Optional: true,
 						},
 
 						"deregister_critical_service_after": {
@@ -265,6 +282,34 @@ func resourceConsulServiceUpdate(d *schema.ResourceData, meta interface{}) error
 		return fmt.Errorf("failed to update service (dc: '%s'): %v", wOpts.Datacenter, err)
 	}
 
+	// Registering the service only adds or updates its checks, those that
+	// have been removed from the configuration must be deregistered
+	if d.HasChange("check") {
+		desired := make(map[string]bool)
+		for _, check := range registration.Checks {
+			desired[check.CheckID] = true
+		}
+
+		o, _ := d.GetChange("check")
+		for _, raw := range o.(*schema.Set).List() {
+			checkID := raw.(map[string]interface{})["check_id"].(string)
+			if desired[checkID] {
+				continue
+			}
+
+			deregistration := &consulapi.CatalogDeregistration{
+				Datacenter: wOpts.Datacenter,
+				Node:       registration.Node,
+				CheckID:    checkID,
+				Namespace:  wOpts.Namespace,
+				Partition:  wOpts.Partition,
+			}
+			if _, err := catalog.Deregister(deregistration, wOpts); err != nil {
+				return fmt.Errorf("failed to deregister check '%s' (dc: '%s'): %v", checkID, wOpts.Datacenter, err)
+			}
+		}
+	}
+
 	return resourceConsulServiceRead(d, meta)
 }
 
@@ -307,10 +352,12 @@ func resourceConsulServiceRead(d *schema.ResourceData, meta interface{}) error {
 		m["status"] = check.Status
 		m["tcp"] = check.Definition.TCP
 		m["http"] = check.Definition.HTTP
+		m["grpc"] = check.Definition.GRPC
+		m["grpc_use_tls"] = check.Definition.GRPCUseTLS
 		m["tls_skip_verify"] = check.Definition.TLSSkipVerify
 		m["method"] = check.Definition.Method
-		m["interval"] = check.Definition.Interval.String()
-		m["timeout"] = check.Definition.Timeout.String()
+		m["interval"] = durationString(check.Definition.Interval)
+		m["timeout"] = durationString(check.Definition.Timeout)
 		m["deregister_critical_service_after"] = check.Definition.DeregisterCriticalServiceAfter.String()
 		headers := make([]interface{}, 0)
 		for name, value := range check.Definition.Header {
@@ -338,7 +385,7 @@ func resourceConsulServiceRead(d *schema.ResourceData, meta interface{}) error {
 	sw.set("check", checks)
 	sw.set("enable_tag_override", service.ServiceEnableTagOverride)
 	sw.set("namespace", service.Namespace)
-	sw.set("partition", service.Partition)
+	sw.set("partition", statePartition(d, meta, service.Partition))
 
 	return sw.error()
 }
@@ -413,20 +460,39 @@ func parseChecks(node string, serviceID string, d *schema.ResourceData) ([]*cons
 		if err != nil {
 			return nil, err
 		}
-		interval, err := time.ParseDuration(check["interval"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse interval: %#v", interval)
-		}
-		timeout, err := time.ParseDuration(check["timeout"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse timeout: %#v", timeout)
-		}
-
 		tcp := check["tcp"].(string)
 		http := check["http"].(string)
+		grpc := check["grpc"].(string)
 		if tcp != "" && http != "" {
 			return nil, fmt.Errorf("you cannot set both tcp and http in the same check")
 		}
+		if grpc != "" && (tcp != "" || http != "") {
+			return nil, fmt.Errorf("you cannot set grpc with tcp or http in the same check")
+		}
+
+		// A check without tcp, http or grpc is a TTL check whose status is
+		// updated outside of Consul, it does not need an interval
+		checkType := "ttl"
+		if tcp != "" || http != "" || grpc != "" {
+			checkType = ""
+			if check["interval"].(string) == "" || check["timeout"].(string) == "" {
+				return nil, fmt.Errorf("interval and timeout must be set for check '%s'", check["check_id"].(string))
+			}
+		}
+
+		var interval, timeout time.Duration
+		if raw := check["interval"].(string); raw != "" {
+			interval, err = time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse interval: %#v", raw)
+			}
+		}
+		if raw := check["timeout"].(string); raw != "" {
+			timeout, err = time.ParseDuration(raw)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse timeout: %#v", raw)
+			}
+		}
 		var tlsSkipVerify bool
 		if check["tls_skip_verify"] != nil {
 			tlsSkipVerify = check["tls_skip_verify"].(bool)
@@ -441,6 +507,8 @@ func parseChecks(node string, serviceID string, d *schema.ResourceData) ([]*cons
 			Method:        method,
 			TLSSkipVerify: tlsSkipVerify,
 			TCP:           tcp,
+			GRPC:          grpc,
+			GRPCUseTLS:    check["grpc_use_tls"].(bool),
 			Interval:      *consulapi.NewReadableDuration(interval),
 			Timeout:       *consulapi.NewReadableDuration(timeout),
 		}
@@ -465,6 +533,7 @@ func parseChecks(node string, serviceID string, d *schema.ResourceData) ([]*cons
 			Name:       check["name"].(string),
 			Notes:      check["notes"].(string),
 			Status:     check["status"].(string),
+			Type:       checkType,
 			Definition: healthCheck,
 		}
 	}
@@ -472,6 +541,15 @@ func parseChecks(node string, serviceID string, d *schema.ResourceData) ([]*cons
 	return s, nil
 }
 
+// durationString returns the duration as it is written in the configuration,
+// a missing duration is left empty.
+func durationString(d consulapi.ReadableDuration) string {
+	if d.Duration() == 0 {
+		return ""
+	}
+	return d.String()
+}
+
 func parseHeaders(check map[string]interface{}) (map[string][]string, error) {
 	headers := make(map[string][]string)
 	header := check["header"].(*schema.Set).List()
@@ -0,0 +1,507 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+// resourceConsulServiceIntentions manages a service-intentions config entry
+// with typed attributes, the consul_config_entry resource can be used for the
+// options it does not support.
+func resourceConsulServiceIntentions() *schema.Resource {
+	actions := []string{string(consulapi.IntentionActionAllow), string(consulapi.IntentionActionDeny)}
+
+	return &schema.Resource{
+		Create: resourceConsulServiceIntentionsUpdate,
+		Update: resourceConsulServiceIntentionsUpdate,
+		Read:   resourceConsulServiceIntentionsRead,
+		Delete: resourceConsulServiceIntentionsDelete,
+		Importer: &schema.ResourceImporter{
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				parts := strings.Split(d.Id(), "/")
+				var name, partition, namespace string
+				switch len(parts) {
+				case 1:
+					name = parts[0]
+				case 3:
+					partition = parts[0]
+					namespace = parts[1]
+					name = parts[2]
+				default:
+					return nil, fmt.Errorf(`expected path of the form "<name>" or "<partition>/<namespace>/<name>"`)
+				}
+
+				d.SetId(fmt.Sprintf("%s-%s", consulapi.ServiceIntentions, name))
+				sw := newStateWriter(d)
+				sw.set("name", name)
+				sw.set("partition", partition)
+				sw.set("namespace", namespace)
+				if err := sw.error(); err != nil {
+					return nil, err
+				}
+
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+
+		CustomizeDiff: resourceConsulServiceIntentionsCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the destination service the intentions apply to.",
+			},
+
+			"partition": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The partition the config entry is associated with.",
+			},
+
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The namespace the config entry is associated with.",
+			},
+
+			"meta": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The metadata of the config entry.",
+			},
+
+			"source": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				Description: "The sources allowed or denied to connect to the destination service, in order.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The name of the source service, `*` matches all the services.",
+						},
+
+						"namespace": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"partition": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"peer": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"action": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringInSlice(actions, false),
+							Description:  "Whether the source is allowed or denied, it cannot be used with `permission`.",
+						},
+
+						"description": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"permission": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "The L7 permissions of the source, evaluated in order, it cannot be used with `action`.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"action": {
+										Type:         schema.TypeString,
+										Required:     true,
+										ValidateFunc: validation.StringInSlice(actions, false),
+									},
+
+									"http": {
+										Type:     schema.TypeList,
+										Required: true,
+										MaxItems: 1,
+										Elem: &schema.Resource{
+											Schema: map[string]*schema.Schema{
+												"path_exact": {
+													Type:     schema.TypeString,
+													Optional: true,
+												},
+
+												"path_prefix": {
+													Type:     schema.TypeString,
+													Optional: true,
+												},
+
+												"path_regex": {
+													Type:     schema.TypeString,
+													Optional: true,
+												},
+
+												"methods": {
+													Type:     schema.TypeList,
+													Optional: true,
+													Elem:     &schema.Schema{Type: schema.TypeString},
+												},
+
+												"header": {
+													Type:     schema.TypeList,
+													Optional: true,
+													Elem: &schema.Resource{
+														Schema: map[string]*schema.Schema{
+															"name": {
+																Type:     schema.TypeString,
+																Required: true,
+															},
+
+															"present": {
+																Type:     schema.TypeBool,
+																Optional: true,
+															},
+
+															"exact": {
+																Type:     schema.TypeString,
+																Optional: true,
+															},
+
+															"prefix": {
+																Type:     schema.TypeString,
+																Optional: true,
+															},
+
+															"suffix": {
+																Type:     schema.TypeString,
+																Optional: true,
+															},
+
+															"regex": {
+																Type:     schema.TypeString,
+																Optional: true,
+															},
+
+															"invert": {
+																Type:     schema.TypeBool,
+																Optional: true,
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+
+			"modify_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The index of the last modification of the config entry, it is used to detect concurrent changes.",
+			},
+		},
+	}
+}
+
+func resourceConsulServiceIntentionsCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("source") {
+		return nil
+	}
+	_, err := serviceIntentionsSources(d.Get("source").([]interface{}))
+	return err
+}
+
+func resourceConsulServiceIntentionsUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, _, wOpts := getClient(d, meta)
+	configEntries := client.ConfigEntries()
+
+	sources, err := serviceIntentionsSources(d.Get("source").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	name := d.Get("name").(string)
+	entry := &consulapi.ServiceIntentionsConfigEntry{
+		Kind:      consulapi.ServiceIntentions,
+		Name:      name,
+		Partition: wOpts.Partition,
+		Namespace: wOpts.Namespace,
+		Sources:   sources,
+		Meta:      map[string]string{},
+	}
+	for k, v := range d.Get("meta").(map[string]interface{}) {
+		entry.Meta[k] = v.(string)
+	}
+
+	// The config entry is only written if it has not been changed since it was
+	// last read, an index of 0 means that it must not exist yet
+	var index uint64
+	if d.Id() != "" {
+		index = uint64(d.Get("modify_index").(int))
+	}
+	ok, _, err := configEntries.CAS(entry, index, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to set '%s' service intentions: %v", name, err)
+	}
+	if !ok {
+		if index == 0 {
+			return fmt.Errorf("failed to set '%s' service intentions: they already exist, import them to manage them with Terraform", name)
+		}
+		return fmt.Errorf("failed to set '%s' service intentions: they have been modified outside of Terraform since they were last read", name)
+	}
+
+	d.SetId(fmt.Sprintf("%s-%s", consulapi.ServiceIntentions, name))
+
+	return resourceConsulServiceIntentionsRead(d, meta)
+}
+
+// serviceIntentionsSources returns the sources of the config entry, each
+// source must either set an action or a list of permissions.
+func serviceIntentionsSources(raw []interface{}) ([]*consulapi.SourceIntention, error) {
+	sources := make([]*consulapi.SourceIntention, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, r := range raw {
+		s := r.(map[string]interface{})
+		source := &consulapi.SourceIntention{
+			Name:        s["name"].(string),
+			Namespace:   s["namespace"].(string),
+			Partition:   s["partition"].(string),
+			Peer:        s["peer"].(string),
+			Action:      consulapi.IntentionAction(s["action"].(string)),
+			Description: s["description"].(string),
+		}
+
+		id := sourceIntentionID(source)
+		if seen[id] {
+			return nil, fmt.Errorf("source %q is declared more than once", id)
+		}
+		seen[id] = true
+
+		for _, rawPermission := range s["permission"].([]interface{}) {
+			p := rawPermission.(map[string]interface{})
+			permission := &consulapi.IntentionPermission{
+				Action: consulapi.IntentionAction(p["action"].(string)),
+			}
+			if h := p["http"].([]interface{}); len(h) > 0 && h[0] != nil {
+				http := h[0].(map[string]interface{})
+				permission.HTTP = &consulapi.IntentionHTTPPermission{
+					PathExact:  http["path_exact"].(string),
+					PathPrefix: http["path_prefix"].(string),
+					PathRegex:  http["path_regex"].(string),
+				}
+				paths := 0
+				for _, path := range []string{permission.HTTP.PathExact, permission.HTTP.PathPrefix, permission.HTTP.PathRegex} {
+					if path != "" {
+						paths++
+					}
+				}
+				if paths > 1 {
+					return nil, fmt.Errorf("only one of path_exact, path_prefix or path_regex can be set in the permissions of source %q", id)
+				}
+				for _, method := range http["methods"].([]interface{}) {
+					permission.HTTP.Methods = append(permission.HTTP.Methods, method.(string))
+				}
+				for _, rawHeader := range http["header"].([]interface{}) {
+					header := rawHeader.(map[string]interface{})
+					permission.HTTP.Header = append(permission.HTTP.Header, consulapi.IntentionHTTPHeaderPermission{
+						Name:    header["name"].(string),
+						Present: header["present"].(bool),
+						Exact:   header["exact"].(string),
+						Prefix:  header["prefix"].(string),
+						Suffix:  header["suffix"].(string),
+						Regex:   header["regex"].(string),
+						Invert:  header["invert"].(bool),
+					})
+				}
+			}
+			source.Permissions = append(source.Permissions, permission)
+		}
+
+		if (source.Action == "") == (len(source.Permissions) == 0) {
+			return nil, fmt.Errorf("source %q must set either action or permission", id)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// sourceIntentionID identifies a source in the config entry.
+func sourceIntentionID(source *consulapi.SourceIntention) string {
+	id := source.Name
+	if source.Namespace != "" {
+		id = source.Namespace + "/" + id
+	}
+	if source.Partition != "" {
+		id = source.Partition + "/" + id
+	}
+	if source.Peer != "" {
+		id = "peer:" + source.Peer + "/" + id
+	}
+	return id
+}
+
+func resourceConsulServiceIntentionsRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+	name := d.Get("name").(string)
+
+	raw, _, err := client.ConfigEntries().Get(consulapi.ServiceIntentions, name, qOpts)
+	if err != nil {
+		if errors.Is(newAPIError(err), ErrNotFound) {
+			// The config entry has been removed
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("failed to read '%s' service intentions: %v", name, err)
+	}
+	entry, ok := raw.(*consulapi.ServiceIntentionsConfigEntry)
+	if !ok {
+		return fmt.Errorf("unexpected config entry type %T for service intentions '%s'", raw, name)
+	}
+
+	// Consul returns the sources sorted by precedence while their order is
+	// significant in the configuration, they are reported in the order of the
+	// state so that only a change to the sources themselves is a drift.
+	order := make(map[string]int)
+	for i, r := range d.Get("source").([]interface{}) {
+		s := r.(map[string]interface{})
+		order[sourceIntentionID(&consulapi.SourceIntention{
+			Name:      s["name"].(string),
+			Namespace: s["namespace"].(string),
+			Partition: s["partition"].(string),
+			Peer:      s["peer"].(string),
+		})] = i
+	}
+	sources := make([]interface{}, len(order))
+	var extra []interface{}
+	for _, source := range entry.Sources {
+		s := flattenSourceIntention(source)
+
+		// The namespace and partition of the sources are set by Consul
+		// Enterprise, they are reported as they were configured when they
+		// are the ones of the config entry.
+		id := sourceIntentionID(source)
+		i, ok := order[id]
+		if !ok {
+			local := *source
+			if local.Namespace == entry.Namespace || local.Namespace == "default" {
+				local.Namespace = ""
+			}
+			if local.Partition == entry.Partition || local.Partition == "default" {
+				local.Partition = ""
+			}
+			i, ok = order[sourceIntentionID(&local)]
+			if ok {
+				s["namespace"] = local.Namespace
+				s["partition"] = local.Partition
+			}
+		}
+		if ok && sources[i] == nil {
+			sources[i] = s
+			continue
+		}
+		extra = append(extra, s)
+	}
+
+	// The sources removed outside of Terraform are dropped and those added
+	// are reported after the others
+	result := make([]interface{}, 0, len(entry.Sources))
+	for _, s := range sources {
+		if s != nil {
+			result = append(result, s)
+		}
+	}
+	result = append(result, extra...)
+
+	sw := newStateWriter(d)
+	sw.set("meta", entry.Meta)
+	sw.set("source", result)
+	sw.set("modify_index", int(entry.ModifyIndex))
+
+	return sw.error()
+}
+
+func flattenSourceIntention(source *consulapi.SourceIntention) map[string]interface{} {
+	permissions := make([]interface{}, 0, len(source.Permissions))
+	for _, p := range source.Permissions {
+		http := []interface{}{}
+		if h := p.HTTP; h != nil {
+			headers := make([]interface{}, 0, len(h.Header))
+			for _, header := range h.Header {
+				headers = append(headers, map[string]interface{}{
+					"name":    header.Name,
+					"present": header.Present,
+					"exact":   header.Exact,
+					"prefix":  header.Prefix,
+					"suffix":  header.Suffix,
+					"regex":   header.Regex,
+					"invert":  header.Invert,
+				})
+			}
+			http = append(http, map[string]interface{}{
+				"path_exact":  h.PathExact,
+				"path_prefix": h.PathPrefix,
+				"path_regex":  h.PathRegex,
+				"methods":     h.Methods,
+				"header":      headers,
+			})
+		}
+		permissions = append(permissions, map[string]interface{}{
+			"action": string(p.Action),
+			"http":   http,
+		})
+	}
+
+	return map[string]interface{}{
+		"name":        source.Name,
+		"namespace":   source.Namespace,
+		"partition":   source.Partition,
+		"peer":        source.Peer,
+		"action":      string(source.Action),
+		"description": source.Description,
+		"permission":  permissions,
+	}
+}
+
+func resourceConsulServiceIntentionsDelete(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, wOpts := getClient(d, meta)
+	configEntries := client.ConfigEntries()
+	name := d.Get("name").(string)
+	index := uint64(d.Get("modify_index").(int))
+
+	ok, _, err := configEntries.DeleteCAS(consulapi.ServiceIntentions, name, index, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to delete '%s' service intentions: %v", name, err)
+	}
+	if !ok {
+		// The CAS also fails when the config entry does not exist anymore
+		_, _, err := configEntries.Get(consulapi.ServiceIntentions, name, qOpts)
+		if err == nil {
+			return fmt.Errorf("failed to delete '%s' service intentions: they have been modified outside of Terraform since they were last read", name)
+		}
+		if !errors.Is(newAPIError(err), ErrNotFound) {
+			return fmt.Errorf("failed to read '%s' service intentions: %v", name, err)
+		}
+	}
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulServiceIntentions_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { skipTestOnConsulEnterpriseEdition(t) },
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulServiceIntentionsDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulServiceIntentionsConfigInvalid,
+				ExpectError: regexp.MustCompile(`source "web" must set either action or permission`),
+			},
+			{
+				Config: testAccConsulServiceIntentionsConfig("deny"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "name", "api"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.#", "3"),
+					// The sources are kept in the order of the configuration
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.0.name", "*"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.0.action", "deny"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.1.name", "web"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.1.permission.#", "2"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.1.permission.0.http.0.path_prefix", "/v1/"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.1.permission.0.http.0.methods.#", "2"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.1.permission.0.http.0.header.0.name", "X-Version"),
+					resource.TestCheckResourceAttr("consul_service_intentions.api", "source.2.name", "admin"),
+					resource.TestCheckResourceAttrSet("consul_service_intentions.api", "modify_index"),
+					func(s *terraform.State) error {
+						raw, _, err := client.ConfigEntries().Get(consulapi.ServiceIntentions, "api", nil)
+						if err != nil {
+							return err
+						}
+						entry := raw.(*consulapi.ServiceIntentionsConfigEntry)
+						if len(entry.Sources) != 3 {
+							return fmt.Errorf("unexpected sources: %#v", entry.Sources)
+						}
+						for _, source := range entry.Sources {
+							if source.Name == "web" && source.Permissions[1].Action != consulapi.IntentionActionDeny {
+								return fmt.Errorf("unexpected permissions: %#v", source.Permissions)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: testAccConsulServiceIntentionsConfig("allow"),
+				Check:  resource.TestCheckResourceAttr("consul_service_intentions.api", "source.0.action", "allow"),
+			},
+			{
+				// A change made outside of Terraform is detected
+				PreConfig: func() {
+					raw, _, err := client.ConfigEntries().Get(consulapi.ServiceIntentions, "api", nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					entry := raw.(*consulapi.ServiceIntentionsConfigEntry)
+					for _, source := range entry.Sources {
+						if source.Name == "admin" {
+							source.Action = consulapi.IntentionActionDeny
+						}
+					}
+					if _, _, err := client.ConfigEntries().Set(entry, nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:             testAccConsulServiceIntentionsConfig("allow"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulServiceIntentionsConfig("allow"),
+			},
+			{
+				Config:            testAccConsulServiceIntentionsConfig("allow"),
+				ResourceName:      "consul_service_intentions.api",
+				ImportState:       true,
+				ImportStateId:     "api",
+				ImportStateVerify: true,
+				// The imported sources are in the order returned by Consul
+				ImportStateVerifyIgnore: []string{"source"},
+			},
+		},
+	})
+}
+
+func testAccCheckConsulServiceIntentionsDestroy(client *consulapi.Client) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, _, err := client.ConfigEntries().Get(consulapi.ServiceIntentions, "api", nil)
+		if err == nil {
+			return fmt.Errorf("service intentions api still exist")
+		}
+		return nil
+	}
+}
+
+func testAccConsulServiceIntentionsConfig(wildcardAction string) string {
+	return fmt.Sprintf(`
+resource "consul_config_entry" "api" {
+  kind = "service-defaults"
+  name = "api"
+
+  config_json = jsonencode({
+    Protocol = "http"
+  })
+}
+
+resource "consul_service_intentions" "api" {
+  name = consul_config_entry.api.name
+
+  source {
+    name   = "*"
+    action = "%s"
+  }
+
+  source {
+    name = "web"
+
+    permission {
+      action = "allow"
+
+      http {
+        path_prefix = "/v1/"
+        methods     = ["GET", "HEAD"]
+
+        header {
+          name  = "X-Version"
+          exact = "2"
+        }
+      }
+    }
+
+    permission {
+      action = "deny"
+
+      http {
+        path_exact = "/admin"
+      }
+    }
+  }
+
+  source {
+    name        = "admin"
+    action      = "allow"
+    description = "The admin console"
+  }
+}
+`, wildcardAction)
+}
+
+const testAccConsulServiceIntentionsConfigInvalid = `
+resource "consul_service_intentions" "api" {
+  name = "api"
+
+  source {
+    name = "web"
+  }
+}
+`
@@ -0,0 +1,487 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// resourceConsulServiceResolver manages a service-resolver config entry with
+// typed attributes, the consul_config_entry resource can be used for the
+// options it does not support.
+func resourceConsulServiceResolver() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulServiceResolverUpdate,
+		Update: resourceConsulServiceResolverUpdate,
+		Read:   resourceConsulServiceResolverRead,
+		Delete: resourceConsulServiceResolverDelete,
+		Importer: &schema.ResourceImporter{
+			State: func(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+				parts := strings.Split(d.Id(), "/")
+				var name, partition, namespace string
+				switch len(parts) {
+				case 1:
+					name = parts[0]
+				case 3:
+					partition = parts[0]
+					namespace = parts[1]
+					name = parts[2]
+				default:
+					return nil, fmt.Errorf(`expected path of the form "<name>" or "<partition>/<namespace>/<name>"`)
+				}
+
+				d.SetId(fmt.Sprintf("%s-%s", consulapi.ServiceResolver, name))
+				sw := newStateWriter(d)
+				sw.set("name", name)
+				sw.set("partition", partition)
+				sw.set("namespace", namespace)
+				if err := sw.error(); err != nil {
+					return nil, err
+				}
+
+				return []*schema.ResourceData{d}, nil
+			},
+		},
+
+		CustomizeDiff: resourceConsulServiceResolverCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The name of the service the resolver applies to.",
+			},
+
+			"partition": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The partition the config entry is associated with.",
+			},
+
+			"namespace": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The namespace the config entry is associated with.",
+			},
+
+			"meta": {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The metadata of the config entry.",
+			},
+
+			"connect_timeout": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateFunc:     validateDurationMinFactory("connect_timeout", "0s"),
+				DiffSuppressFunc: diffDuration,
+				Description:      "The timeout for establishing new network connections to the service.",
+			},
+
+			"default_subset": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The subset to use when no subset is requested, it must be declared in a `subset` block.",
+			},
+
+			"subset": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "A subset of the instances of the service.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The name of the subset.",
+						},
+
+						"filter": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The filter expression selecting the instances of the subset.",
+						},
+
+						"only_passing": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Whether only the instances with passing health checks are part of the subset.",
+						},
+					},
+				},
+			},
+
+			"redirect": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Redirects the requests for the service to another service.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"service": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"service_subset": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"namespace": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"partition": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"datacenter": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"peer": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+
+			"failover": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "The failover policies of the subsets of the service.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"subset_name": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "The subset the policy applies to, `*` applies to all the subsets.",
+						},
+
+						"service": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"service_subset": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"namespace": {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+
+						"datacenters": {
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+
+						"target": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "The targets to fail over to, in order of preference.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"service": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+
+									"service_subset": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+
+									"partition": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+
+									"namespace": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+
+									"datacenter": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+
+									"peer": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+
+			"modify_index": {
+				Type:        schema.TypeInt,
+				Computed:    true,
+				Description: "The index of the last modification of the config entry, it is used to detect concurrent changes.",
+			},
+		},
+	}
+}
+
+func resourceConsulServiceResolverCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("default_subset") || !d.NewValueKnown("subset") {
+		return nil
+	}
+	return checkServiceResolverDefaultSubset(d)
+}
+
+// checkServiceResolverDefaultSubset returns an error when default_subset is
+// not one of the subsets of the resolver.
+func checkServiceResolverDefaultSubset(d interface{ Get(string) interface{} }) error {
+	defaultSubset := d.Get("default_subset").(string)
+	if defaultSubset == "" {
+		return nil
+	}
+
+	names := []string{}
+	for _, raw := range d.Get("subset").(*schema.Set).List() {
+		name := raw.(map[string]interface{})["name"].(string)
+		if name == defaultSubset {
+			return nil
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("default_subset %q is not a declared subset, expected one of %v", defaultSubset, names)
+}
+
+func resourceConsulServiceResolverUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, _, wOpts := getClient(d, meta)
+	configEntries := client.ConfigEntries()
+
+	if err := checkServiceResolverDefaultSubset(d); err != nil {
+		return err
+	}
+
+	name := d.Get("name").(string)
+	entry := &consulapi.ServiceResolverConfigEntry{
+		Kind:          consulapi.ServiceResolver,
+		Name:          name,
+		Partition:     wOpts.Partition,
+		Namespace:     wOpts.Namespace,
+		DefaultSubset: d.Get("default_subset").(string),
+		Meta:          map[string]string{},
+	}
+	for k, v := range d.Get("meta").(map[string]interface{}) {
+		entry.Meta[k] = v.(string)
+	}
+	if timeout := d.Get("connect_timeout").(string); timeout != "" {
+		// The duration has already been validated by the schema
+		entry.ConnectTimeout, _ = time.ParseDuration(timeout)
+	}
+
+	subsets := d.Get("subset").(*schema.Set).List()
+	if len(subsets) > 0 {
+		entry.Subsets = make(map[string]consulapi.ServiceResolverSubset, len(subsets))
+	}
+	for _, raw := range subsets {
+		subset := raw.(map[string]interface{})
+		name := subset["name"].(string)
+		if _, ok := entry.Subsets[name]; ok {
+			return fmt.Errorf("subset %q is declared more than once", name)
+		}
+		entry.Subsets[name] = consulapi.ServiceResolverSubset{
+			Filter:      subset["filter"].(string),
+			OnlyPassing: subset["only_passing"].(bool),
+		}
+	}
+
+	if redirect := d.Get("redirect").([]interface{}); len(redirect) > 0 && redirect[0] != nil {
+		r := redirect[0].(map[string]interface{})
+		entry.Redirect = &consulapi.ServiceResolverRedirect{
+			Service:       r["service"].(string),
+			ServiceSubset: r["service_subset"].(string),
+			Namespace:     r["namespace"].(string),
+			Partition:     r["partition"].(string),
+			Datacenter:    r["datacenter"].(string),
+			Peer:          r["peer"].(string),
+		}
+	}
+
+	failovers := d.Get("failover").(*schema.Set).List()
+	if len(failovers) > 0 {
+		entry.Failover = make(map[string]consulapi.ServiceResolverFailover, len(failovers))
+	}
+	for _, raw := range failovers {
+		f := raw.(map[string]interface{})
+		subsetName := f["subset_name"].(string)
+		if _, ok := entry.Failover[subsetName]; ok {
+			return fmt.Errorf("the failover of subset %q is declared more than once", subsetName)
+		}
+
+		failover := consulapi.ServiceResolverFailover{
+			Service:       f["service"].(string),
+			ServiceSubset: f["service_subset"].(string),
+			Namespace:     f["namespace"].(string),
+		}
+		for _, dc := range f["datacenters"].([]interface{}) {
+			failover.Datacenters = append(failover.Datacenters, dc.(string))
+		}
+		for _, rawTarget := range f["target"].([]interface{}) {
+			t := rawTarget.(map[string]interface{})
+			failover.Targets = append(failover.Targets, consulapi.ServiceResolverFailoverTarget{
+				Service:       t["service"].(string),
+				ServiceSubset: t["service_subset"].(string),
+				Partition:     t["partition"].(string),
+				Namespace:     t["namespace"].(string),
+				Datacenter:    t["datacenter"].(string),
+				Peer:          t["peer"].(string),
+			})
+		}
+		entry.Failover[subsetName] = failover
+	}
+
+	// The config entry is only written if it has not been changed since it was
+	// last read, an index of 0 means that it must not exist yet
+	var index uint64
+	if d.Id() != "" {
+		index = uint64(d.Get("modify_index").(int))
+	}
+	ok, _, err := configEntries.CAS(entry, index, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to set '%s' service resolver: %v", name, err)
+	}
+	if !ok {
+		if index == 0 {
+			return fmt.Errorf("failed to set '%s' service resolver: it already exists, import it to manage it with Terraform", name)
+		}
+		return fmt.Errorf("failed to set '%s' service resolver: it has been modified outside of Terraform since it was last read", name)
+	}
+
+	d.SetId(fmt.Sprintf("%s-%s", consulapi.ServiceResolver, name))
+
+	return resourceConsulServiceResolverRead(d, meta)
+}
+
+func resourceConsulServiceResolverRead(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+	name := d.Get("name").(string)
+
+	raw, _, err := client.ConfigEntries().Get(consulapi.ServiceResolver, name, qOpts)
+	if err != nil {
+		if errors.Is(newAPIError(err), ErrNotFound) {
+			// The config entry has been removed
+			d.SetId("")
+			return nil
+		}
+		return fmt.Errorf("failed to read '%s' service resolver: %v", name, err)
+	}
+	entry, ok := raw.(*consulapi.ServiceResolverConfigEntry)
+	if !ok {
+		return fmt.Errorf("unexpected config entry type %T for service resolver '%s'", raw, name)
+	}
+
+	subsets := make([]interface{}, 0, len(entry.Subsets))
+	for name, subset := range entry.Subsets {
+		subsets = append(subsets, map[string]interface{}{
+			"name":         name,
+			"filter":       subset.Filter,
+			"only_passing": subset.OnlyPassing,
+		})
+	}
+
+	redirect := []interface{}{}
+	if r := entry.Redirect; r != nil {
+		redirect = append(redirect, map[string]interface{}{
+			"service":        r.Service,
+			"service_subset": r.ServiceSubset,
+			"namespace":      r.Namespace,
+			"partition":      r.Partition,
+			"datacenter":     r.Datacenter,
+			"peer":           r.Peer,
+		})
+	}
+
+	failovers := make([]interface{}, 0, len(entry.Failover))
+	for subsetName, f := range entry.Failover {
+		targets := make([]interface{}, 0, len(f.Targets))
+		for _, t := range f.Targets {
+			targets = append(targets, map[string]interface{}{
+				"service":        t.Service,
+				"service_subset": t.ServiceSubset,
+				"partition":      t.Partition,
+				"namespace":      t.Namespace,
+				"datacenter":     t.Datacenter,
+				"peer":           t.Peer,
+			})
+		}
+		failovers = append(failovers, map[string]interface{}{
+			"subset_name":    subsetName,
+			"service":        f.Service,
+			"service_subset": f.ServiceSubset,
+			"namespace":      f.Namespace,
+			"datacenters":    f.Datacenters,
+			"target":         targets,
+		})
+	}
+
+	connectTimeout := ""
+	if entry.ConnectTimeout != 0 {
+		connectTimeout = entry.ConnectTimeout.String()
+	}
+
+	sw := newStateWriter(d)
+	sw.set("meta", entry.Meta)
+	sw.set("connect_timeout", connectTimeout)
+	sw.set("default_subset", entry.DefaultSubset)
+	sw.set("subset", subsets)
+	sw.set("redirect", redirect)
+	sw.set("failover", failovers)
+	sw.set("modify_index", int(entry.ModifyIndex))
+
+	return sw.error()
+}
+
+func resourceConsulServiceResolverDelete(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, wOpts := getClient(d, meta)
+	configEntries := client.ConfigEntries()
+	name := d.Get("name").(string)
+	index := uint64(d.Get("modify_index").(int))
+
+	ok, _, err := configEntries.DeleteCAS(consulapi.ServiceResolver, name, index, wOpts)
+	if err != nil {
+		return fmt.Errorf("failed to delete '%s' service resolver: %v", name, err)
+	}
+	if !ok {
+		// The CAS also fails when the config entry does not exist anymore
+		_, _, err := configEntries.Get(consulapi.ServiceResolver, name, qOpts)
+		if err == nil {
+			return fmt.Errorf("failed to delete '%s' service resolver: it has been modified outside of Terraform since it was last read", name)
+		}
+		if !errors.Is(newAPIError(err), ErrNotFound) {
+			return fmt.Errorf("failed to read '%s' service resolver: %v", name, err)
+		}
+	}
+	d.SetId("")
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulServiceResolver_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { skipTestOnConsulEnterpriseEdition(t) },
+		Providers:    providers,
+		CheckDestroy: testAccCheckConsulServiceResolverDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulServiceResolverConfig("v3"),
+				ExpectError: regexp.MustCompile(`default_subset "v3" is not a declared subset, expected one of \[v1 v2\]`),
+			},
+			{
+				Config: testAccConsulServiceResolverConfig("v1"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_service_resolver.web", "name", "web"),
+					resource.TestCheckResourceAttr("consul_service_resolver.web", "default_subset", "v1"),
+					resource.TestCheckResourceAttr("consul_service_resolver.web", "connect_timeout", "15s"),
+					resource.TestCheckResourceAttr("consul_service_resolver.web", "subset.#", "2"),
+					resource.TestCheckResourceAttr("consul_service_resolver.web", "failover.#", "1"),
+					resource.TestCheckResourceAttrSet("consul_service_resolver.web", "modify_index"),
+					func(s *terraform.State) error {
+						raw, _, err := client.ConfigEntries().Get(consulapi.ServiceResolver, "web", nil)
+						if err != nil {
+							return err
+						}
+						entry := raw.(*consulapi.ServiceResolverConfigEntry)
+						if entry.DefaultSubset != "v1" {
+							return fmt.Errorf("unexpected default subset %q", entry.DefaultSubset)
+						}
+						if entry.Subsets["v2"].Filter != `Service.Meta.version == "v2"` {
+							return fmt.Errorf("unexpected subsets: %#v", entry.Subsets)
+						}
+						failover := entry.Failover["*"]
+						if len(failover.Targets) != 1 || failover.Targets[0].Datacenter != "dc2" {
+							return fmt.Errorf("unexpected failover: %#v", entry.Failover)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: testAccConsulServiceResolverConfig("v2"),
+				Check:  resource.TestCheckResourceAttr("consul_service_resolver.web", "default_subset", "v2"),
+			},
+			{
+				// A change made outside of Terraform is detected
+				PreConfig: func() {
+					raw, _, err := client.ConfigEntries().Get(consulapi.ServiceResolver, "web", nil)
+					if err != nil {
+						t.Fatalf("err: %v", err)
+					}
+					entry := raw.(*consulapi.ServiceResolverConfigEntry)
+					entry.DefaultSubset = "v1"
+					if _, _, err := client.ConfigEntries().Set(entry, nil); err != nil {
+						t.Fatalf("err: %v", err)
+					}
+				},
+				Config:             testAccConsulServiceResolverConfig("v2"),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testAccConsulServiceResolverConfig("v2"),
+			},
+			{
+				Config:            testAccConsulServiceResolverConfig("v2"),
+				ResourceName:      "consul_service_resolver.web",
+				ImportState:       true,
+				ImportStateId:     "web",
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccCheckConsulServiceResolverDestroy(client *consulapi.Client) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		_, _, err := client.ConfigEntries().Get(consulapi.ServiceResolver, "web", nil)
+		if err == nil {
+			return fmt.Errorf("service resolver web still exists")
+		}
+		return nil
+	}
+}
+
+func testAccConsulServiceResolverConfig(defaultSubset string) string {
+	return fmt.Sprintf(`
+resource "consul_service_resolver" "web" {
+  name            = "web"
+  connect_timeout = "15s"
+  default_subset  = "%s"
+
+  subset {
+    name         = "v1"
+    filter       = "Service.Meta.version == \"v1\""
+    only_passing = true
+  }
+
+  subset {
+    name   = "v2"
+    filter = "Service.Meta.version == \"v2\""
+  }
+
+  failover {
+    subset_name = "*"
+
+    target {
+      datacenter = "dc2"
+    }
+  }
+}
+`, defaultSubset)
+}
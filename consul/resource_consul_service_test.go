@@ -6,6 +6,8 @@ package consul
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -210,6 +212,57 @@ func TestAccConsulServiceCheck(t *testing.T) {
 	})
 }
 
+func TestAccConsulServiceCheck_types(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	checkIDs := func(expected ...string) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			checks, _, err := client.Health().Checks("checks", nil)
+			if err != nil {
+				return err
+			}
+			ids := make([]string, 0, len(checks))
+			for _, check := range checks {
+				ids = append(ids, check.CheckID)
+			}
+			sort.Strings(ids)
+			if strings.Join(ids, ",") != strings.Join(expected, ",") {
+				return fmt.Errorf("unexpected checks %v", ids)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		CheckDestroy: resource.ComposeTestCheckFunc(
+			testAccCheckConsulServiceDestroy(client),
+			checkIDs(),
+		),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulServiceCheckTypes,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_service.checks", "check.#", "3"),
+					checkIDs("grpc", "tcp", "ttl"),
+				),
+			},
+			{
+				// The checks removed from the configuration are deregistered
+				Config: testAccConsulServiceCheckTypesUpdated,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_service.checks", "check.#", "1"),
+					checkIDs("grpc"),
+				),
+			},
+			{
+				Config:      testAccConsulServiceCheckTypesInvalid,
+				ExpectError: regexp.MustCompile("interval and timeout must be set for check 'tcp'"),
+			},
+		},
+	})
+}
+
 func TestAccConsulServiceCheckOrder(t *testing.T) {
 	providers, _ := startTestServer(t)
 
@@ -540,6 +593,83 @@ address = "www.example.com"
 }
 `
 
+const testAccConsulServiceCheckTypes = `
+resource "consul_node" "checks" {
+  name    = "checks"
+  address = "www.example.com"
+}
+
+resource "consul_service" "checks" {
+  name = "checks"
+  node = consul_node.checks.name
+  port = 80
+
+  check {
+    check_id = "tcp"
+    name     = "TCP check"
+    tcp      = "www.example.com:80"
+    interval = "5s"
+    timeout  = "1s"
+  }
+
+  check {
+    check_id     = "grpc"
+    name         = "gRPC check"
+    grpc         = "www.example.com:8502"
+    grpc_use_tls = true
+    interval     = "10s"
+    timeout      = "2s"
+  }
+
+  check {
+    check_id = "ttl"
+    name     = "TTL check"
+    status   = "passing"
+  }
+}
+`
+
+const testAccConsulServiceCheckTypesUpdated = `
+resource "consul_node" "checks" {
+  name    = "checks"
+  address = "www.example.com"
+}
+
+resource "consul_service" "checks" {
+  name = "checks"
+  node = consul_node.checks.name
+  port = 80
+
+  check {
+    check_id     = "grpc"
+    name         = "gRPC check"
+    grpc         = "www.example.com:8502"
+    grpc_use_tls = true
+    interval     = "10s"
+    timeout      = "2s"
+  }
+}
+`
+
+const testAccConsulServiceCheckTypesInvalid = `
+resource "consul_node" "checks" {
+  name    = "checks"
+  address = "www.example.com"
+}
+
+resource "consul_service" "checks" {
+  name = "checks"
+  node = consul_node.checks.name
+  port = 80
+
+  check {
+    check_id = "tcp"
+    name     = "TCP check"
+    tcp      = "www.example.com:80"
+  }
+}
+`
+
 const testAccConsulServiceConfigBasic = `
 resource "consul_service" "example" {
 	name    = "example"
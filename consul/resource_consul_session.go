@@ -0,0 +1,175 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+func resourceConsulSession() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulSessionCreate,
+		Read:   resourceConsulSessionRead,
+		Delete: resourceConsulSessionDelete,
+
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Description: "The name of the session.",
+			},
+
+			"node": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The node the session is associated with. Defaults to the node of the agent.",
+			},
+
+			"ttl": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				ValidateFunc:     validateDurationMinFactory("ttl", "10s"),
+				DiffSuppressFunc: diffDuration,
+				Description:      "The duration after which the session is invalidated if it is not renewed, between 10s and 24h. Sessions without a TTL never expire.",
+			},
+
+			"lock_delay": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				ValidateFunc:     validateDurationMinFactory("lock_delay", "0s"),
+				DiffSuppressFunc: diffDuration,
+				Description:      "The time during which the locks held by the session cannot be acquired after it has been invalidated. Defaults to 15s.",
+			},
+
+			"behavior": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      consulapi.SessionBehaviorRelease,
+				ValidateFunc: validation.StringInSlice([]string{consulapi.SessionBehaviorRelease, consulapi.SessionBehaviorDelete}, false),
+				Description:  "What to do with the locks held by the session when it is invalidated, either `release` or `delete`.",
+			},
+
+			"checks": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "The IDs of the node checks that must be passing for the session to stay valid. Defaults to the `serfHealth` check.",
+			},
+
+			"datacenter": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The datacenter to use. This overrides the agent's default datacenter and the datacenter in the provider setup.",
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func resourceConsulSessionCreate(d *schema.ResourceData, meta interface{}) error {
+	sessions := newSessionClient(d, meta)
+
+	entry := &consulapi.SessionEntry{
+		Name:     d.Get("name").(string),
+		Node:     d.Get("node").(string),
+		TTL:      d.Get("ttl").(string),
+		Behavior: d.Get("behavior").(string),
+	}
+	if v, ok := d.GetOk("lock_delay"); ok {
+		// The duration has already been validated by the schema
+		entry.LockDelay, _ = time.ParseDuration(v.(string))
+	}
+	if v, ok := d.GetOk("checks"); ok {
+		for _, check := range v.(*schema.Set).List() {
+			entry.NodeChecks = append(entry.NodeChecks, check.(string))
+		}
+	}
+
+	id, err := sessions.Create(entry)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	return resourceConsulSessionRead(d, meta)
+}
+
+func resourceConsulSessionRead(d *schema.ResourceData, meta interface{}) error {
+	sessions := newSessionClient(d, meta)
+
+	entry, err := sessions.Info(d.Id())
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		// The session has been destroyed or its TTL has expired
+		d.SetId("")
+		return nil
+	}
+
+	checks := entry.NodeChecks
+	if len(checks) == 0 {
+		// Consul versions before 1.7.0 only return the Checks attribute
+		checks = entry.Checks
+	}
+
+	sw := newStateWriter(d)
+	sw.set("name", entry.Name)
+	sw.set("node", entry.Node)
+	sw.set("ttl", entry.TTL)
+	sw.set("lock_delay", entry.LockDelay.String())
+	sw.set("behavior", entry.Behavior)
+	sw.set("checks", checks)
+	sw.set("datacenter", sessions.qOpts.Datacenter)
+	sw.set("namespace", entry.Namespace)
+
+	return sw.error()
+}
+
+func resourceConsulSessionDelete(d *schema.ResourceData, meta interface{}) error {
+	sessions := newSessionClient(d, meta)
+
+	if err := sessions.Destroy(d.Id()); err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// diffDuration suppresses the diff between two strings representing the same
+// duration, like "1m" and "60s".
+func diffDuration(k, old, new string, d *schema.ResourceData) bool {
+	return sameDuration(old, new)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulSession_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	var sessionID string
+
+	resource.Test(t, resource.TestCase{
+		Providers:    providers,
+		CheckDestroy: testAccConsulSessionCheckDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulSessionBasic,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_session.test", "name", "test-lock"),
+					resource.TestCheckResourceAttr("consul_session.test", "ttl", "60s"),
+					resource.TestCheckResourceAttr("consul_session.test", "lock_delay", "15s"),
+					resource.TestCheckResourceAttr("consul_session.test", "behavior", "delete"),
+					resource.TestCheckResourceAttr("consul_session.test", "checks.#", "1"),
+					resource.TestCheckResourceAttr("consul_session.test", "datacenter", "dc1"),
+					resource.TestCheckResourceAttrSet("consul_session.test", "node"),
+					func(s *terraform.State) error {
+						sessionID = s.RootModule().Resources["consul_session.test"].Primary.ID
+						return nil
+					},
+				),
+			},
+			{
+				// The session is recreated when it has been invalidated
+				PreConfig: func() {
+					if _, err := client.Session().Destroy(sessionID, nil); err != nil {
+						t.Fatalf("failed to destroy session: %v", err)
+					}
+				},
+				Config: testAccConsulSessionBasic,
+				Check: func(s *terraform.State) error {
+					if s.RootModule().Resources["consul_session.test"].Primary.ID == sessionID {
+						return fmt.Errorf("session %q should have been replaced", sessionID)
+					}
+					return nil
+				},
+			},
+			{
+				Config:            testAccConsulSessionBasic,
+				ResourceName:      "consul_session.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func testAccConsulSessionCheckDestroy(client *consulapi.Client) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "consul_session" {
+				continue
+			}
+
+			entry, _, err := client.Session().Info(rs.Primary.ID, nil)
+			if err != nil {
+				return fmt.Errorf("failed to read session %q: %v", rs.Primary.ID, err)
+			}
+			if entry != nil {
+				return fmt.Errorf("session %q still exists", rs.Primary.ID)
+			}
+		}
+		return nil
+	}
+}
+
+const testAccConsulSessionBasic = `
+resource "consul_session" "test" {
+  name       = "test-lock"
+  ttl        = "60s"
+  lock_delay = "15s"
+  behavior   = "delete"
+}
+`
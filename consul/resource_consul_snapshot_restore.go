@@ -0,0 +1,141 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+var errSnapshotRestoreNotConfirmed = errors.New("restoring a snapshot replaces all the data of the cluster, confirm must be set to true")
+
+// resourceConsulSnapshotRestore restores a snapshot saved by the
+// consul_snapshot data source or the Consul CLI. The snapshot is restored
+// once when the resource is created, destroying the resource does nothing.
+func resourceConsulSnapshotRestore() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulSnapshotRestoreCreate,
+		Read:   resourceConsulSnapshotRestoreRead,
+		Delete: resourceConsulSnapshotRestoreDelete,
+
+		CustomizeDiff: resourceConsulSnapshotRestoreCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"source_path": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The local path of the snapshot to restore.",
+			},
+
+			"sha256": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "The expected SHA-256 checksum of the snapshot, it is checked before the snapshot is restored.",
+			},
+
+			"confirm": {
+				Type:        schema.TypeBool,
+				Required:    true,
+				ForceNew:    true,
+				Description: "Must be set to `true` to acknowledge that the data of the cluster is replaced by the snapshot.",
+			},
+		},
+	}
+}
+
+func resourceConsulSnapshotRestoreCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" && d.NewValueKnown("confirm") && !d.Get("confirm").(bool) {
+		return errSnapshotRestoreNotConfirmed
+	}
+	return nil
+}
+
+func resourceConsulSnapshotRestoreCreate(d *schema.ResourceData, meta interface{}) error {
+	client, _, wOpts := getClient(d, meta)
+
+	if !d.Get("confirm").(bool) {
+		return errSnapshotRestoreNotConfirmed
+	}
+
+	path := d.Get("source_path").(string)
+
+	// The checksum is computed first so that a corrupted or unexpected file
+	// is never sent to Consul
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if expected := d.Get("sha256").(string); expected != "" && expected != sum {
+		return fmt.Errorf("the checksum of snapshot %q is %s but %s was expected", path, sum, expected)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot %q: %v", path, err)
+	}
+	defer file.Close()
+
+	log.Printf("[WARN] Restoring snapshot %q in datacenter %q", path, wOpts.Datacenter)
+	if err := client.Snapshot().Restore(wOpts, file); err != nil {
+		return fmt.Errorf("failed to restore snapshot %q: %v", path, err)
+	}
+
+	d.SetId(resource.UniqueId())
+
+	sw := newStateWriter(d)
+	sw.set("sha256", sum)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", wOpts.Datacenter)
+
+	return sw.error()
+}
+
+// resourceConsulSnapshotRestoreRead does nothing, the snapshot is only
+// restored once.
+func resourceConsulSnapshotRestoreRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+// resourceConsulSnapshotRestoreDelete only removes the resource from the
+// state, the data of the cluster is left as it is.
+func resourceConsulSnapshotRestoreDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+// fileSHA256 returns the SHA-256 checksum of the file at path without
+// reading it in memory all at once.
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open snapshot %q: %v", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read snapshot %q: %v", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
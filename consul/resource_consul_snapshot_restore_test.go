@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+func TestAccConsulSnapshotRestore_basic(t *testing.T) {
+	providers, _ := startTestServer(t)
+
+	path := filepath.Join(t.TempDir(), "consul.snap")
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccConsulSnapshotRestoreConfig(path, false),
+				ExpectError: regexp.MustCompile("confirm must be set to true"),
+			},
+			{
+				Config: testAccConsulSnapshotRestoreConfig(path, true),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_snapshot_restore.restore", "datacenter", "dc1"),
+					resource.TestCheckResourceAttrSet("consul_snapshot_restore.restore", "sha256"),
+				),
+			},
+		},
+	})
+}
+
+func testAccConsulSnapshotRestoreConfig(path string, confirm bool) string {
+	return fmt.Sprintf(`
+data "consul_snapshot" "backup" {
+  output_path = %q
+}
+
+resource "consul_snapshot_restore" "restore" {
+  source_path = %q
+  confirm     = %t
+
+  depends_on = [data.consul_snapshot.backup]
+}`, path, path, confirm)
+}
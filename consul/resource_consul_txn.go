@@ -0,0 +1,350 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	txnOpKVSet      = "kv_set"
+	txnOpKVDelete   = "kv_delete"
+	txnOpKVCAS      = "kv_cas"
+	txnOpServiceSet = "service_set"
+	txnOpCheckSet   = "check_set"
+)
+
+func resourceConsulTxn() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceConsulTxnCreateUpdate,
+		Update: resourceConsulTxnCreateUpdate,
+		Read:   resourceConsulTxnRead,
+		Delete: resourceConsulTxnDelete,
+
+		Schema: map[string]*schema.Schema{
+			"operation": {
+				Type:        schema.TypeList,
+				Required:    true,
+				MinItems:    1,
+				MaxItems:    maxTxnOps,
+				Description: "The operations to submit in the transaction, in order.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice([]string{txnOpKVSet, txnOpKVDelete, txnOpKVCAS, txnOpServiceSet, txnOpCheckSet}, false),
+							Description:  "The type of the operation, one of `kv_set`, `kv_delete`, `kv_cas`, `service_set` or `check_set`.",
+						},
+
+						"key": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The path of the key for the `kv_*` operations.",
+						},
+
+						"value": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The value to write for the `kv_set` and `kv_cas` operations.",
+						},
+
+						"flags": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Description: "The flags to write for the `kv_set` and `kv_cas` operations.",
+						},
+
+						"index": {
+							Type:        schema.TypeInt,
+							Optional:    true,
+							Description: "The modify index the key must have for the `kv_cas` operation to succeed.",
+						},
+
+						"node": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "The node of the service or of the check for the `service_set` and `check_set` operations.",
+						},
+
+						"service": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							MaxItems:    1,
+							Description: "The service to register for the `service_set` operation.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"id": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"name": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"address": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"port": {
+										Type:     schema.TypeInt,
+										Optional: true,
+									},
+									"tags": {
+										Type:     schema.TypeList,
+										Optional: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+								},
+							},
+						},
+
+						"check": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							MaxItems:    1,
+							Description: "The check to register for the `check_set` operation.",
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"check_id": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"name": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"status": {
+										Type:         schema.TypeString,
+										Optional:     true,
+										Default:      consulapi.HealthCritical,
+										ValidateFunc: validation.StringInSlice([]string{consulapi.HealthPassing, consulapi.HealthWarning, consulapi.HealthCritical}, false),
+									},
+									"service_id": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+									"notes": {
+										Type:     schema.TypeString,
+										Optional: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+
+			"results": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "The result of each operation, in the same order as `operation`.",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"create_index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"modify_index": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+
+			"datacenter": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+
+			"namespace": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+
+			"partition": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+		},
+	}
+}
+
+func resourceConsulTxnCreateUpdate(d *schema.ResourceData, meta interface{}) error {
+	client, qOpts, _ := getClient(d, meta)
+
+	operations := d.Get("operation").([]interface{})
+	ops := make(consulapi.TxnOps, 0, len(operations))
+	types := make([]string, 0, len(operations))
+	for i, raw := range operations {
+		op, err := getTxnOp(raw.(map[string]interface{}), qOpts)
+		if err != nil {
+			return fmt.Errorf("invalid operation %d: %v", i, err)
+		}
+		ops = append(ops, op)
+		types = append(types, raw.(map[string]interface{})["type"].(string))
+	}
+
+	log.Printf("[DEBUG] Submitting a transaction with %d operations in %s", len(ops), qOpts.Datacenter)
+	ok, resp, _, err := client.Txn().Txn(ops, qOpts)
+	if err != nil {
+		return fmt.Errorf("failed to submit transaction: %v", enterpriseFeatureError(qOpts, err))
+	}
+	if !ok {
+		errors := make([]string, 0, len(resp.Errors))
+		for _, e := range resp.Errors {
+			errors = append(errors, fmt.Sprintf("operation %d (%s): %s", e.OpIndex, types[e.OpIndex], e.What))
+		}
+		return fmt.Errorf("the transaction has been rolled back: %s", strings.Join(errors, ", "))
+	}
+
+	// Consul only returns a result for the operations that write something,
+	// we align them with the operations so that results[i] matches operation i
+	results := make([]interface{}, 0, len(ops))
+	next := 0
+	for _, typ := range types {
+		var createIndex, modifyIndex uint64
+		if typ != txnOpKVDelete && next < len(resp.Results) {
+			result := resp.Results[next]
+			next++
+			switch {
+			case result.KV != nil:
+				createIndex, modifyIndex = result.KV.CreateIndex, result.KV.ModifyIndex
+			case result.Service != nil:
+				createIndex, modifyIndex = result.Service.CreateIndex, result.Service.ModifyIndex
+			case result.Check != nil:
+				createIndex, modifyIndex = result.Check.CreateIndex, result.Check.ModifyIndex
+			}
+		}
+		results = append(results, map[string]interface{}{
+			"type":         typ,
+			"create_index": int(createIndex),
+			"modify_index": int(modifyIndex),
+		})
+	}
+
+	if d.Id() == "" {
+		d.SetId(resource.UniqueId())
+	}
+
+	sw := newStateWriter(d)
+	sw.set("results", results)
+
+	// Store the datacenter on this resource, which can be helpful for reference
+	// in case it was read from the provider
+	sw.set("datacenter", qOpts.Datacenter)
+
+	return sw.error()
+}
+
+// resourceConsulTxnRead does nothing, a transaction cannot be read back from
+// Consul and the objects it wrote are not managed by this resource.
+func resourceConsulTxnRead(d *schema.ResourceData, meta interface{}) error {
+	return nil
+}
+
+// resourceConsulTxnDelete only removes the resource from the state, the
+// operations of the transaction are not reverted.
+func resourceConsulTxnDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+func getTxnOp(op map[string]interface{}, qOpts *consulapi.QueryOptions) (*consulapi.TxnOp, error) {
+	typ := op["type"].(string)
+	key := op["key"].(string)
+	node := op["node"].(string)
+
+	switch typ {
+	case txnOpKVSet, txnOpKVDelete, txnOpKVCAS:
+		if key == "" {
+			return nil, fmt.Errorf("key must be set for %s", typ)
+		}
+		kv := &consulapi.KVTxnOp{
+			Key:       key,
+			Namespace: qOpts.Namespace,
+			Partition: qOpts.Partition,
+		}
+		switch typ {
+		case txnOpKVSet:
+			kv.Verb = consulapi.KVSet
+		case txnOpKVDelete:
+			kv.Verb = consulapi.KVDelete
+		case txnOpKVCAS:
+			kv.Verb = consulapi.KVCAS
+			kv.Index = uint64(op["index"].(int))
+		}
+		if typ != txnOpKVDelete {
+			kv.Value = []byte(op["value"].(string))
+			kv.Flags = uint64(op["flags"].(int))
+		}
+		return &consulapi.TxnOp{KV: kv}, nil
+
+	case txnOpServiceSet:
+		services := op["service"].([]interface{})
+		if node == "" || len(services) == 0 || services[0] == nil {
+			return nil, fmt.Errorf("node and service must be set for %s", typ)
+		}
+		s := services[0].(map[string]interface{})
+		tags := make([]string, 0)
+		for _, tag := range s["tags"].([]interface{}) {
+			tags = append(tags, tag.(string))
+		}
+		return &consulapi.TxnOp{
+			Service: &consulapi.ServiceTxnOp{
+				Verb: consulapi.ServiceSet,
+				Node: node,
+				Service: consulapi.AgentService{
+					ID:        s["id"].(string),
+					Service:   s["name"].(string),
+					Address:   s["address"].(string),
+					Port:      s["port"].(int),
+					Tags:      tags,
+					Namespace: qOpts.Namespace,
+					Partition: qOpts.Partition,
+				},
+			},
+		}, nil
+
+	case txnOpCheckSet:
+		checks := op["check"].([]interface{})
+		if node == "" || len(checks) == 0 || checks[0] == nil {
+			return nil, fmt.Errorf("node and check must be set for %s", typ)
+		}
+		c := checks[0].(map[string]interface{})
+		return &consulapi.TxnOp{
+			Check: &consulapi.CheckTxnOp{
+				Verb: consulapi.CheckSet,
+				Check: consulapi.HealthCheck{
+					Node:      node,
+					CheckID:   c["check_id"].(string),
+					Name:      c["name"].(string),
+					Status:    c["status"].(string),
+					ServiceID: c["service_id"].(string),
+					Notes:     c["notes"].(string),
+					Namespace: qOpts.Namespace,
+					Partition: qOpts.Partition,
+				},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operation type %q", typ)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestAccConsulTxn_basic(t *testing.T) {
+	providers, client := startTestServer(t)
+
+	resource.Test(t, resource.TestCase{
+		Providers: providers,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccConsulTxnConfig,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("consul_txn.test", "results.#", "4"),
+					resource.TestCheckResourceAttr("consul_txn.test", "results.0.type", "kv_set"),
+					resource.TestCheckResourceAttrSet("consul_txn.test", "results.0.modify_index"),
+					resource.TestCheckResourceAttr("consul_txn.test", "results.1.type", "kv_delete"),
+					resource.TestCheckResourceAttr("consul_txn.test", "results.1.modify_index", "0"),
+					resource.TestCheckResourceAttr("consul_txn.test", "results.2.type", "service_set"),
+					resource.TestCheckResourceAttrSet("consul_txn.test", "results.2.modify_index"),
+					resource.TestCheckResourceAttr("consul_txn.test", "results.3.type", "check_set"),
+					resource.TestCheckResourceAttrSet("consul_txn.test", "results.3.modify_index"),
+					func(s *terraform.State) error {
+						pair, _, err := client.KV().Get("test/txn/enabled", nil)
+						if err != nil {
+							return err
+						}
+						if pair == nil || string(pair.Value) != "true" || pair.Flags != 4 {
+							return fmt.Errorf("unexpected key %#v", pair)
+						}
+
+						checks, _, err := client.Health().Checks("web", nil)
+						if err != nil {
+							return err
+						}
+						if len(checks) != 1 || checks[0].Status != consulapi.HealthPassing {
+							return fmt.Errorf("unexpected checks %#v", checks)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// A failed operation rolls back the whole transaction
+				Config:      testAccConsulTxnConfigRollback,
+				ExpectError: regexp.MustCompile(`the transaction has been rolled back: operation 1 \(kv_cas\)`),
+			},
+			{
+				Config: testAccConsulTxnConfig,
+				Check: func(s *terraform.State) error {
+					pair, _, err := client.KV().Get("test/txn/rollback", nil)
+					if err != nil {
+						return err
+					}
+					if pair != nil {
+						return fmt.Errorf("key 'test/txn/rollback' should not exist")
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestGetTxnOp(t *testing.T) {
+	qOpts := &consulapi.QueryOptions{}
+
+	op, err := getTxnOp(map[string]interface{}{
+		"type":    "kv_cas",
+		"key":     "foo",
+		"value":   "bar",
+		"flags":   2,
+		"index":   10,
+		"node":    "",
+		"service": []interface{}{},
+		"check":   []interface{}{},
+	}, qOpts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if op.KV == nil || op.KV.Verb != consulapi.KVCAS || op.KV.Index != 10 || string(op.KV.Value) != "bar" || op.KV.Flags != 2 {
+		t.Fatalf("unexpected operation %#v", op.KV)
+	}
+
+	_, err = getTxnOp(map[string]interface{}{
+		"type":    "service_set",
+		"key":     "",
+		"node":    "",
+		"service": []interface{}{},
+		"check":   []interface{}{},
+	}, qOpts)
+	if err == nil || err.Error() != "node and service must be set for service_set" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+const testAccConsulTxnConfig = `
+resource "consul_node" "web" {
+  name    = "web"
+  address = "www.example.com"
+}
+
+resource "consul_txn" "test" {
+  operation {
+    type  = "kv_set"
+    key   = "test/txn/enabled"
+    value = "true"
+    flags = 4
+  }
+
+  operation {
+    type = "kv_delete"
+    key  = "test/txn/disabled"
+  }
+
+  operation {
+    type = "service_set"
+    node = consul_node.web.name
+
+    service {
+      id   = "web-1"
+      name = "web"
+      port = 80
+    }
+  }
+
+  operation {
+    type = "check_set"
+    node = consul_node.web.name
+
+    check {
+      check_id   = "web-1-alive"
+      name       = "web alive"
+      status     = "passing"
+      service_id = "web-1"
+    }
+  }
+}
+`
+
+const testAccConsulTxnConfigRollback = `
+resource "consul_node" "web" {
+  name    = "web"
+  address = "www.example.com"
+}
+
+resource "consul_txn" "test" {
+  operation {
+    type  = "kv_set"
+    key   = "test/txn/rollback"
+    value = "true"
+  }
+
+  operation {
+    type  = "kv_cas"
+    key   = "test/txn/enabled"
+    value = "false"
+    index = 1
+  }
+}
+`
@@ -4,15 +4,21 @@
 package consul
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 	"github.com/mitchellh/mapstructure"
 )
@@ -29,7 +35,7 @@ func deprecated(name string, resource *schema.Resource) *schema.Resource {
 
 // Provider returns a terraform.ResourceProvider.
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
+	provider := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"datacenter": {
 				Type:        schema.TypeString,
@@ -37,6 +43,20 @@ func Provider() terraform.ResourceProvider {
 				Description: "The datacenter to use. Defaults to that of the agent.",
 			},
 
+			"skip_connection_check": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether to skip the check made when the provider is configured that Consul can be reached and accepts the ACL token.",
+			},
+
+			"validate_datacenters": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to check that the datacenters used by the provider and by the resources are known to Consul.",
+			},
+
 			"address": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -44,7 +64,7 @@ func Provider() terraform.ResourceProvider {
 					"CONSUL_ADDRESS",
 					"CONSUL_HTTP_ADDR",
 				}, "localhost:8500"),
-				Description: `The HTTP(S) API address of the agent to use. Defaults to "127.0.0.1:8500".`,
+				Description: `The HTTP(S) API address of the agent to use. Defaults to "127.0.0.1:8500". A Unix domain socket can be used with an address like "unix:///var/run/consul.sock".`,
 			},
 
 			"scheme": {
@@ -134,6 +154,20 @@ func Provider() terraform.ResourceProvider {
 				Description: "The ACL token to use by default when making requests to the agent. Can also be specified with `CONSUL_HTTP_TOKEN` or `CONSUL_TOKEN` as an environment variable.",
 			},
 
+			"token_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "A path to a file containing the ACL token to use by default. The file is read again when Consul rejects the token so that it can be rotated during a run. It is ignored when `token` is set.",
+			},
+
+			"encryption_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("CONSUL_ENCRYPTION_KEY", nil),
+				Description: "The base64 encoded 32 bytes key used to encrypt the values of the `consul_keys` keys that set `encrypt`. Can also be specified with the `CONSUL_ENCRYPTION_KEY` environment variable.",
+			},
+
 			"auth_jwt": {
 				Type:        schema.TypeList,
 				Optional:    true,
@@ -149,8 +183,14 @@ func Provider() terraform.ResourceProvider {
 						"bearer_token": {
 							Type:        schema.TypeString,
 							Optional:    true,
+							Sensitive:   true,
 							Description: "The bearer token to present to the auth method during login for authentication purposes. For the Kubernetes auth method this is a [Service Account Token (JWT)](https://kubernetes.io/docs/reference/access-authn-authz/authentication/#service-account-tokens).",
 						},
+						"bearer_token_file": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "A path to a file containing the bearer token to present to the auth method during login, like `/var/run/secrets/kubernetes.io/serviceaccount/token` for the Kubernetes auth method.",
+						},
 						"use_terraform_cloud_workload_identity": {
 							Type:        schema.TypeBool,
 							Optional:    true,
@@ -173,6 +213,113 @@ func Provider() terraform.ResourceProvider {
 				Optional: true,
 			},
 
+			"partition": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The admin partition to use by default for the resources and data sources that do not set one explicitly. This is a Consul Enterprise feature.",
+			},
+
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validateIntMinFactory("max_retries", 0),
+				Description:  "The maximum number of times a request to the key/value store is retried when Consul returns a 5xx error or refuses the connection, for example during a leader election. The check-and-set and transaction requests are only retried when the connection is refused since Consul may have applied them before returning an error. Defaults to 0.",
+			},
+
+			"read_cache": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the identical reads of the key/value store made by the data sources are collapsed into a single request to Consul.",
+			},
+
+			"read_cache_ttl": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5s",
+				ValidateFunc: validateDurationMinFactory("read_cache_ttl", "0s"),
+				Description:  `The time after which a read cached with read_cache is sent to Consul again. Defaults to "5s".`,
+			},
+
+			"retry_wait_min": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "1s",
+				ValidateFunc: validateDurationMinFactory("retry_wait_min", "0s"),
+				Description:  `The time to wait before the first retry, it is doubled after each attempt. Defaults to "1s".`,
+			},
+
+			"retry_wait_max": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "30s",
+				ValidateFunc: validateDurationMinFactory("retry_wait_max", "0s"),
+				Description:  `The maximum time to wait between two retries. Defaults to "30s".`,
+			},
+
+			"operation_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "2m",
+				ValidateFunc: validateDurationMinFactory("operation_timeout", "0s"),
+				Description:  `The maximum time to wait for Consul to answer a request made to the key/value store before aborting it, the wait time of the blocking queries is added to it. Only the idempotent requests are retried after a timeout. Defaults to "2m", "0s" disables the timeout.`,
+			},
+
+			"default_kv_flags": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The flags set on the keys written by the resources that do not set flags themselves. The flags already stored on existing keys are not considered as drift. Defaults to 0.",
+			},
+
+			"kv_max_value_bytes": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      512 * 1024,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "The maximum size in bytes of the values written to the key/value store, the larger values are rejected before being sent to Consul. It must match the `kv_max_value_size` limit of the Consul servers. Defaults to 524288, the default limit of Consul, 0 disables the check.",
+			},
+
+			"metrics_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The path of a file where the number of requests made to the key/value store and their durations are written in the Prometheus text format once Terraform is done with the provider.",
+			},
+
+			"writes_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "The maximum number of write requests per second sent to the key/value store, shared by all the resources. Defaults to 0, which means no limit.",
+			},
+
+			"reads_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				Default:      0,
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "The maximum number of read requests per second sent to the key/value store, shared by all the resources and data sources. Defaults to 0, which means no limit.",
+			},
+
+			"http_max_idle_conns": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      100,
+				ValidateFunc: validateIntMinFactory("http_max_idle_conns", 0),
+				Description:  "The maximum number of idle connections to Consul kept open to be reused by the following requests. Defaults to 100.",
+			},
+
+			"http_idle_conn_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "90s",
+				ValidateFunc: validateDurationMinFactory("http_idle_conn_timeout", "0s"),
+				Description:  `The time after which an idle connection to Consul is closed. Defaults to "90s".`,
+			},
+
 			"header": {
 				Type:        schema.TypeList,
 				Optional:    true,
@@ -196,26 +343,32 @@ func Provider() terraform.ResourceProvider {
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"consul_agent_self":           dataSourceConsulAgentSelf(),
-			"consul_agent_config":         dataSourceConsulAgentConfig(),
-			"consul_autopilot_health":     dataSourceConsulAutopilotHealth(),
-			"consul_nodes":                dataSourceConsulNodes(),
-			"consul_service":              dataSourceConsulService(),
-			"consul_service_health":       dataSourceConsulServiceHealth(),
-			"consul_services":             dataSourceConsulServices(),
-			"consul_keys":                 dataSourceConsulKeys(),
-			"consul_key_prefix":           dataSourceConsulKeyPrefix(),
-			"consul_acl_auth_method":      dataSourceConsulACLAuthMethod(),
-			"consul_acl_policy":           dataSourceConsulACLPolicy(),
-			"consul_acl_role":             dataSourceConsulACLRole(),
-			"consul_acl_token":            dataSourceConsulACLToken(),
-			"consul_acl_token_secret_id":  dataSourceConsulACLTokenSecretID(),
-			"consul_network_segments":     dataSourceConsulNetworkSegments(),
-			"consul_network_area_members": dataSourceConsulNetworkAreaMembers(),
-			"consul_datacenters":          dataSourceConsulDatacenters(),
-			"consul_config_entry":         dataSourceConsulConfigEntry(),
-			"consul_peering":              dataSourceConsulPeering(),
-			"consul_peerings":             dataSourceConsulPeerings(),
+			"consul_agent_self":             dataSourceConsulAgentSelf(),
+			"consul_agent_config":           dataSourceConsulAgentConfig(),
+			"consul_autopilot_health":       dataSourceConsulAutopilotHealth(),
+			"consul_nodes":                  dataSourceConsulNodes(),
+			"consul_service":                dataSourceConsulService(),
+			"consul_service_health":         dataSourceConsulServiceHealth(),
+			"consul_services":               dataSourceConsulServices(),
+			"consul_keys":                   dataSourceConsulKeys(),
+			"consul_key_prefix":             dataSourceConsulKeyPrefix(),
+			"consul_keys_lookup":            dataSourceConsulKeysLookup(),
+			"consul_kv_metadata":            dataSourceConsulKVMetadata(),
+			"consul_kv_prefix":              dataSourceConsulKVPrefix(),
+			"consul_acl_auth_method":        dataSourceConsulACLAuthMethod(),
+			"consul_acl_policy":             dataSourceConsulACLPolicy(),
+			"consul_acl_role":               dataSourceConsulACLRole(),
+			"consul_acl_token":              dataSourceConsulACLToken(),
+			"consul_acl_token_secret_id":    dataSourceConsulACLTokenSecretID(),
+			"consul_network_segments":       dataSourceConsulNetworkSegments(),
+			"consul_network_area_members":   dataSourceConsulNetworkAreaMembers(),
+			"consul_datacenters":            dataSourceConsulDatacenters(),
+			"consul_config_entry":           dataSourceConsulConfigEntry(),
+			"consul_peering":                dataSourceConsulPeering(),
+			"consul_peerings":               dataSourceConsulPeerings(),
+			"consul_prepared_query_execute": dataSourceConsulPreparedQueryExecute(),
+			"consul_raft_configuration":     dataSourceConsulRaftConfiguration(),
+			"consul_snapshot":               dataSourceConsulSnapshot(),
 
 			// Aliases to limit the impact of rename of catalog
 			// datasources
@@ -237,8 +390,14 @@ func Provider() terraform.ResourceProvider {
 			"consul_catalog_entry":               resourceConsulCatalogEntry(),
 			"consul_certificate_authority":       resourceConsulCertificateAuthority(),
 			"consul_config_entry":                resourceConsulConfigEntry(),
+			"consul_service_resolver":            resourceConsulServiceResolver(),
+			"consul_service_intentions":          resourceConsulServiceIntentions(),
+			"consul_event":                       resourceConsulEvent(),
 			"consul_keys":                        resourceConsulKeys(),
 			"consul_key_prefix":                  resourceConsulKeyPrefix(),
+			"consul_keys_file":                   resourceConsulKeysFile(),
+			"consul_keys_import":                 resourceConsulKeysImport(),
+			"consul_kv_migration":                resourceConsulKVMigration(),
 			"consul_license":                     resourceConsulLicense(),
 			"consul_namespace":                   resourceConsulNamespace(),
 			"consul_namespace_policy_attachment": resourceConsulNamespacePolicyAttachment(),
@@ -247,13 +406,71 @@ func Provider() terraform.ResourceProvider {
 			"consul_prepared_query":              resourceConsulPreparedQuery(),
 			"consul_autopilot_config":            resourceConsulAutopilotConfig(),
 			"consul_service":                     resourceConsulService(),
+			"consul_session":                     resourceConsulSession(),
+			"consul_txn":                         resourceConsulTxn(),
 			"consul_intention":                   resourceConsulIntention(),
 			"consul_network_area":                resourceConsulNetworkArea(),
 			"consul_peering_token":               resourceSourceConsulPeeringToken(),
 			"consul_peering":                     resourceSourceConsulPeering(),
+			"consul_raft_peer":                   resourceConsulRaftPeer(),
+			"consul_snapshot_restore":            resourceConsulSnapshotRestore(),
 		},
+	}
+
+	provider.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
+		meta, err := providerConfigure(d)
+		if err != nil {
+			return nil, err
+		}
+
+		// The requests made to Consul are aborted when Terraform stops the
+		// provider, for example when the user cancels an apply
+		meta.(*Config).stopCtx = provider.StopContext()
+		return meta, nil
+	}
+
+	for _, r := range provider.ResourcesMap {
+		addDatacenterValidation(r)
+	}
+	for _, r := range provider.DataSourcesMap {
+		addDatacenterValidation(r)
+	}
+
+	return provider
+}
+
+// addDatacenterValidation makes the resource check its datacenter attribute
+// when validate_datacenters is set. This is done in CustomizeDiff for the
+// resources so that the error is reported during the plan.
+func addDatacenterValidation(r *schema.Resource) {
+	if _, ok := r.Schema["datacenter"]; !ok {
+		return
+	}
+
+	if r.Update != nil || r.Create != nil {
+		customizeDiff := r.CustomizeDiff
+		r.CustomizeDiff = func(d *schema.ResourceDiff, meta interface{}) error {
+			if dc, ok := d.GetOk("datacenter"); ok {
+				if err := meta.(*Config).validateDatacenter(dc.(string)); err != nil {
+					return err
+				}
+			}
+			if customizeDiff != nil {
+				return customizeDiff(d, meta)
+			}
+			return nil
+		}
+		return
+	}
 
-		ConfigureFunc: providerConfigure,
+	read := r.Read
+	r.Read = func(d *schema.ResourceData, meta interface{}) error {
+		if dc, ok := d.GetOk("datacenter"); ok {
+			if err := meta.(*Config).validateDatacenter(dc.(string)); err != nil {
+				return err
+			}
+		}
+		return read(d, meta)
 	}
 }
 
@@ -270,8 +487,75 @@ func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 	}
 	config.client = client
 
-	// Set headers if provided
-	headers := d.Get("header").([]interface{})
+	// The durations have already been validated by the schema
+	waitMin, _ := time.ParseDuration(config.RetryWaitMin)
+	waitMax, _ := time.ParseDuration(config.RetryWaitMax)
+	if waitMax < waitMin {
+		return nil, fmt.Errorf("retry_wait_max (%s) must be greater than retry_wait_min (%s)", config.RetryWaitMax, config.RetryWaitMin)
+	}
+	config.retry = retryPolicy{
+		maxRetries: config.MaxRetries,
+		waitMin:    waitMin,
+		waitMax:    waitMax,
+	}
+	config.operationTimeout, _ = time.ParseDuration(config.OperationTimeout)
+
+	// The limiters are shared by all the key clients so that the rate is
+	// respected whatever the parallelism of Terraform
+	config.writeLimiter = newRateLimiter(config.WritesPerSecond)
+	config.readLimiter = newRateLimiter(config.ReadsPerSecond)
+
+	if config.ReadCache {
+		// The duration has already been validated by the schema
+		ttl, _ := time.ParseDuration(config.ReadCacheTTL)
+		config.readCache = newReadCache(ttl)
+	}
+
+	if config.MetricsFile != "" {
+		config.metrics = metricsForFile(config.MetricsFile)
+	}
+
+	setHeaders(client, d.Get("header").([]interface{}))
+
+	if config.DefaultKVFlags&kvFlagsReserved != 0 {
+		return nil, fmt.Errorf("default_kv_flags cannot use the bits %d and %d, they are reserved by the provider", kvFlagCompressed, kvFlagEncrypted)
+	}
+
+	if config.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(config.EncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption_key: %v", err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption_key must be 32 bytes long, got %d bytes", len(key))
+		}
+		config.encryptionKey = key
+	}
+
+	if err := config.validateDatacenter(config.Datacenter); err != nil {
+		return nil, err
+	}
+
+	authJWT := d.Get("auth_jwt").([]interface{})
+	if len(authJWT) > 0 {
+		if err := login(d, config, authJWT[0].(map[string]interface{})); err != nil {
+			return nil, err
+		}
+	}
+
+	// A wrong address or token is reported now rather than by the first
+	// resource using the provider, in the middle of the apply
+	if !config.SkipConnectionCheck {
+		if err := config.checkConnection(); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// setHeaders adds the headers set in the provider configuration to client.
+func setHeaders(client *consulapi.Client, headers []interface{}) {
 	parsedHeaders := client.Headers().Clone()
 
 	if parsedHeaders == nil {
@@ -283,51 +567,111 @@ func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 		parsedHeaders.Add(header["name"].(string), header["value"].(string))
 	}
 	client.SetHeaders(parsedHeaders)
+}
 
-	authJWT := d.Get("auth_jwt").([]interface{})
-	if len(authJWT) > 0 {
-		authConfig := authJWT[0].(map[string]interface{})
-		authMethod := authConfig["auth_method"].(string)
-		tfeWorkloadIdentity := authConfig["use_terraform_cloud_workload_identity"].(bool)
-		bearerToken := authConfig["bearer_token"].(string)
-
-		if tfeWorkloadIdentity {
-			bearerToken = os.Getenv("TFC_WORKLOAD_IDENTITY_TOKEN")
-			if bearerToken == "" {
-				return nil, fmt.Errorf("auth_jwt.use_terraform_cloud_workload_identity has been set but no token found in TFC_WORKLOAD_IDENTITY_TOKEN environment variable")
-			}
+// login uses the auth method configured in the auth_jwt block to get an ACL
+// token, the client of config is then replaced by one using this token.
+func login(d *schema.ResourceData, config *Config, authConfig map[string]interface{}) error {
+	authMethod := authConfig["auth_method"].(string)
+	tfeWorkloadIdentity := authConfig["use_terraform_cloud_workload_identity"].(bool)
+	bearerToken := authConfig["bearer_token"].(string)
+	bearerTokenFile := authConfig["bearer_token_file"].(string)
 
-		} else if bearerToken == "" {
-			return nil, fmt.Errorf("either auth_jwt.bearer_token or auth_jwt.use_terraform_cloud_workload_identity should be set")
-		}
+	if bearerToken != "" && bearerTokenFile != "" {
+		return fmt.Errorf("only one of auth_jwt.bearer_token and auth_jwt.bearer_token_file can be set")
+	}
 
-		meta := map[string]string{}
-		for k, v := range authConfig["meta"].(map[string]interface{}) {
-			meta[k] = v.(string)
+	if tfeWorkloadIdentity {
+		bearerToken = os.Getenv("TFC_WORKLOAD_IDENTITY_TOKEN")
+		if bearerToken == "" {
+			return fmt.Errorf("auth_jwt.use_terraform_cloud_workload_identity has been set but no token found in TFC_WORKLOAD_IDENTITY_TOKEN environment variable")
 		}
-		_, wOpts := getOptions(d, config)
-		token, _, err := client.ACL().Login(&consulapi.ACLLoginParams{
-			AuthMethod:  authMethod,
-			BearerToken: bearerToken,
-			Meta:        meta,
-		}, wOpts)
+	} else if bearerTokenFile != "" {
+		content, err := os.ReadFile(bearerTokenFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to login using JWT auth method %q: %v", authMethod, err)
+			return fmt.Errorf("failed to read auth_jwt.bearer_token_file: %v", err)
 		}
-		config.Token = token.SecretID
+		bearerToken = strings.TrimSpace(string(content))
+		if bearerToken == "" {
+			return fmt.Errorf("auth_jwt.bearer_token_file %q is empty", bearerTokenFile)
+		}
+	} else if bearerToken == "" {
+		return fmt.Errorf("either auth_jwt.bearer_token, auth_jwt.bearer_token_file or auth_jwt.use_terraform_cloud_workload_identity should be set")
 	}
 
-	return config, nil
+	meta := map[string]string{}
+	for k, v := range authConfig["meta"].(map[string]interface{}) {
+		meta[k] = v.(string)
+	}
+	_, wOpts := getOptions(d, config)
+	log.Printf("[INFO] Logging in to Consul using auth method %q", authMethod)
+	token, _, err := config.client.ACL().Login(&consulapi.ACLLoginParams{
+		AuthMethod:  authMethod,
+		BearerToken: bearerToken,
+		Meta:        meta,
+	}, wOpts)
+	if err != nil {
+		return loginError(authMethod, err)
+	}
+
+	config.Token = token.SecretID
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+	setHeaders(client, d.Get("header").([]interface{}))
+	config.client = client
+
+	// The logout request must use the token returned by the auth method
+	logoutOpts := *wOpts
+	logoutOpts.Token = ""
+	registerLogout(client, &logoutOpts)
+	return nil
 }
 
-func getClient(d *schema.ResourceData, meta interface{}) (*consulapi.Client, *consulapi.QueryOptions, *consulapi.WriteOptions) {
+// loginError tells apart the credentials rejected by the auth method from
+// the errors returned when Consul cannot be reached.
+func loginError(authMethod string, err error) error {
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("failed to login using JWT auth method %q, the credentials were rejected: %v", authMethod, err)
+		}
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("failed to login using JWT auth method %q, Consul could not be reached: %v", authMethod, err)
+	}
+
+	return fmt.Errorf("failed to login using JWT auth method %q: %v", authMethod, err)
+}
+
+// resourceGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff so that a client can also be created during a
+// CustomizeDiff function.
+type resourceGetter interface {
+	GetOk(key string) (interface{}, bool)
+}
+
+func getClient(d resourceGetter, meta interface{}) (*consulapi.Client, *consulapi.QueryOptions, *consulapi.WriteOptions) {
 	config := meta.(*Config)
 	client := config.client
 	qOpts, wOpts := getOptions(d, config)
 	return client, qOpts, wOpts
 }
 
-func getOptions(d *schema.ResourceData, meta interface{}) (*consulapi.QueryOptions, *consulapi.WriteOptions) {
+// stopContext returns the context that is cancelled once Terraform stops the
+// provider.
+func stopContext(meta interface{}) context.Context {
+	if ctx := meta.(*Config).stopCtx; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+func getOptions(d resourceGetter, meta interface{}) (*consulapi.QueryOptions, *consulapi.WriteOptions) {
 	config := meta.(*Config)
 	client := config.client
 	var dc, token, namespace, partition string
@@ -343,6 +687,9 @@ func getOptions(d *schema.ResourceData, meta interface{}) (*consulapi.QueryOptio
 	if v, ok := d.GetOk("partition"); ok {
 		partition = v.(string)
 	}
+	if partition == "" {
+		partition = config.Partition
+	}
 
 	if dc == "" {
 		if config.Datacenter != "" {
@@ -371,6 +718,17 @@ func getOptions(d *schema.ResourceData, meta interface{}) (*consulapi.QueryOptio
 	return qOpts, wOpts
 }
 
+// statePartition returns the partition to write in the state of a resource.
+// The partition inherited from the provider configuration is not written when
+// the resource does not set one explicitly, otherwise it would show a diff.
+func statePartition(d *schema.ResourceData, meta interface{}, partition string) string {
+	config := meta.(*Config)
+	if d.Get("partition").(string) == "" && partition == config.Partition {
+		return ""
+	}
+	return partition
+}
+
 type stateWriter struct {
 	d      *schema.ResourceData
 	errors []string
@@ -5,7 +5,9 @@ package consul
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"regexp"
@@ -100,7 +102,22 @@ func TestResourceProvider(t *testing.T) {
 				data "consul_key_prefix" "app" {
 					path_prefix = "test"
 				}`,
-			ExpectError: regexp.MustCompile("either auth_jwt.bearer_token or auth_jwt.use_terraform_cloud_workload_identity should be set"),
+			ExpectError: regexp.MustCompile("either auth_jwt.bearer_token, auth_jwt.bearer_token_file or auth_jwt.use_terraform_cloud_workload_identity should be set"),
+		},
+		"auth_jwt_token_file": {
+			Config: `
+				provider "consul" {
+					address = "demo.consul.io:80"
+					auth_jwt {
+						auth_method       = "jwt"
+						bearer_token_file = "test-fixtures/missing-token"
+					}
+				}
+
+				data "consul_key_prefix" "app" {
+					path_prefix = "test"
+				}`,
+			ExpectError: regexp.MustCompile("failed to read auth_jwt.bearer_token_file"),
 		},
 		"auth_jwt_tfc_workload_identity": {
 			Config: `
@@ -117,6 +134,34 @@ func TestResourceProvider(t *testing.T) {
 				}`,
 			ExpectError: regexp.MustCompile("no token found in TFC_WORKLOAD_IDENTITY_TOKEN environment variable"),
 		},
+		"validate_datacenters": {
+			Config: `
+				provider "consul" {
+					datacenter           = "dc2"
+					validate_datacenters = true
+				}
+
+				data "consul_key_prefix" "app" {
+					path_prefix = "test"
+				}`,
+			ExpectError: regexp.MustCompile(`datacenter "dc2" does not exist, valid datacenters are: dc1`),
+		},
+		"validate_datacenters_resource": {
+			Config: `
+				provider "consul" {
+					validate_datacenters = true
+				}
+
+				resource "consul_keys" "app" {
+					datacenter = "dc2"
+
+					key {
+						path  = "test"
+						value = "test"
+					}
+				}`,
+			ExpectError: regexp.MustCompile(`datacenter "dc2" does not exist, valid datacenters are: dc1`),
+		},
 	}
 
 	for name, tc := range testCases {
@@ -136,6 +181,35 @@ func TestResourceProvider(t *testing.T) {
 	}
 }
 
+func TestLoginError(t *testing.T) {
+	cases := map[string]struct {
+		err      error
+		expected string
+	}{
+		"rejected": {
+			consulapi.StatusError{Code: 403, Body: "Permission denied"},
+			`failed to login using JWT auth method "k8s", the credentials were rejected: Unexpected response code: 403 (Permission denied)`,
+		},
+		"unreachable": {
+			&url.Error{Op: "Post", URL: "http://127.0.0.1:8500/v1/acl/login", Err: errors.New("connection refused")},
+			`failed to login using JWT auth method "k8s", Consul could not be reached: Post "http://127.0.0.1:8500/v1/acl/login": connection refused`,
+		},
+		"other": {
+			consulapi.StatusError{Code: 500, Body: "No cluster leader"},
+			`failed to login using JWT auth method "k8s": Unexpected response code: 500 (No cluster leader)`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := loginError("k8s", c.err)
+			if err.Error() != c.expected {
+				t.Fatalf("unexpected error\ngot:      %s\nexpected: %s", err, c.expected)
+			}
+		})
+	}
+}
+
 func TestResourceProvider_Configure(t *testing.T) {
 	rp := Provider()
 
@@ -151,6 +225,30 @@ func TestResourceProvider_Configure(t *testing.T) {
 	}
 }
 
+func TestResourceProvider_ConfigureTransport(t *testing.T) {
+	rp := Provider()
+
+	raw := map[string]interface{}{
+		"address":                "demo.consul.io:80",
+		"datacenter":             "nyc3",
+		"http_max_idle_conns":    10,
+		"http_idle_conn_timeout": "30s",
+	}
+
+	err := rp.Configure(terraform.NewResourceConfigRaw(raw))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	transport := rp.(*schema.Provider).Meta().(*Config).transport
+	if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 10 {
+		t.Fatalf("unexpected idle connections limits: %d, %d", transport.MaxIdleConns, transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Fatalf("unexpected idle timeout: %s", transport.IdleConnTimeout)
+	}
+}
+
 func TestResourceProvider_ConfigureTLS(t *testing.T) {
 	rp := Provider()
 
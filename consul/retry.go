@@ -0,0 +1,106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"syscall"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// retryPolicy describes how the requests made to Consul are retried when
+// they fail with a transient error, like during a leader election.
+type retryPolicy struct {
+	maxRetries int
+	waitMin    time.Duration
+	waitMax    time.Duration
+}
+
+// do calls f until it succeeds, returns an error that is not transient or the
+// maximum number of retries is reached. The wait between two attempts doubles
+// each time, starting at waitMin and capped at waitMax.
+//
+// Only the errors returned by f are considered so check-and-set conflicts,
+// that Consul reports as a successful request, are never retried.
+func (p retryPolicy) do(f func() error) error {
+	return p.doContext(context.Background(), f)
+}
+
+// doContext is like do but stops retrying as soon as ctx is done, the last
+// error is then returned.
+func (p retryPolicy) doContext(ctx context.Context, f func() error) error {
+	wait := p.waitMin
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.maxRetries || !isTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] Request to Consul failed, retrying in %s (%d/%d): %s", wait, attempt+1, p.maxRetries, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		wait *= 2
+		if wait > p.waitMax {
+			wait = p.waitMax
+		}
+	}
+}
+
+// isTransientError returns whether err is an error worth retrying: a 5xx
+// response from Consul, a connection that was refused or the timeout of an
+// idempotent operation. The 5xx errors of the operations that are not
+// idempotent are wrapped in an unsafeRetryError and never retried.
+func isTransientError(err error) bool {
+	var unsafeErr *unsafeRetryError
+	if errors.As(err, &unsafeErr) {
+		return false
+	}
+
+	var timeoutErr *timeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr.retryable
+	}
+
+	var statusErr consulapi.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500
+	}
+
+	if isConnectionRefused(err) {
+		return true
+	}
+
+	// Some endpoints do not return a StatusError
+	return strings.Contains(err.Error(), "Unexpected response code: 5")
+}
+
+// isConnectionRefused returns whether err reports a connection refused by
+// Consul, the request then never reached the server.
+func isConnectionRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(err.Error(), "connection refused")
+}
+
+// unsafeRetryError wraps the error of an operation that must not be sent
+// again: Consul may have applied it before failing, like a check-and-set
+// committed by Raft just before the server returned a 500.
+type unsafeRetryError struct {
+	err error
+}
+
+func (e *unsafeRetryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *unsafeRetryError) Unwrap() error {
+	return e.err
+}
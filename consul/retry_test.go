@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := retryPolicy{maxRetries: 3}
+
+	cases := map[string]struct {
+		err      error
+		expected int
+	}{
+		"success":            {nil, 1},
+		"server error":       {consulapi.StatusError{Code: 500, Body: "No cluster leader"}, 4},
+		"unavailable":        {fmt.Errorf("Unexpected response code: 503 (rpc error)"), 4},
+		"connection refused": {fmt.Errorf("dial tcp 127.0.0.1:8500: connect: connection refused"), 4},
+		"bad request":        {consulapi.StatusError{Code: 400, Body: "Bad request"}, 1},
+		"permission denied":  {fmt.Errorf("Unexpected response code: 403 (Permission denied)"), 1},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			err := policy.do(func() error {
+				attempts++
+				return c.err
+			})
+			if err != c.err {
+				t.Fatalf("expected %v, got %v", c.err, err)
+			}
+			if attempts != c.expected {
+				t.Fatalf("expected %d attempts, got %d", c.expected, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Context(t *testing.T) {
+	policy := retryPolicy{maxRetries: 3, waitMin: time.Hour, waitMax: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	expected := consulapi.StatusError{Code: 500, Body: "No cluster leader"}
+
+	// The wait before the next attempt is interrupted by the cancellation
+	attempts := 0
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := policy.doContext(ctx, func() error {
+		attempts++
+		return expected
+	})
+	if err != expected {
+		t.Fatalf("expected %v, got %v", expected, err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+
+	// No retry is made once the context is done
+	attempts = 0
+	err = policy.doContext(ctx, func() error {
+		attempts++
+		return expected
+	})
+	if err != expected || attempts != 1 {
+		t.Fatalf("unexpected result after %d attempts: %v", attempts, err)
+	}
+}
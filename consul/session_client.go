@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// sessionClient is a wrapper around the upstream Consul client that is
+// specialized for Terraform's manipulations of sessions.
+type sessionClient struct {
+	client *consulapi.Session
+	qOpts  *consulapi.QueryOptions
+	wOpts  *consulapi.WriteOptions
+	retry  retryPolicy
+
+	// requestID identifies the operations made by this client in the logs
+	requestID string
+}
+
+func newSessionClient(d resourceGetter, meta interface{}) *sessionClient {
+	client, qOpts, wOpts := getClient(d, meta)
+
+	return &sessionClient{
+		client:    client.Session(),
+		qOpts:     qOpts,
+		wOpts:     wOpts,
+		retry:     meta.(*Config).retry,
+		requestID: newRequestID(),
+	}
+}
+
+// Create creates a new session and returns its ID.
+func (c *sessionClient) Create(entry *consulapi.SessionEntry) (string, error) {
+	c.logf("DEBUG", "create", "", "Creating session '%s'", entry.Name)
+	var id string
+	err := c.do("create", "", func() (err error) {
+		id, _, err = c.client.Create(entry, c.wOpts)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create session '%s': %s", entry.Name, enterpriseFeatureError(c.qOpts, err))
+	}
+	return id, nil
+}
+
+// Info reads the given session, nil is returned if it does not exist anymore.
+func (c *sessionClient) Info(sessionID string) (*consulapi.SessionEntry, error) {
+	c.logf("DEBUG", "info", sessionID, "Reading session")
+	var entry *consulapi.SessionEntry
+	err := c.do("info", sessionID, func() (err error) {
+		entry, _, err = c.client.Info(sessionID, c.qOpts)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session '%s': %s", sessionID, enterpriseFeatureError(c.qOpts, err))
+	}
+	return entry, nil
+}
+
+// RenewOrCreateTTL renews the given session if it still exists and has the
+// given TTL and lock delay, or creates a new session that will delete the
+// keys it holds when it expires. It returns the ID of the session and whether
+// it has been created. The TTL of a session cannot be changed, the caller
+// must release the previous session when a new one is returned.
+func (c *sessionClient) RenewOrCreateTTL(sessionID, ttl string, lockDelay time.Duration) (string, bool, error) {
+	if sessionID != "" {
+		c.logf("DEBUG", "renew", sessionID, "Renewing session")
+		var entry *consulapi.SessionEntry
+		err := c.do("renew", sessionID, func() (err error) {
+			entry, _, err = c.client.Renew(sessionID, c.wOpts)
+			return err
+		})
+		if err != nil {
+			return "", false, fmt.Errorf("failed to renew session '%s': %s", sessionID, enterpriseFeatureError(c.qOpts, err))
+		}
+		if entry != nil && sameDuration(entry.TTL, ttl) && sameLockDelay(entry.LockDelay, lockDelay) {
+			return sessionID, false, nil
+		}
+	}
+
+	c.logf("DEBUG", "create", "", "Creating session with TTL %s", ttl)
+	var id string
+	err := c.do("create", "", func() (err error) {
+		id, _, err = c.client.CreateNoChecks(&consulapi.SessionEntry{
+			Name:      "terraform-provider-consul",
+			TTL:       ttl,
+			LockDelay: lockDelay,
+			Behavior:  consulapi.SessionBehaviorDelete,
+		}, c.wOpts)
+		return err
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create session: %s", enterpriseFeatureError(c.qOpts, err))
+	}
+	return id, true, nil
+}
+
+// sameLockDelay returns whether the lock delay of a session matches the
+// expected one, 0 meaning the default lock delay of Consul.
+func sameLockDelay(actual, expected time.Duration) bool {
+	if expected == 0 {
+		expected = 15 * time.Second
+	}
+	return actual == expected
+}
+
+// Destroy invalidates the given session. Destroying a session that does not
+// exist anymore is not an error.
+func (c *sessionClient) Destroy(sessionID string) error {
+	c.logf("DEBUG", "destroy", sessionID, "Destroying session")
+	err := c.do("destroy", sessionID, func() error {
+		_, err := c.client.Destroy(sessionID, c.wOpts)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to destroy session '%s': %s", sessionID, enterpriseFeatureError(c.qOpts, err))
+	}
+	return nil
+}
+
+// logf writes a log line about an operation of the client with the same
+// key=value fields as keyClient.logf.
+func (c *sessionClient) logf(level, operation, sessionID, format string, args ...interface{}) {
+	log.Printf(
+		"[%s] %s: operation=%s session=%q datacenter=%s request_id=%s",
+		level, fmt.Sprintf(format, args...), operation, sessionID, c.wOpts.Datacenter, c.requestID,
+	)
+}
+
+// do sends a request to Consul using the retry policy of the client, the
+// requests that fail are logged with the fields of the operation.
+func (c *sessionClient) do(operation, sessionID string, f func() error) error {
+	start := time.Now()
+	err := c.retry.do(f)
+	if err != nil {
+		c.logf("WARN", operation, sessionID, "Request to Consul failed after %s: %v", time.Since(start), err)
+	}
+	return err
+}
@@ -0,0 +1,149 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sessionPoolKey identifies the sessions that can be shared, they must have
+// been created with the same parameters in the same datacenter.
+type sessionPoolKey struct {
+	datacenter string
+	namespace  string
+	partition  string
+	ttl        string
+	behavior   string
+	lockDelay  time.Duration
+}
+
+// pooledSession is a session used by the keys written during this run.
+type pooledSession struct {
+	id string
+
+	// users is the number of keys that acquired the session and have not
+	// released it yet
+	users int
+}
+
+// sessionPool lets the consul_keys resources that write keys with the same
+// session parameters share one session instead of each creating their own.
+// It is stored on the Config so it lives as long as the provider, the
+// sessions are renewed by the first resource that needs them in a run.
+type sessionPool struct {
+	lock     sync.Mutex
+	sessions map[sessionPoolKey]*pooledSession
+}
+
+func newSessionPoolKey(c *sessionClient, ttl, behavior string, lockDelay time.Duration) sessionPoolKey {
+	return sessionPoolKey{
+		datacenter: c.wOpts.Datacenter,
+		namespace:  c.wOpts.Namespace,
+		partition:  c.wOpts.Partition,
+		ttl:        ttl,
+		behavior:   behavior,
+		lockDelay:  lockDelay,
+	}
+}
+
+// acquire returns the session to use for a key that was previously held by
+// oldID. The session already used by another key with the same parameters
+// is returned if there is one, otherwise oldID is renewed or a new session
+// is created.
+func (p *sessionPool) acquire(c *sessionClient, oldID, ttl, behavior string, lockDelay time.Duration) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.sessions == nil {
+		p.sessions = make(map[sessionPoolKey]*pooledSession)
+	}
+
+	key := newSessionPoolKey(c, ttl, behavior, lockDelay)
+	if s, ok := p.sessions[key]; ok {
+		s.users++
+		c.logf("DEBUG", "renew", s.id, "Reusing session shared by %d keys", s.users)
+		return s.id, nil
+	}
+
+	// The previous session may now be used with other parameters by another
+	// key, it must not be renewed for this one
+	if p.usedLocked(oldID) {
+		oldID = ""
+	}
+	id, _, err := c.RenewOrCreateTTL(oldID, ttl, lockDelay)
+	if err != nil {
+		return "", err
+	}
+	p.sessions[key] = &pooledSession{id: id, users: 1}
+	return id, nil
+}
+
+// release lets the session go once the key at path no longer uses it. The
+// session is destroyed when no other key written during this run uses it and
+// no other key is locked by it in Consul, the keys it holds being deleted
+// along with it. It returns whether the session is gone, when it is not the
+// caller must let the key go itself.
+//
+// The keys locked in Consul are checked because the pool only counts the
+// keys written during this run, the session may still be held by resources
+// that are not applied, or that are applied later in the run.
+func (p *sessionPool) release(ctx context.Context, c *keyClient, id, path string) (bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	key, s := p.findLocked(id)
+	if s != nil && s.users > 0 {
+		s.users--
+	}
+	if s != nil && s.users > 0 {
+		c.sessions.logf("DEBUG", "destroy", id, "Keeping session still used by %d keys", s.users)
+		return false, nil
+	}
+
+	entry, err := c.sessions.Info(id)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		// The session has expired, Consul already deleted its keys
+		delete(p.sessions, key)
+		return true, nil
+	}
+	paths, err := c.SessionKeys(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	for _, held := range paths {
+		if held != path {
+			c.sessions.logf("DEBUG", "destroy", id, "Keeping session still locking key '%s'", held)
+			return false, nil
+		}
+	}
+
+	if err := c.sessions.Destroy(id); err != nil {
+		return false, err
+	}
+	delete(p.sessions, key)
+	return true, nil
+}
+
+// findLocked returns the session of the pool with the given ID, if any.
+func (p *sessionPool) findLocked(id string) (sessionPoolKey, *pooledSession) {
+	for key, s := range p.sessions {
+		if s.id == id {
+			return key, s
+		}
+	}
+	return sessionPoolKey{}, nil
+}
+
+func (p *sessionPool) usedLocked(id string) bool {
+	if id == "" {
+		return false
+	}
+	_, s := p.findLocked(id)
+	return s != nil && s.users > 0
+}
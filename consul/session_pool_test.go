@@ -0,0 +1,185 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+func TestSessionPool(t *testing.T) {
+	var lock sync.Mutex
+	var created, destroyed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.URL.Path == "/v1/session/create":
+			id := "session-" + string(rune('a'+len(created)))
+			created = append(created, id)
+			json.NewEncoder(w).Encode(map[string]string{"ID": id})
+		case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+			destroyed = append(destroyed, strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+			w.Write([]byte("true"))
+		case strings.HasPrefix(r.URL.Path, "/v1/session/renew/"):
+			// The sessions of the previous runs have expired
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/v1/session/info/"):
+			id := strings.TrimPrefix(r.URL.Path, "/v1/session/info/")
+			json.NewEncoder(w).Encode([]map[string]string{{"ID": id}})
+		case r.URL.Path == "/v1/kv/":
+			// No other key is locked by the sessions
+			w.Write([]byte("[]"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &sessionClient{
+		client: client.Session(),
+		qOpts:  &consulapi.QueryOptions{},
+		wOpts:  &consulapi.WriteOptions{},
+	}
+	kv := &keyClient{
+		client:   client.KV(),
+		sessions: c,
+		qOpts:    &consulapi.QueryOptions{},
+		wOpts:    &consulapi.WriteOptions{},
+	}
+	ctx := context.Background()
+	pool := &sessionPool{}
+
+	// The keys with the same parameters share the session, even when
+	// acquired concurrently
+	var wg sync.WaitGroup
+	ids := make([]string, 10)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id, err := pool.acquire(c, "", "30s", consulapi.SessionBehaviorDelete, 0)
+			if err != nil {
+				t.Errorf("err: %v", err)
+			}
+			ids[i] = id
+		}(i)
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != "session-a" {
+			t.Fatalf("expected all the keys to share session-a, got %v", ids)
+		}
+	}
+
+	other, err := pool.acquire(c, "", "30s", consulapi.SessionBehaviorDelete, time.Second)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if other != "session-b" || len(created) != 2 {
+		t.Fatalf("expected a new session for another lock delay, got %q and %v", other, created)
+	}
+
+	// A session is kept until the last of the keys that acquired it
+	// releases it
+	for i := 0; i < len(ids)-1; i++ {
+		ok, err := pool.release(ctx, kv, "session-a", "app/a")
+		if err != nil || ok {
+			t.Fatalf("session-a should have been kept: %v", err)
+		}
+	}
+	ok, err := pool.release(ctx, kv, "session-a", "app/a")
+	if err != nil || !ok {
+		t.Fatalf("session-a should have been destroyed: %v", err)
+	}
+	if len(destroyed) != 1 || destroyed[0] != "session-a" {
+		t.Fatalf("unexpected destroyed sessions %v", destroyed)
+	}
+}
+
+func TestSessionPoolReleaseShared(t *testing.T) {
+	var lock sync.Mutex
+	var destroyed []string
+	// Two resources share session-old since a previous run, only the
+	// resource holding app/a is destroyed in this one
+	locks := []string{"app/a", "app/b"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/session/destroy/"):
+			destroyed = append(destroyed, strings.TrimPrefix(r.URL.Path, "/v1/session/destroy/"))
+			w.Write([]byte("true"))
+		case r.URL.Path == "/v1/session/info/session-old":
+			json.NewEncoder(w).Encode([]map[string]string{{"ID": "session-old"}})
+		case r.URL.Path == "/v1/session/info/session-expired":
+			json.NewEncoder(w).Encode([]map[string]string{})
+		case r.URL.Path == "/v1/kv/":
+			var pairs []map[string]string
+			for _, path := range locks {
+				pairs = append(pairs, map[string]string{"Key": path, "Session": "session-old"})
+			}
+			pairs = append(pairs, map[string]string{"Key": "app/c"})
+			json.NewEncoder(w).Encode(pairs)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := consulapi.NewClient(&consulapi.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := &keyClient{
+		client: client.KV(),
+		sessions: &sessionClient{
+			client: client.Session(),
+			qOpts:  &consulapi.QueryOptions{},
+			wOpts:  &consulapi.WriteOptions{},
+		},
+		qOpts: &consulapi.QueryOptions{},
+		wOpts: &consulapi.WriteOptions{},
+	}
+	ctx := context.Background()
+	pool := &sessionPool{}
+
+	// The pool is empty but app/b is still locked by the session
+	ok, err := pool.release(ctx, kv, "session-old", "app/a")
+	if err != nil || ok {
+		t.Fatalf("session-old should have been kept: %v", err)
+	}
+	if len(destroyed) != 0 {
+		t.Fatalf("unexpected destroyed sessions %v", destroyed)
+	}
+
+	// Once the other resource is destroyed too the session can go
+	locks = []string{"app/b"}
+	ok, err = pool.release(ctx, kv, "session-old", "app/b")
+	if err != nil || !ok {
+		t.Fatalf("session-old should have been destroyed: %v", err)
+	}
+	if len(destroyed) != 1 || destroyed[0] != "session-old" {
+		t.Fatalf("unexpected destroyed sessions %v", destroyed)
+	}
+
+	// The keys of an expired session have already been deleted by Consul
+	ok, err = pool.release(ctx, kv, "session-expired", "app/a")
+	if err != nil || !ok {
+		t.Fatalf("session-expired should be reported as gone: %v", err)
+	}
+	if len(destroyed) != 1 {
+		t.Fatalf("unexpected destroyed sessions %v", destroyed)
+	}
+}
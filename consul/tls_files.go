@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/hashicorp/go-rootcerts"
+)
+
+// tlsFiles reads the client certificate and the certificate authorities from
+// disk each time a connection to Consul is opened instead of only once when
+// the provider is configured, so that certificates rotated during a run are
+// picked up.
+type tlsFiles struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	caPath   string
+	caPEM    []byte
+}
+
+// install makes transport use the files for all the new TLS connections, the
+// settings already in transport.TLSClientConfig are kept.
+func (f *tlsFiles) install(transport *http.Transport) {
+	base := transport.TLSClientConfig
+	if base == nil {
+		base = &tls.Config{}
+	}
+	if f.certFile != "" && f.keyFile != "" {
+		// The certificate is read on each handshake
+		base.Certificates = nil
+		base.GetClientCertificate = f.clientCertificate
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		config, err := f.config(base, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// config returns the TLS configuration to use for a new connection to addr
+// with the current certificate authorities.
+func (f *tlsFiles) config(base *tls.Config, addr string) (*tls.Config, error) {
+	config := base.Clone()
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		config.ServerName = host
+	}
+
+	if f.caFile != "" || f.caPath != "" {
+		pool, err := rootcerts.LoadCACerts(&rootcerts.Config{
+			CAFile:        f.caFile,
+			CAPath:        f.caPath,
+			CACertificate: f.caPEM,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the certificate authorities: %v", err)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+func (f *tlsFiles) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+	}
+	return &cert, nil
+}
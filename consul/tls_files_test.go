@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTLSFiles(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The CA file initially holds a certificate authority that did not sign
+	// the certificate of the server
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	wrongCA, err := os.ReadFile("test-fixtures/cacert.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(caFile, wrongCA, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &http.Transport{}
+	files := &tlsFiles{caFile: caFile}
+	files.install(transport)
+	client := &http.Client{Transport: transport}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("expected the certificate of the server to be rejected")
+	}
+
+	// The new certificate authority must be used for the next connection
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, serverCA, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTLSFiles_clientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	files := &tlsFiles{
+		certFile: filepath.Join(dir, "cert.pem"),
+		keyFile:  filepath.Join(dir, "key.pem"),
+	}
+
+	// The certificate is read when it is needed, not when the provider is
+	// configured
+	if _, err := files.clientCertificate(nil); err == nil {
+		t.Fatal("expected an error when the certificate does not exist")
+	}
+
+	for src, dst := range map[string]string{
+		"test-fixtures/usercert.pem": files.certFile,
+		"test-fixtures/userkey.pem":  files.keyFile,
+	} {
+		content, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dst, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cert, err := files.clientCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cert.Certificate) == 0 {
+		t.Fatal("expected a certificate")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// tokenFile holds the ACL token read from the file given in token_file. The
+// token is read once and read again when Consul rejects it, so that a token
+// rotated on disk during a long run is picked up.
+type tokenFile struct {
+	path string
+
+	lock  sync.Mutex
+	token string
+}
+
+// get returns the current token, reading the file the first time.
+func (f *tokenFile) get() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.token != "" {
+		return f.token, nil
+	}
+	token, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	f.token = token
+	return token, nil
+}
+
+// reload reads the file again after Consul rejected the token, it returns
+// whether the token changed on disk.
+func (f *tokenFile) reload(rejected string) (string, bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Another request may already have reloaded the token
+	if f.token != rejected {
+		return f.token, true, nil
+	}
+	token, err := f.read()
+	if err != nil {
+		return "", false, err
+	}
+	f.token = token
+	return token, token != rejected, nil
+}
+
+func (f *tokenFile) read() (string, error) {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token_file: %v", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("token_file %q is empty", f.path)
+	}
+	return token, nil
+}
+
+// tokenFileTransport sets the token of the file on the requests that do not
+// already have one. A request rejected with a 403 is sent once more if the
+// token changed on disk in the meantime.
+type tokenFileTransport struct {
+	http.RoundTripper
+	file *tokenFile
+}
+
+func (t *tokenFileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The token set explicitly by the resources or the provider takes
+	// precedence
+	if req.Header.Get("X-Consul-Token") != "" {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	token, err := t.file.get()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.RoundTripper.RoundTrip(withToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+
+	// The token may have been rotated since it was read
+	newToken, changed, err := t.file.reload(token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if !changed {
+		log.Printf("[WARN] Consul rejected the token of token_file %q and it did not change on disk", t.file.path)
+		return resp, nil
+	}
+	if req.Body != nil && req.GetBody == nil {
+		// The body has already been consumed and cannot be sent again
+		return resp, nil
+	}
+
+	retried := withToken(req, newToken)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retried.Body = body
+	}
+	resp.Body.Close()
+
+	log.Printf("[INFO] The token of token_file %q changed on disk, sending the request again", t.file.path)
+	resp, err = t.RoundTripper.RoundTrip(retried)
+	if err == nil && resp.StatusCode == http.StatusForbidden {
+		log.Printf("[WARN] Consul rejected the token of token_file %q even after it was reloaded", t.file.path)
+	}
+	return resp, err
+}
+
+// withToken returns a copy of req that uses token, without modifying req as
+// required by http.RoundTripper.
+func withToken(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("X-Consul-Token", token)
+	return r
+}
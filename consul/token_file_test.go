@@ -0,0 +1,93 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTokenFileTransport(t *testing.T) {
+	var tokens, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Consul-Token")
+		body, _ := io.ReadAll(r.Body)
+		tokens = append(tokens, token)
+		bodies = append(bodies, string(body))
+		if token != "new" && token != "explicit" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{
+		Transport: &tokenFileTransport{
+			RoundTripper: http.DefaultTransport,
+			file:         &tokenFile{path: path},
+		},
+	}
+
+	put := func(header string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("value"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			req.Header.Set("X-Consul-Token", header)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// The token did not change on disk, the request is not retried
+	if code := put(""); code != http.StatusForbidden {
+		t.Fatalf("expected a 403, got %d", code)
+	}
+	if len(tokens) != 1 || tokens[0] != "old" {
+		t.Fatalf("unexpected tokens %v", tokens)
+	}
+
+	// Once the token is rotated the rejected request is sent again with the
+	// new one
+	if err := os.WriteFile(path, []byte("new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if code := put(""); code != http.StatusOK {
+		t.Fatalf("expected a 200, got %d", code)
+	}
+	if len(tokens) != 3 || tokens[1] != "old" || tokens[2] != "new" || bodies[2] != "value" {
+		t.Fatalf("unexpected requests %v %v", tokens, bodies)
+	}
+
+	// A token set on the request is kept
+	if code := put("explicit"); code != http.StatusOK {
+		t.Fatalf("expected a 200, got %d", code)
+	}
+	if tokens[3] != "explicit" {
+		t.Fatalf("unexpected tokens %v", tokens)
+	}
+
+	// An empty file is an error
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := (&tokenFile{path: path}).get()
+	if err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -4,12 +4,19 @@
 package consul
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // validateDurationMin is the minimum duration to accept as input
@@ -143,3 +150,97 @@ func validateRegexpFactory(name string, reString string) func(v interface{}, key
 		return warnings, errors
 	}
 }
+
+// validateKVPath checks that the attribute is a path that can be used as is
+// for a key of the key/value store.
+func validateKVPath(v interface{}, key string) (warnings []string, errors []error) {
+	if err := checkKVPath(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("invalid %s specified (%q): %v", key, v.(string), err))
+	}
+	return warnings, errors
+}
+
+// checkKVPath returns an error when path would not be stored under the same
+// key by Consul: the leading slash is silently removed by the Consul client
+// and the control characters are not accepted in the keys.
+func checkKVPath(path string) error {
+	if strings.HasPrefix(path, "/") {
+		return fmt.Errorf("the path must not start with a slash")
+	}
+	if !utf8.ValidString(path) {
+		return fmt.Errorf("the path must be valid UTF-8")
+	}
+	for _, r := range path {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("the path must not contain control characters, found %U", r)
+		}
+	}
+	return nil
+}
+
+// validateKVFlags checks that the attribute does not use the flag bits
+// reserved by the provider to mark the compressed and encrypted values.
+func validateKVFlags(v interface{}, key string) (warnings []string, errors []error) {
+	if flags := v.(int); flags&kvFlagsReserved != 0 {
+		errors = append(errors, fmt.Errorf("invalid %s specified (%d): the bits %d and %d are reserved by the provider", key, flags, kvFlagCompressed, kvFlagEncrypted))
+	}
+	return warnings, errors
+}
+
+// validateJSONSchemaDocument checks that the attribute is a valid JSON Schema
+// document.
+func validateJSONSchemaDocument(v interface{}, key string) (warnings []string, errors []error) {
+	if _, err := compileJSONSchema(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("invalid %s specified: %v", key, err))
+	}
+	return warnings, errors
+}
+
+func compileJSONSchema(document string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("value_schema.json", strings.NewReader(document)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile("value_schema.json")
+}
+
+// validateJSONSchemaValue checks that value is a JSON document conforming to
+// the given JSON Schema. The error returned lists the location in the
+// document of each violation.
+func validateJSONSchemaValue(document, value string) error {
+	s, err := compileJSONSchema(document)
+	if err != nil {
+		return fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	var instance interface{}
+	if err := json.Unmarshal([]byte(value), &instance); err != nil {
+		return fmt.Errorf("value is not a valid JSON document: %v", err)
+	}
+
+	err = s.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	var violations []string
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "/"
+			}
+			violations = append(violations, fmt.Sprintf(" - %s: %s", location, e.Message))
+			return
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(validationErr)
+	sort.Strings(violations)
+
+	return fmt.Errorf("value does not conform to the JSON schema:\n%s", strings.Join(violations, "\n"))
+}
@@ -0,0 +1,145 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package consul
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateJSONSchemaValue(t *testing.T) {
+	const objectSchema = `{
+  "type": "object",
+  "required": ["port"],
+  "properties": {
+    "host": {"type": "string"},
+    "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+  },
+  "additionalProperties": false
+}`
+	const listSchema = `{
+  "type": "array",
+  "items": {"type": "string", "enum": ["a", "b"]}
+}`
+
+	cases := map[string]struct {
+		schema   string
+		value    string
+		expected []string
+	}{
+		"valid object": {
+			schema: objectSchema,
+			value:  `{"host": "localhost", "port": 8500}`,
+		},
+		"wrong type": {
+			schema:   objectSchema,
+			value:    `{"port": "8500"}`,
+			expected: []string{"/port: expected integer, but got string"},
+		},
+		"missing property": {
+			schema:   objectSchema,
+			value:    `{"host": "localhost"}`,
+			expected: []string{"/: missing properties: 'port'"},
+		},
+		"multiple violations": {
+			schema: objectSchema,
+			value:  `{"port": 0, "extra": true}`,
+			expected: []string{
+				"/: additionalProperties 'extra' not allowed",
+				"/port: must be >= 1 but found 0",
+			},
+		},
+		"valid list": {
+			schema: listSchema,
+			value:  `["a", "b"]`,
+		},
+		"invalid item": {
+			schema:   listSchema,
+			value:    `["a", "c"]`,
+			expected: []string{`/1: value must be one of "a", "b"`},
+		},
+		"not JSON": {
+			schema:   listSchema,
+			value:    `a, b`,
+			expected: []string{"value is not a valid JSON document"},
+		},
+		"invalid schema": {
+			schema:   `{"type": 12}`,
+			value:    `{}`,
+			expected: []string{"invalid JSON schema"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateJSONSchemaValue(c.schema, c.value)
+			if len(c.expected) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, e := range c.expected {
+				if !strings.Contains(err.Error(), e) {
+					t.Fatalf("expected error to contain %q, got %q", e, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchemaDocument(t *testing.T) {
+	if _, errs := validateJSONSchemaDocument(`{"type": "object"}`, "value_schema"); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if _, errs := validateJSONSchemaDocument(`{"type": `, "value_schema"); len(errs) != 1 {
+		t.Fatalf("expected an error, got %v", errs)
+	}
+}
+
+func TestValidateKVPath(t *testing.T) {
+	cases := map[string]struct {
+		path     string
+		expected string
+	}{
+		"simple":             {path: "app/config"},
+		"special characters": {path: "app/my key?#%&=+;"},
+		"unicode":            {path: "app/café"},
+		"leading slash":      {path: "/app/config", expected: "the path must not start with a slash"},
+		"control character":  {path: "app/con\tfig", expected: "the path must not contain control characters, found U+0009"},
+		"invalid utf-8":      {path: "app/\xff", expected: "the path must be valid UTF-8"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, errs := validateKVPath(tc.path, "path")
+			if tc.expected == "" {
+				if len(errs) != 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.expected) {
+				t.Fatalf("expected an error containing %q, got %v", tc.expected, errs)
+			}
+		})
+	}
+}
+
+func TestValidateKVFlags(t *testing.T) {
+	for _, flags := range []int{0, 1, 3, 0x100} {
+		if _, errs := validateKVFlags(flags, "flags"); len(errs) != 0 {
+			t.Fatalf("unexpected errors for %d: %v", flags, errs)
+		}
+	}
+	for _, flags := range []int{kvFlagCompressed, kvFlagEncrypted | 1} {
+		if _, errs := validateKVFlags(flags, "flags"); len(errs) != 1 {
+			t.Fatalf("expected an error for %d, got %v", flags, errs)
+		}
+	}
+}
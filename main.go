@@ -11,4 +11,8 @@ import (
 func main() {
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: consul.Provider})
+
+	// Serve returns once Terraform is done with the provider
+	consul.Logout()
+	consul.WriteMetrics()
 }